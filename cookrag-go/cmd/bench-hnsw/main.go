@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cookrag-go/pkg/storage/vectorstore"
+
+	"github.com/charmbracelet/log"
+)
+
+// HNSW 召回率基准：在随机聚簇向量上对比 HNSW 与精确搜索的 recall@k 和延迟
+// 用法：go run cmd/bench-hnsw/main.go -n 20000 -dim 256 -k 10 -ef 16,32,64,128,256
+func main() {
+	n := flag.Int("n", 20000, "向量数量")
+	dim := flag.Int("dim", 256, "向量维度")
+	clusters := flag.Int("clusters", 100, "聚簇数量（模拟真实 embedding 的聚簇分布）")
+	queries := flag.Int("queries", 200, "查询数量")
+	k := flag.Int("k", 10, "每个查询返回的结果数")
+	m := flag.Int("m", vectorstore.DefaultHNSWConfig().M, "HNSW M")
+	efConstruction := flag.Int("ef-construction", vectorstore.DefaultHNSWConfig().EfConstruction, "HNSW efConstruction")
+	efList := flag.String("ef", "16,32,64,128,256", "要测试的 efSearch（逗号分隔）")
+	seed := flag.Int64("seed", 1, "随机数种子")
+	flag.Parse()
+
+	efValues, err := parseInts(*efList)
+	if err != nil {
+		log.Fatalf("❌ Invalid -ef: %v", err)
+	}
+
+	log.Infof("📊 HNSW recall benchmark: n=%d dim=%d clusters=%d queries=%d k=%d M=%d efConstruction=%d",
+		*n, *dim, *clusters, *queries, *k, *m, *efConstruction)
+
+	rng := rand.New(rand.NewSource(*seed))
+	centers := randomVectors(rng, *clusters, *dim, nil, 1)
+	vectors := randomVectors(rng, *n, *dim, centers, 0.3)
+	queryVectors := randomVectors(rng, *queries, *dim, centers, 0.3)
+
+	// 1. 构建索引
+	config := vectorstore.DefaultHNSWConfig()
+	config.M = *m
+	config.EfConstruction = *efConstruction
+	index := vectorstore.NewHNSWIndex(config, *dim)
+
+	start := time.Now()
+	for i, vector := range vectors {
+		if err := index.Insert(strconv.Itoa(i), vector); err != nil {
+			log.Fatalf("❌ Failed to insert vector: %v", err)
+		}
+	}
+	buildTime := time.Since(start)
+	log.Infof("✅ Built index in %v (%.0f inserts/s)", buildTime, float64(*n)/buildTime.Seconds())
+
+	// 2. 精确搜索作为标准答案
+	start = time.Now()
+	truth := make([]map[string]bool, len(queryVectors))
+	for i, query := range queryVectors {
+		truth[i] = exactSearch(vectors, query, *k)
+	}
+	exactLatency := time.Since(start) / time.Duration(len(queryVectors))
+	log.Infof("🎯 Exact search: %v/query", exactLatency)
+
+	// 3. 不同 efSearch 下的召回率和延迟
+	fmt.Fprintf(os.Stdout, "\n%-10s %-12s %-14s %-10s\n", "efSearch", "recall@"+strconv.Itoa(*k), "latency/query", "speedup")
+	for _, ef := range efValues {
+		index.SetEfSearch(ef)
+
+		hits := 0
+		start = time.Now()
+		for i, query := range queryVectors {
+			results, err := index.Search(query, *k, nil)
+			if err != nil {
+				log.Fatalf("❌ Search failed: %v", err)
+			}
+			for _, result := range results {
+				if truth[i][result.ID] {
+					hits++
+				}
+			}
+		}
+		latency := time.Since(start) / time.Duration(len(queryVectors))
+
+		recall := float64(hits) / float64(len(queryVectors)**k)
+		speedup := fmt.Sprintf("%.1fx", float64(exactLatency)/float64(latency))
+		fmt.Fprintf(os.Stdout, "%-10d %-12.4f %-14v %-10s\n", ef, recall, latency, speedup)
+	}
+}
+
+// randomVectors 生成随机向量：centers 为空时各维独立均匀分布，否则为随机中心加高斯噪声
+func randomVectors(rng *rand.Rand, count, dim int, centers [][]float32, noise float64) [][]float32 {
+	vectors := make([][]float32, count)
+	for i := range vectors {
+		vector := make([]float32, dim)
+		var center []float32
+		if len(centers) > 0 {
+			center = centers[rng.Intn(len(centers))]
+		}
+		for d := range vector {
+			if center != nil {
+				vector[d] = center[d] + float32(rng.NormFloat64()*noise)
+			} else {
+				vector[d] = float32(rng.Float64()*2 - 1)
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors
+}
+
+// exactSearch 暴力计算余弦相似度，返回 topK 的ID集合
+func exactSearch(vectors [][]float32, query []float32, k int) map[string]bool {
+	type scored struct {
+		id    int
+		score float64
+	}
+
+	scores := make([]scored, len(vectors))
+	queryNorm := norm(query)
+	for i, vector := range vectors {
+		dot := 0.0
+		for d := range vector {
+			dot += float64(vector[d]) * float64(query[d])
+		}
+		scores[i] = scored{id: i, score: dot / (norm(vector) * queryNorm)}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].score > scores[j].score
+	})
+
+	ids := make(map[string]bool, k)
+	for _, s := range scores[:min(k, len(scores))] {
+		ids[strconv.Itoa(s.id)] = true
+	}
+	return ids
+}
+
+// norm 向量的 L2 范数
+func norm(vector []float32) float64 {
+	sum := 0.0
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum)
+}
+
+// parseInts 解析逗号分隔的整数列表
+func parseInts(s string) ([]int, error) {
+	values := make([]int, 0)
+	for _, part := range strings.Split(s, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cookrag-go/internal/kg"
+
+	"github.com/charmbracelet/log"
+)
+
+// 用法：go run cmd/build-dict/main.go [菜谱目录] [输出词典]
+func main() {
+	log.Infof("📖 CookRAG Dictionary Builder")
+
+	docsDir := "docs/dishes"
+	if len(os.Args) > 1 {
+		docsDir = os.Args[1]
+	}
+	output := "config/dict/cookrag.dict"
+	if len(os.Args) > 2 {
+		output = os.Args[2]
+	}
+
+	log.Infof("📚 Loading documents from: %s", docsDir)
+	documents, err := loadDocumentsFromDir(docsDir)
+	if err != nil {
+		log.Fatalf("❌ Failed to load documents: %v", err)
+	}
+	log.Infof("✅ Loaded %d documents", len(documents))
+
+	entries := kg.GenerateDictionary(documents)
+
+	dishes, ingredients := 0, 0
+	for _, entry := range entries {
+		if entry.Tag == kg.DictTagDish {
+			dishes++
+		} else {
+			ingredients++
+		}
+	}
+
+	if err := kg.WriteDictionary(output, entries); err != nil {
+		log.Fatalf("❌ Failed to write dictionary: %v", err)
+	}
+
+	log.Infof("✅ Dictionary written: %s (%d dishes, %d ingredients)", output, dishes, ingredients)
+	log.Infof("🔄 BM25 snapshot will be rebuilt on next start (tokenizer fingerprint changed)")
+}
+
+// loadDocumentsFromDir 加载菜谱文档（路径格式：category/dish.md 或 category/dish/dish.md）
+func loadDocumentsFromDir(dir string) ([]kg.Document, error) {
+	var documents []kg.Document
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !strings.HasSuffix(path, ".md") {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			log.Warnf("⚠️  Failed to read file %s: %v", path, err)
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		parts := strings.Split(relPath, string(filepath.Separator))
+		if len(parts) < 2 {
+			return nil
+		}
+
+		documents = append(documents, kg.Document{
+			ID:       relPath,
+			Content:  string(content),
+			Category: parts[0],
+			DishName: strings.TrimSuffix(parts[len(parts)-1], ".md"),
+		})
+
+		return nil
+	})
+
+	return documents, err
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/charmbracelet/log"
 	"cookrag-go/internal/config"
 	"cookrag-go/internal/kg"
+	"cookrag-go/internal/synonym"
 	"cookrag-go/pkg/storage/neo4j"
 )
 
@@ -98,6 +99,14 @@ func main() {
 	// 5. 构建知识图谱
 	builder := kg.NewGraphBuilder(neo4jClient)
 
+	// 食材别名统一到规范名节点（西红柿 → 番茄）
+	synonyms, err := synonym.Load(cfg.Synonyms.Path)
+	if err != nil {
+		log.Warnf("⚠️  Failed to load synonyms, building without alias resolution: %v", err)
+	} else {
+		builder.SetSynonyms(synonyms)
+	}
+
 	stats, err := builder.BuildFromDocuments(context.Background(), documents)
 	if err != nil {
 		log.Fatalf("❌ Failed to build graph: %v", err)
@@ -166,6 +175,7 @@ func loadDocumentsFromDir(dir string) ([]kg.Document, error) {
 		}
 
 		documents = append(documents, kg.Document{
+			ID:       relPath,
 			Content:  string(content),
 			Category: category,
 			DishName: dishName,
@@ -14,15 +14,22 @@ import (
 	"github.com/charmbracelet/log"
 	"cookrag-go/internal/api/server"
 	"cookrag-go/internal/config"
+	"cookrag-go/internal/core/chunker"
+	"cookrag-go/internal/core/fuzzy"
+	"cookrag-go/internal/core/rerank"
 	"cookrag-go/internal/core/retrieval"
 	"cookrag-go/internal/core/router"
+	"cookrag-go/internal/kg"
 	"cookrag-go/internal/models"
 	"cookrag-go/internal/observability"
+	"cookrag-go/internal/synonym"
 	embeddingCfg "cookrag-go/pkg/ml/embedding"
 	"cookrag-go/pkg/ml/llm"
 	"cookrag-go/pkg/storage/cache"
+	"cookrag-go/pkg/storage/docstore"
 	"cookrag-go/pkg/storage/milvus"
 	"cookrag-go/pkg/storage/neo4j"
+	"cookrag-go/pkg/storage/vectorstore"
 )
 
 // initLoggingWithFile 初始化日志配置（同时输出到终端和文件）
@@ -92,13 +99,7 @@ func main() {
 		cfg.Embedding.Provider, embeddingProvider.Dimension())
 
 	// 3. 初始化存储客户端
-	milvusClient, err := milvus.NewClient(cfg.Milvus.Host, cfg.Milvus.Port)
-	if err != nil {
-		log.Warnf("⚠️  Failed to connect to Milvus: %v", err)
-		milvusClient = nil
-	} else {
-		log.Info("✅ Milvus client connected")
-	}
+	vectorStore := newVectorStore(cfg)
 
 	neo4jClient, err := neo4j.NewClient(
 		cfg.Neo4j.URI,
@@ -129,45 +130,93 @@ func main() {
 		log.Info("✅ Redis client connected")
 	}
 
-	// 4. 初始化检索器
+	// 同义词表（BM25 查询扩展 + 图谱实体别名解析）
+	synonyms, err := synonym.Load(cfg.Synonyms.Path)
+	if err != nil {
+		log.Warnf("⚠️  Failed to load synonyms: %v", err)
+		synonyms = synonym.NewRegistry(cfg.Synonyms.Path)
+	}
+	if neo4jClient != nil {
+		neo4jClient.SetAliasResolver(synonyms)
+	}
+
+	// 4. 初始化检索器（开启分块时向量检索和BM25都按原菜谱聚合分块命中）
+	recipeChunker := newChunker(cfg)
+	vectorConfig := retrieval.DefaultVectorRetrieverConfig()
+	vectorConfig.GroupByParent = recipeChunker != nil
+	vectorConfig.SentenceHighlight = cfg.Vector.SentenceHighlight
+	if cfg.Vector.MaxSnippetSentences > 0 {
+		vectorConfig.MaxSnippetSentences = cfg.Vector.MaxSnippetSentences
+	}
+
 	var vectorRetriever *retrieval.VectorRetriever
 	if redisCache != nil {
 		vectorRetriever = retrieval.NewVectorRetriever(
-			retrieval.DefaultVectorRetrieverConfig(),
+			vectorConfig,
 			embeddingProvider,
-			milvusClient,
+			vectorStore,
 			redisCache,
 		)
 	} else {
 		vectorRetriever = retrieval.NewVectorRetriever(
-			retrieval.DefaultVectorRetrieverConfig(),
+			vectorConfig,
 			embeddingProvider,
-			milvusClient,
+			vectorStore,
 			nil,
 		)
 	}
 
-	bm25Retriever := retrieval.NewBM25Retriever(retrieval.DefaultBM25Config())
+	bm25Retriever := retrieval.NewBM25Retriever(newBM25Config(cfg))
+	bm25Retriever.SetSynonyms(synonyms)
+
+	// 图检索按菜谱ID回查完整菜谱；分块索引时BM25存储的是分块，原文档另存一份
+	recipeStore := bm25Retriever.DocumentStore()
+	if recipeChunker != nil {
+		recipeStore = docstore.NewMemoryStore()
+	}
 
 	graphRetriever := retrieval.NewGraphRetriever(
 		retrieval.DefaultGraphRetrieverConfig(),
 		neo4jClient,
+		recipeStore,
 	)
 
 	hybridRetriever := retrieval.NewHybridRetriever(
-		retrieval.DefaultHybridRetrieverConfig(),
+		newHybridConfig(cfg),
 		vectorRetriever,
 		bm25Retriever,
 	)
+	// 图检索作为第三路参与融合（需要 Neo4j）
+	if neo4jClient != nil && cfg.Hybrid.GraphWeight > 0 {
+		hybridRetriever.AddSource(retrieval.HybridSource{
+			Name:      "graph",
+			Retriever: graphRetriever,
+			Weight:    cfg.Hybrid.GraphWeight,
+		})
+	}
 
 	// 5. 初始化路由器
 	queryRouter := router.NewQueryRouter(
-		router.DefaultQueryRouterConfig(),
+		newQueryRouterConfig(cfg),
 		vectorRetriever,
 		bm25Retriever,
 		graphRetriever,
 		hybridRetriever,
 	)
+	queryRouter.SetCorrector(newCorrector(cfg, synonyms))
+	queryRouter.SetParentRetriever(newParentRetriever(cfg, recipeStore))
+
+	// MMR 多样化按配置挂在向量检索、混合检索或路由之后
+	if diversifier := newMMRReranker(cfg, vectorRetriever); diversifier != nil {
+		switch cfg.MMR.Stage {
+		case "vector":
+			vectorRetriever.SetDiversifier(diversifier)
+		case "hybrid":
+			hybridRetriever.SetDiversifier(diversifier)
+		default:
+			queryRouter.SetDiversifier(diversifier)
+		}
+	}
 
 	// 6. 初始化LLM生成器
 	llmProvider, err := llm.NewZhipuLLM("glm-4-flash")
@@ -177,6 +226,10 @@ func main() {
 	} else {
 		log.Info("✅ LLM provider initialized")
 	}
+	queryRouter.SetReranker(newRerankStage(cfg, llmProvider, bm25Retriever))
+	if llmProvider != nil {
+		hybridRetriever.SetExpander(newQueryExpander(cfg, llmProvider))
+	}
 
 	// 7. 启动监控
 	metricsCtx, cancel := context.WithCancel(context.Background())
@@ -185,11 +238,11 @@ func main() {
 	go observability.Global.StartMetricsReporter(metricsCtx, 30*time.Second)
 
 	// 8. 演示完整的RAG流程（包含LLM生成）
-	demonstrateCompleteRAG(metricsCtx, queryRouter, llmProvider, vectorRetriever, embeddingProvider, milvusClient)
+	demonstrateCompleteRAG(metricsCtx, queryRouter, llmProvider, vectorRetriever, bm25Retriever, embeddingProvider, recipeChunker, recipeStore)
 
 	// 9. 启动HTTP服务器
 	go func() {
-		srv := server.NewServer(server.DefaultConfig())
+		srv := server.NewServer(server.DefaultConfig(), queryRouter, llmProvider)
 		if err := srv.Start(); err != nil {
 			log.Errorf("❌ HTTP server error: %v", err)
 		}
@@ -203,8 +256,11 @@ func main() {
 	log.Info("🛑 Shutting down...")
 
 	// 清理资源
-	if milvusClient != nil {
-		milvusClient.Close(context.Background())
+	if err := bm25Retriever.Close(context.Background()); err != nil {
+		log.Errorf("❌ Failed to save BM25 document store: %v", err)
+	}
+	if vectorStore != nil {
+		vectorStore.Close(context.Background())
 	}
 	if neo4jClient != nil {
 		neo4jClient.Close(context.Background())
@@ -218,7 +274,8 @@ func main() {
 }
 
 // demonstrateCompleteRAG 演示完整的RAG流程（包含LLM生成）
-func demonstrateCompleteRAG(ctx context.Context, queryRouter *router.QueryRouter, llmProvider *llm.ZhipuLLM, vectorRetriever *retrieval.VectorRetriever, embeddingProvider embeddingCfg.Provider, milvusClient *milvus.Client) {
+// 配置了分块器时原文档写入 recipeStore，索引的是按章节切分的分块
+func demonstrateCompleteRAG(ctx context.Context, queryRouter *router.QueryRouter, llmProvider *llm.ZhipuLLM, vectorRetriever *retrieval.VectorRetriever, bm25Retriever *retrieval.BM25Retriever, embeddingProvider embeddingCfg.Provider, recipeChunker *chunker.Chunker, recipeStore docstore.Store) {
 	log.Info("📚 Running Complete RAG Demonstration...")
 
 	// 从 docs/dishes 目录加载所有菜谱文档
@@ -231,91 +288,38 @@ func demonstrateCompleteRAG(ctx context.Context, queryRouter *router.QueryRouter
 
 	log.Infof("📚 Loaded %d documents", len(documents))
 
-	// 使用BM25进行全文检索（补充向量检索的不足）
-	bm25Retriever := retrieval.NewBM25Retriever(retrieval.DefaultBM25Config())
-	bm25Retriever.IndexDocuments(ctx, documents)
-
-	// 使用向量检索器，索引到Milvus
-	if vectorRetriever != nil && embeddingProvider != nil && milvusClient != nil {
-		log.Infof("📦 Indexing %d documents to Milvus for vector search...", len(documents))
-
-		// 确保 Milvus 集合存在
-		collectionName := "cookrag_documents"
-		hasCollection, err := milvusClient.HasCollection(ctx, collectionName)
-		if err != nil {
-			log.Warnf("⚠️  Failed to check collection: %v", err)
-		} else if !hasCollection {
-			log.Infof("📦 Creating Milvus collection: %s", collectionName)
-			if err := milvusClient.CreateCollection(ctx, collectionName, embeddingProvider.Dimension()); err != nil {
-				log.Warnf("⚠️  Failed to create collection: %v", err)
-			} else {
-				log.Infof("✅ Collection created: %s", collectionName)
+	if recipeChunker != nil {
+		if err := recipeStore.Put(ctx, documents...); err != nil {
+			log.Warnf("⚠️  Failed to store recipes: %v", err)
+		}
+		documents = recipeChunker.SplitAll(documents)
+	}
 
-				// 创建索引
-				if err := milvusClient.CreateIndex(ctx, collectionName, "vector", "IVF_FLAT", map[string]string{}); err != nil {
-					log.Warnf("⚠️  Failed to create index: %v", err)
-				} else {
-					log.Infof("✅ Index created on collection: %s", collectionName)
-				}
+	// 使用BM25进行全文检索（补充向量检索的不足）
+	// 与路由器共用同一个检索器，索引后的文档存储也供图检索回查菜谱
+	if err := bm25Retriever.RestoreOrIndex(ctx, documents); err != nil {
+		log.Warnf("⚠️  Failed to index documents to BM25: %v", err)
+	}
 
-				// 加载集合
-				if err := milvusClient.LoadCollection(ctx, collectionName); err != nil {
-					log.Warnf("⚠️  Failed to load collection: %v", err)
-				} else {
-					log.Infof("✅ Collection loaded: %s", collectionName)
-				}
+	// 使用向量检索器，索引到向量存储（Milvus 或进程内存储）
+	if vectorRetriever != nil && embeddingProvider != nil {
+		log.Infof("📦 Indexing %d documents to vector store for vector search...", len(documents))
 
-				// 新创建的集合，需要插入数据
-				if err := vectorRetriever.IndexDocuments(ctx, documents); err != nil {
-					log.Warnf("⚠️  Failed to index to Milvus: %v", err)
-				} else {
-					log.Infof("✅ Documents indexed to Milvus")
-				}
-			}
-		} else {
-			// 集合已存在，检查是否已有数据
-			stats, err := milvusClient.GetCollectionStats(ctx, collectionName)
+		if err := vectorRetriever.EnsureCollection(ctx); err != nil {
+			log.Warnf("⚠️  Failed to create vector collection: %v", err)
+		} else if rowCount, err := vectorRetriever.Count(ctx); err != nil || rowCount == 0 {
+			// 空集合或无法获取统计信息时插入数据（按文档ID覆盖，重复插入不会产生重复记录）
 			if err != nil {
 				log.Warnf("⚠️  Failed to get collection stats: %v", err)
-				// 无法获取统计信息，尝试插入
-				if err := vectorRetriever.IndexDocuments(ctx, documents); err != nil {
-					log.Warnf("⚠️  Failed to index to Milvus: %v", err)
-				} else {
-					log.Infof("✅ Documents indexed to Milvus")
-				}
-			} else {
-				rowCount := int64(0)
-				if count, ok := stats["row_count"]; ok {
-					// Milvus 返回的 row_count 可能是 string 或 int64，需要处理两种情况
-					switch v := count.(type) {
-					case int64:
-						rowCount = v
-					case string:
-						// 如果是字符串，转换为 int64
-						fmt.Sscanf(v, "%d", &rowCount)
-					case float64:
-						rowCount = int64(v)
-					}
-				}
-
-				if rowCount > 0 {
-					// 已有数据，跳过插入
-					log.Infof("⏭️  Collection already has %d documents, skipping insertion", rowCount)
-				} else {
-					// 空集合，插入数据
-					log.Infof("📝 Collection is empty, inserting %d documents", len(documents))
-					if err := vectorRetriever.IndexDocuments(ctx, documents); err != nil {
-						log.Warnf("⚠️  Failed to index to Milvus: %v", err)
-					} else {
-						log.Infof("✅ Documents indexed to Milvus")
-					}
-				}
 			}
-
-			// 确保集合已加载
-			if err := milvusClient.LoadCollection(ctx, collectionName); err != nil {
-				log.Warnf("⚠️  Failed to load collection: %v", err)
+			if err := vectorRetriever.IndexDocuments(ctx, documents); err != nil {
+				log.Warnf("⚠️  Failed to index to vector store: %v", err)
+			} else {
+				log.Infof("✅ Documents indexed to vector store")
 			}
+		} else {
+			// 已有数据，跳过插入
+			log.Infof("⏭️  Collection already has %d documents, skipping insertion", rowCount)
 		}
 	}
 
@@ -347,9 +351,9 @@ func demonstrateCompleteRAG(ctx context.Context, queryRouter *router.QueryRouter
 	}
 
 	for _, query := range queries {
-		log.Infof("\n" + strings.Repeat("=", 70))
+		log.Info("\n" + strings.Repeat("=", 70))
 		log.Infof("🔍 Query: %s", query)
-		log.Infof(strings.Repeat("=", 70))
+		log.Info(strings.Repeat("=", 70))
 
 		// 1. 检索相关文档
 		startTime := time.Now()
@@ -422,6 +426,7 @@ func demonstrateCompleteRAG(ctx context.Context, queryRouter *router.QueryRouter
 // loadDocumentsFromDir 从目录加载所有 Markdown 文档
 func loadDocumentsFromDir(dir string) ([]models.Document, error) {
 	var documents []models.Document
+	extractor := kg.NewRecipeExtractor()
 
 	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -455,16 +460,15 @@ func loadDocumentsFromDir(dir string) ([]models.Document, error) {
 		// 提取菜名（从文件名）
 		dishName := strings.TrimSuffix(filepath.Base(path), ".md")
 
-		// 创建文档
+		// 创建文档（菜系、难度、原料写入元数据，供检索过滤使用）
 		doc := models.Document{
-			ID:      relPath,
-			Content: string(content),
-			Metadata: map[string]interface{}{
-				"file":     relPath,
-				"category": category,
-				"dish":     dishName,
-			},
+			ID:       relPath,
+			Content:  string(content),
+			Metadata: extractor.ExtractMetadata(string(content), category),
 		}
+		doc.Metadata["file"] = relPath
+		doc.Metadata[kg.MetadataCategory] = category
+		doc.Metadata["dish"] = dishName
 
 		documents = append(documents, doc)
 		return nil
@@ -481,6 +485,314 @@ func loadDocumentsFromDir(dir string) ([]models.Document, error) {
 	return documents, nil
 }
 
+// newVectorStore 根据配置创建向量存储（Milvus 连接失败时退回进程内存储）
+func newVectorStore(cfg *config.Config) vectorstore.Store {
+	if cfg.VectorStore.Backend != "embedded" {
+		milvusClient, err := milvus.NewClient(cfg.Milvus.Host, cfg.Milvus.Port)
+		if err == nil {
+			if err := milvusClient.SetIndexConfig(newMilvusIndexConfig(cfg)); err != nil {
+				log.Warnf("⚠️  Invalid Milvus index config: %v, using default IVF_FLAT/L2", err)
+			}
+			log.Infof("✅ Milvus client connected (index: %s, metric: %s)",
+				milvusClient.IndexConfig().IndexType, milvusClient.IndexConfig().MetricType)
+			return vectorstore.NewMilvusStore(milvusClient)
+		}
+		log.Warnf("⚠️  Failed to connect to Milvus: %v, falling back to embedded vector store", err)
+	}
+
+	storeConfig := vectorstore.DefaultEmbeddedStoreConfig()
+	storeConfig.Dir = cfg.VectorStore.Path
+	if cfg.VectorStore.Index != "" {
+		storeConfig.Index = cfg.VectorStore.Index
+	}
+	if cfg.VectorStore.HNSW.M > 0 {
+		storeConfig.HNSW.M = cfg.VectorStore.HNSW.M
+	}
+	if cfg.VectorStore.HNSW.EfConstruction > 0 {
+		storeConfig.HNSW.EfConstruction = cfg.VectorStore.HNSW.EfConstruction
+	}
+	if cfg.VectorStore.HNSW.EfSearch > 0 {
+		storeConfig.HNSW.EfSearch = cfg.VectorStore.HNSW.EfSearch
+	}
+
+	store, err := vectorstore.NewEmbeddedStore(storeConfig)
+	if err != nil {
+		log.Warnf("⚠️  Failed to load embedded vector store: %v", err)
+		return nil
+	}
+	log.Infof("✅ Embedded vector store ready: %s (index: %s)", cfg.VectorStore.Path, storeConfig.Index)
+	return store
+}
+
+// newMilvusIndexConfig 根据配置文件生成 Milvus 索引配置（未配置的参数使用默认值）
+func newMilvusIndexConfig(cfg *config.Config) *milvus.IndexConfig {
+	indexConfig := milvus.DefaultIndexConfig()
+	if cfg.Milvus.IndexType != "" {
+		indexConfig.IndexType = cfg.Milvus.IndexType
+	}
+	if cfg.Milvus.MetricType != "" {
+		indexConfig.MetricType = cfg.Milvus.MetricType
+	}
+	if cfg.Milvus.IndexParams.NList > 0 {
+		indexConfig.NList = cfg.Milvus.IndexParams.NList
+	}
+	if cfg.Milvus.IndexParams.M > 0 {
+		indexConfig.M = cfg.Milvus.IndexParams.M
+	}
+	if cfg.Milvus.IndexParams.EfConstruction > 0 {
+		indexConfig.EfConstruction = cfg.Milvus.IndexParams.EfConstruction
+	}
+	if cfg.Milvus.SearchParams.NProbe > 0 {
+		indexConfig.NProbe = cfg.Milvus.SearchParams.NProbe
+	}
+	if cfg.Milvus.SearchParams.Ef > 0 {
+		indexConfig.Ef = cfg.Milvus.SearchParams.Ef
+	}
+	return indexConfig
+}
+
+// newBM25Config 根据配置文件生成BM25配置（未配置的参数使用默认值，评分变体无效时使用经典BM25）
+func newBM25Config(cfg *config.Config) *retrieval.BM25Config {
+	bm25Config := retrieval.DefaultBM25Config()
+	if cfg.BM25.K1 > 0 {
+		bm25Config.K1 = cfg.BM25.K1
+	}
+	if cfg.BM25.B > 0 {
+		bm25Config.B = cfg.BM25.B
+	}
+	if err := retrieval.ValidateBM25Variant(retrieval.BM25Variant(cfg.BM25.Variant)); err != nil {
+		log.Warnf("⚠️  %v, using %s", err, bm25Config.Variant)
+	} else if cfg.BM25.Variant != "" {
+		bm25Config.Variant = retrieval.BM25Variant(cfg.BM25.Variant)
+	}
+	bm25Config.Delta = cfg.BM25.Delta
+	bm25Config.DocStorePath = cfg.BM25.DocStorePath
+	bm25Config.SnapshotPath = cfg.BM25.SnapshotPath
+	bm25Config.Tokenizer = &retrieval.TokenizerConfig{
+		UserDictPaths:  cfg.Tokenizer.UserDictPaths,
+		StopWordsPaths: cfg.Tokenizer.StopWordsPaths,
+	}
+	for field, fieldConfig := range cfg.BM25.Fields {
+		bm25Config.Fields[field] = retrieval.BM25FieldConfig{Weight: fieldConfig.Weight, B: fieldConfig.B}
+	}
+	bm25Config.GroupByParent = cfg.Chunking.Enabled
+	return bm25Config
+}
+
+// newHybridConfig 根据配置文件生成混合检索配置（融合方法无效时使用默认的 RRF）
+func newHybridConfig(cfg *config.Config) *retrieval.HybridRetrieverConfig {
+	hybridConfig := retrieval.DefaultHybridRetrieverConfig()
+	if cfg.Hybrid.RRFK > 0 {
+		hybridConfig.RRFK = cfg.Hybrid.RRFK
+		hybridConfig.RRF = cfg.Hybrid.RRFK
+	}
+	if cfg.Hybrid.VectorWeight > 0 {
+		hybridConfig.VectorWeight = cfg.Hybrid.VectorWeight
+	}
+	if cfg.Hybrid.BM25Weight > 0 {
+		hybridConfig.BM25Weight = cfg.Hybrid.BM25Weight
+	}
+	if cfg.Hybrid.SourceTimeoutMs > 0 {
+		hybridConfig.SourceTimeout = time.Duration(cfg.Hybrid.SourceTimeoutMs) * time.Millisecond
+	}
+	if err := retrieval.ValidateFusion(cfg.Hybrid.Fusion); err != nil {
+		log.Warnf("⚠️  %v, using %s", err, hybridConfig.Fusion)
+	} else if cfg.Hybrid.Fusion != "" {
+		hybridConfig.Fusion = cfg.Hybrid.Fusion
+	}
+	return hybridConfig
+}
+
+// newQueryExpander 根据配置创建 LLM 查询扩展器
+func newQueryExpander(cfg *config.Config, llmProvider *llm.ZhipuLLM) *retrieval.QueryExpander {
+	expansion := cfg.Hybrid.Expansion
+	expanderConfig := retrieval.DefaultQueryExpanderConfig()
+	expanderConfig.EnabledDefault = expansion.Enabled
+	expanderConfig.HyDE = expansion.HyDE
+	if expansion.Paraphrases > 0 {
+		expanderConfig.Paraphrases = expansion.Paraphrases
+	}
+	if expansion.MaxHyDERunes > 0 {
+		expanderConfig.MaxHyDERunes = expansion.MaxHyDERunes
+	}
+	if expansion.Timeout > 0 {
+		expanderConfig.Timeout = time.Duration(expansion.Timeout) * time.Second
+	}
+	if expansion.OriginalWeight > 0 {
+		expanderConfig.OriginalWeight = expansion.OriginalWeight
+	}
+	if expansion.VariantWeight > 0 {
+		expanderConfig.VariantWeight = expansion.VariantWeight
+	}
+
+	log.Infof("🪄 Query expansion available: %d paraphrases, hyde=%t, default=%t",
+		expanderConfig.Paraphrases, expanderConfig.HyDE, expanderConfig.EnabledDefault)
+	return retrieval.NewQueryExpander(expanderConfig, llmProvider)
+}
+
+// newQueryRouterConfig 根据配置文件生成路由配置（未配置回退顺序时使用默认顺序）
+func newQueryRouterConfig(cfg *config.Config) *router.QueryRouterConfig {
+	routerConfig := router.DefaultQueryRouterConfig()
+	if len(cfg.Router.Fallbacks) > 0 {
+		routerConfig.Fallbacks = cfg.Router.Fallbacks
+	}
+	if len(cfg.Router.MinTopScore) > 0 {
+		routerConfig.MinTopScore = cfg.Router.MinTopScore
+	}
+	if cfg.Router.BM25TopK > 0 {
+		routerConfig.BM25TopK = cfg.Router.BM25TopK
+	}
+	return routerConfig
+}
+
+// newParentRetriever 根据配置创建父文档检索（未启用时返回 nil，直接返回命中的分块）
+func newParentRetriever(cfg *config.Config, recipeStore docstore.Store) *retrieval.ParentDocumentRetriever {
+	parentConfig := cfg.Chunking.ParentDocument
+	if !parentConfig.Enabled {
+		return nil
+	}
+
+	retrieverConfig := retrieval.DefaultParentDocumentRetrieverConfig()
+	if parentConfig.ScoreMode != "" {
+		retrieverConfig.ScoreMode = retrieval.ParentScoreMode(parentConfig.ScoreMode)
+	}
+	if parentConfig.MaxContextTokens > 0 {
+		retrieverConfig.MaxContextTokens = parentConfig.MaxContextTokens
+	}
+	return retrieval.NewParentDocumentRetriever(retrieverConfig, recipeStore)
+}
+
+// newMMRReranker 根据配置创建 MMR 多样化重排（未启用时返回 nil；向量相似度使用向量存储中的已存向量）
+func newMMRReranker(cfg *config.Config, vectorRetriever *retrieval.VectorRetriever) *retrieval.MMRReranker {
+	if !cfg.MMR.Enabled {
+		return nil
+	}
+
+	mmrConfig := retrieval.DefaultMMRConfig()
+	if cfg.MMR.Lambda > 0 {
+		mmrConfig.Lambda = cfg.MMR.Lambda
+	}
+	if cfg.MMR.TopK > 0 {
+		mmrConfig.TopK = cfg.MMR.TopK
+	}
+	if cfg.MMR.CandidateFactor > 0 {
+		mmrConfig.CandidateFactor = cfg.MMR.CandidateFactor
+	}
+	if cfg.MMR.Similarity != "" {
+		mmrConfig.Similarity = cfg.MMR.Similarity
+	}
+	log.Infof("🎯 MMR diversification enabled (stage=%s, lambda=%.2f)", cfg.MMR.Stage, mmrConfig.Lambda)
+	return retrieval.NewMMRReranker(mmrConfig, vectorRetriever)
+}
+
+// newRerankStage 根据配置创建重排阶段（未启用时返回 nil；http / llm 失败时退回离线的词项重排）
+func newRerankStage(cfg *config.Config, llmProvider *llm.ZhipuLLM, bm25Retriever *retrieval.BM25Retriever) *rerank.Stage {
+	if !cfg.Rerank.Enabled {
+		return nil
+	}
+
+	stageConfig := rerank.DefaultStageConfig()
+	if cfg.Rerank.TopN > 0 {
+		stageConfig.TopN = cfg.Rerank.TopN
+	}
+	if cfg.Rerank.TopK > 0 {
+		stageConfig.TopK = cfg.Rerank.TopK
+	}
+
+	lexical := rerank.NewLexicalReranker(bm25Retriever.Tokenize)
+	switch cfg.Rerank.Provider {
+	case "http":
+		httpConfig := rerank.DefaultHTTPConfig()
+		if cfg.Rerank.HTTP.URL != "" {
+			httpConfig.URL = cfg.Rerank.HTTP.URL
+		}
+		if cfg.Rerank.HTTP.Model != "" {
+			httpConfig.Model = cfg.Rerank.HTTP.Model
+		}
+		if cfg.Rerank.HTTP.Timeout > 0 {
+			httpConfig.Timeout = time.Duration(cfg.Rerank.HTTP.Timeout) * time.Second
+		}
+		if cfg.Rerank.HTTP.MaxDocumentRunes > 0 {
+			httpConfig.MaxDocumentRunes = cfg.Rerank.HTTP.MaxDocumentRunes
+		}
+		httpConfig.APIKey = cfg.Rerank.HTTP.APIKey
+		log.Infof("🏅 Rerank enabled: http (%s), top %d → %d", httpConfig.URL, stageConfig.TopN, stageConfig.TopK)
+		return rerank.NewStage(stageConfig, rerank.NewHTTPReranker(httpConfig), lexical)
+	case "llm":
+		if llmProvider == nil {
+			log.Warnf("⚠️  LLM is not available for reranking, using lexical reranker")
+			break
+		}
+		llmConfig := rerank.DefaultLLMConfig()
+		if cfg.Rerank.LLM.Concurrency > 0 {
+			llmConfig.Concurrency = cfg.Rerank.LLM.Concurrency
+		}
+		if cfg.Rerank.LLM.MaxDocumentRunes > 0 {
+			llmConfig.MaxDocumentRunes = cfg.Rerank.LLM.MaxDocumentRunes
+		}
+		log.Infof("🏅 Rerank enabled: llm, top %d → %d", stageConfig.TopN, stageConfig.TopK)
+		return rerank.NewStage(stageConfig, rerank.NewLLMReranker(llmConfig, llmProvider), lexical)
+	}
+
+	log.Infof("🏅 Rerank enabled: lexical, top %d → %d", stageConfig.TopN, stageConfig.TopK)
+	return rerank.NewStage(stageConfig, lexical, nil)
+}
+
+// newChunker 根据配置创建菜谱分块器（未启用时返回 nil，整篇文档直接索引）
+func newChunker(cfg *config.Config) *chunker.Chunker {
+	if !cfg.Chunking.Enabled {
+		return nil
+	}
+
+	chunkerConfig := chunker.DefaultChunkerConfig()
+	if cfg.Chunking.MaxTokens > 0 {
+		chunkerConfig.MaxTokens = cfg.Chunking.MaxTokens
+	}
+	if cfg.Chunking.OverlapTokens > 0 {
+		chunkerConfig.OverlapTokens = cfg.Chunking.OverlapTokens
+	}
+	if cfg.Chunking.MinTokens > 0 {
+		chunkerConfig.MinTokens = cfg.Chunking.MinTokens
+	}
+	if cfg.Chunking.HeadingLevel > 0 {
+		chunkerConfig.HeadingLevel = cfg.Chunking.HeadingLevel
+	}
+	return chunker.NewChunker(chunkerConfig)
+}
+
+// newCorrector 根据配置创建查询纠错器（未启用时返回 nil）
+// 同义词表中的词也加入词表，避免"马铃薯"之类的别名被纠成其他词
+func newCorrector(cfg *config.Config, synonyms *synonym.Registry) *fuzzy.Corrector {
+	if !cfg.Fuzzy.Enabled {
+		return nil
+	}
+
+	correctorConfig := fuzzy.DefaultCorrectorConfig()
+	if cfg.Fuzzy.Mode != "" {
+		correctorConfig.Mode = cfg.Fuzzy.Mode
+	}
+	if cfg.Fuzzy.MaxEditDistance > 0 {
+		correctorConfig.MaxEditDistance = cfg.Fuzzy.MaxEditDistance
+	}
+	if cfg.Fuzzy.MinEditTermLength > 0 {
+		correctorConfig.MinEditTermLength = cfg.Fuzzy.MinEditTermLength
+	}
+	if cfg.Fuzzy.MaxPinyinDistance > 0 {
+		correctorConfig.MaxPinyinDistance = cfg.Fuzzy.MaxPinyinDistance
+	}
+
+	corrector := fuzzy.NewCorrector(correctorConfig)
+	for _, path := range cfg.Fuzzy.VocabularyPaths {
+		if err := corrector.LoadVocabulary(path); err != nil {
+			log.Warnf("⚠️  Failed to load fuzzy vocabulary: %v", err)
+		}
+	}
+	for canonical, aliases := range synonyms.Groups() {
+		corrector.AddTerms(fuzzy.KindIngredient, append([]string{canonical}, aliases...)...)
+	}
+	return corrector
+}
+
 // getSampleDocuments 获取示例文档（作为后备）
 func getSampleDocuments() []models.Document {
 	return []models.Document{
@@ -488,27 +800,30 @@ func getSampleDocuments() []models.Document {
 			ID:      "doc1",
 			Content: "红烧肉是一道经典的中国菜，主要食材是五花肉，用酱油、糖、料酒等调料炖煮而成。做法是先将五花肉切块焯水，然后用糖炒糖色，加入酱油、料酒、八角、桂皮等调料小火慢炖1-2小时，直到肉质软烂，肥而不腻。红烧肉富含蛋白质和脂肪，是中式料理的代表之一。",
 			Metadata: map[string]interface{}{
-				"category": "肉类",
-				"cuisine":  "中式",
-				"difficulty": "简单",
+				"category":    "meat_dish",
+				"cuisine":     "中式",
+				"difficulty":  3,
+				"ingredients": []string{"五花肉", "酱油", "料酒", "八角", "桂皮"},
 			},
 		},
 		{
 			ID:      "doc2",
 			Content: "宫保鸡丁是四川传统名菜，属于川菜代表。主料是鸡胸肉和花生米，调料包括干辣椒、花椒、葱姜蒜、糖醋汁。制作要点是先将鸡胸肉切丁上浆，然后热油快炒，保持鸡肉嫩滑。特点是酸甜微辣，鸡肉嫩滑，花生酥脆，营养均衡。",
 			Metadata: map[string]interface{}{
-				"category": "肉类",
-				"cuisine":  "川菜",
-				"difficulty": "中等",
+				"category":    "meat_dish",
+				"cuisine":     "川菜",
+				"difficulty":  3,
+				"ingredients": []string{"鸡胸肉", "花生米", "干辣椒", "花椒"},
 			},
 		},
 		{
 			ID:      "doc3",
 			Content: "麻婆豆腐是川菜中的经典素食菜品，发明于清朝同治年间。主要食材是嫩豆腐和牛肉末，调料有豆瓣酱、花椒、辣椒面。特点是麻、辣、鲜、香、烫，口感丰富。制作关键是豆腐要先焯水去豆腥味，炒制时要小火慢炖让豆腐充分入味。",
 			Metadata: map[string]interface{}{
-				"category": "素食",
-				"cuisine":  "川菜",
-				"difficulty": "简单",
+				"category":    "vegetable_dish",
+				"cuisine":     "川菜",
+				"difficulty":  2,
+				"ingredients": []string{"豆腐", "牛肉末", "豆瓣酱", "花椒", "辣椒面"},
 			},
 		},
 	}
@@ -16,6 +16,7 @@ import (
 	"cookrag-go/pkg/storage/cache"
 	"cookrag-go/pkg/storage/milvus"
 	"cookrag-go/pkg/storage/neo4j"
+	"cookrag-go/pkg/storage/vectorstore"
 )
 
 // initLoggingWithFile 初始化日志配置（同时输出到终端和文件）
@@ -65,15 +66,22 @@ func main() {
 	})
 
 	milvusClient, _ := milvus.NewClient(cfg.Milvus.Host, cfg.Milvus.Port)
+	var vectorStore vectorstore.Store
+	if milvusClient != nil {
+		if err := milvusClient.SetIndexConfig(newMilvusIndexConfig(cfg)); err != nil {
+			log.Warnf("⚠️  Invalid Milvus index config: %v, using default IVF_FLAT/L2", err)
+		}
+		vectorStore = vectorstore.NewMilvusStore(milvusClient)
+	}
 
 	neo4jClient, _ := neo4j.NewClient(cfg.Neo4j.URI, cfg.Neo4j.Username, cfg.Neo4j.Password, cfg.Neo4j.Database)
 
 	redisClient, _ := cache.NewRedisClient(cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.Password, cfg.Redis.DB)
 
 	// 创建检索器
-	vectorRetriever := retrieval.NewVectorRetriever(retrieval.DefaultVectorRetrieverConfig(), embeddingProvider, milvusClient, redisClient)
+	vectorRetriever := retrieval.NewVectorRetriever(retrieval.DefaultVectorRetrieverConfig(), embeddingProvider, vectorStore, redisClient)
 	bm25Retriever := retrieval.NewBM25Retriever(retrieval.DefaultBM25Config())
-	graphRetriever := retrieval.NewGraphRetriever(retrieval.DefaultGraphRetrieverConfig(), neo4jClient)
+	graphRetriever := retrieval.NewGraphRetriever(retrieval.DefaultGraphRetrieverConfig(), neo4jClient, bm25Retriever.DocumentStore())
 	hybridRetriever := retrieval.NewHybridRetriever(retrieval.DefaultHybridRetrieverConfig(), vectorRetriever, bm25Retriever)
 
 	// 创建路由器
@@ -120,7 +128,36 @@ func main() {
 	}
 
 	// 清理
-	milvusClient.Close(ctx)
+	if vectorStore != nil {
+		vectorStore.Close(ctx)
+	}
 	neo4jClient.Close(ctx)
 	redisClient.Close()
 }
+
+// newMilvusIndexConfig 根据配置文件生成 Milvus 索引配置（未配置的参数使用默认值）
+func newMilvusIndexConfig(cfg *config.Config) *milvus.IndexConfig {
+	indexConfig := milvus.DefaultIndexConfig()
+	if cfg.Milvus.IndexType != "" {
+		indexConfig.IndexType = cfg.Milvus.IndexType
+	}
+	if cfg.Milvus.MetricType != "" {
+		indexConfig.MetricType = cfg.Milvus.MetricType
+	}
+	if cfg.Milvus.IndexParams.NList > 0 {
+		indexConfig.NList = cfg.Milvus.IndexParams.NList
+	}
+	if cfg.Milvus.IndexParams.M > 0 {
+		indexConfig.M = cfg.Milvus.IndexParams.M
+	}
+	if cfg.Milvus.IndexParams.EfConstruction > 0 {
+		indexConfig.EfConstruction = cfg.Milvus.IndexParams.EfConstruction
+	}
+	if cfg.Milvus.SearchParams.NProbe > 0 {
+		indexConfig.NProbe = cfg.Milvus.SearchParams.NProbe
+	}
+	if cfg.Milvus.SearchParams.Ef > 0 {
+		indexConfig.Ef = cfg.Milvus.SearchParams.Ef
+	}
+	return indexConfig
+}
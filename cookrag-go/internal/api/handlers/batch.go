@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"cookrag-go/internal/core/retrieval"
+	"cookrag-go/internal/models"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// maxBatchQueries 单次批量查询的最大查询数
+const maxBatchQueries = 32
+
+// BatchQueryHandler 批量向量检索处理器
+type BatchQueryHandler struct {
+	retriever *retrieval.VectorRetriever
+}
+
+// NewBatchQueryHandler 创建批量向量检索处理器
+func NewBatchQueryHandler(retriever *retrieval.VectorRetriever) *BatchQueryHandler {
+	return &BatchQueryHandler{
+		retriever: retriever,
+	}
+}
+
+// BatchQueryRequest 批量查询请求
+type BatchQueryRequest struct {
+	Queries []string          `json:"queries" binding:"required"`
+	Filter  *retrieval.Filter `json:"filter,omitempty"` // 元数据过滤，对全部查询生效
+}
+
+// BatchQueryResponse 批量查询响应（Results 与请求中的 Queries 一一对应）
+type BatchQueryResponse struct {
+	Results []*models.RetrievalResult `json:"results"`
+	Latency float64                   `json:"latency_ms"`
+}
+
+// HandleBatchQuery 处理批量查询请求（向量检索，每个查询单独返回结果）
+func (h *BatchQueryHandler) HandleBatchQuery(c *gin.Context) {
+	var req BatchQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if len(req.Queries) == 0 || len(req.Queries) > maxBatchQueries {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid queries",
+			"details": fmt.Sprintf("queries must contain 1 to %d items", maxBatchQueries),
+		})
+		return
+	}
+	for _, query := range req.Queries {
+		if strings.TrimSpace(query) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid queries",
+				"details": "queries must not be empty",
+			})
+			return
+		}
+	}
+
+	if err := req.Filter.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid filter",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Infof("📥 Received batch query: %d queries", len(req.Queries))
+
+	startTime := time.Now()
+	ctx := retrieval.WithFilter(c.Request.Context(), req.Filter)
+	results, err := h.retriever.RetrieveBatch(ctx, req.Queries)
+	if err != nil {
+		log.Errorf("❌ Batch query failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Batch query processing failed",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, BatchQueryResponse{
+		Results: results,
+		Latency: float64(time.Since(startTime).Milliseconds()),
+	})
+}
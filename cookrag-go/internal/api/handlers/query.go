@@ -5,6 +5,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/charmbracelet/log"
+	"cookrag-go/internal/core/retrieval"
 	"cookrag-go/internal/core/router"
 	"cookrag-go/internal/models"
 )
@@ -25,7 +26,11 @@ func NewQueryHandler(r *router.QueryRouter, llm any) *QueryHandler {
 
 // QueryRequest 查询请求
 type QueryRequest struct {
-	Query string `json:"query" binding:"required"`
+	Query   string            `json:"query" binding:"required"`
+	Explain bool              `json:"explain"`          // 返回分数解释（也可用 ?explain=true）
+	Filter  *retrieval.Filter `json:"filter,omitempty"` // 元数据过滤（分类、菜系、难度范围、包含原料），对所有检索策略生效
+	Fusion  string            `json:"fusion,omitempty"` // 混合检索的融合方法（rrf / combsum_minmax / combsum_zscore / combmnz_minmax / combmnz_zscore / dbsf），为空时使用配置
+	Expand  *bool             `json:"expand,omitempty"` // 混合检索的查询扩展（LLM 改写 + HyDE）开关，为空时使用配置
 }
 
 // QueryResponse 查询响应
@@ -34,6 +39,13 @@ type QueryResponse struct {
 	Documents []models.Document `json:"documents"`
 	Strategy  string            `json:"strategy"`
 	Latency   float64           `json:"latency_ms"`
+	// 查询纠错（没有纠错时省略）
+	RewrittenQuery string                   `json:"rewritten_query,omitempty"`
+	Corrections    []models.QueryCorrection `json:"corrections,omitempty"`
+	// 查询扩展的变体及各自的命中数（没有扩展时省略）
+	Variants []models.QueryVariant `json:"variants,omitempty"`
+	// 策略回退记录（没有回退时省略）
+	Fallbacks []models.StrategyFallback `json:"fallbacks,omitempty"`
 }
 
 // HandleQuery 处理查询请求
@@ -47,10 +59,37 @@ func (h *QueryHandler) HandleQuery(c *gin.Context) {
 		return
 	}
 
+	if err := req.Filter.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid filter",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := retrieval.ValidateFusion(req.Fusion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid fusion method",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	log.Infof("📥 Received query: %s", req.Query)
 
+	// 分数解释：BM25 词项明细和混合检索的 RRF 排名
+	ctx := c.Request.Context()
+	if req.Explain || c.Query("explain") == "true" {
+		ctx = retrieval.WithExplain(ctx)
+	}
+	ctx = retrieval.WithFilter(ctx, req.Filter)
+	ctx = retrieval.WithFusion(ctx, req.Fusion)
+	if req.Expand != nil {
+		ctx = retrieval.WithExpansion(ctx, *req.Expand)
+	}
+
 	// 调用路由器进行检索
-	result, err := h.router.Route(c.Request.Context(), req.Query)
+	result, err := h.router.Route(ctx, req.Query)
 	if err != nil {
 		log.Errorf("❌ Query failed: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -62,10 +101,14 @@ func (h *QueryHandler) HandleQuery(c *gin.Context) {
 
 	// 构建响应
 	response := QueryResponse{
-		Answer:    "", // LLM生成的答案将在后续添加
-		Documents: result.Documents,
-		Strategy:  result.Strategy,
-		Latency:   result.Latency,
+		Answer:         "", // LLM生成的答案将在后续添加
+		Documents:      result.Documents,
+		Strategy:       result.Strategy,
+		Latency:        result.Latency,
+		RewrittenQuery: result.RewrittenQuery,
+		Corrections:    result.Corrections,
+		Variants:       result.Variants,
+		Fallbacks:      result.Fallbacks,
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"cookrag-go/internal/synonym"
+
+	"github.com/charmbracelet/log"
+	"github.com/gin-gonic/gin"
+)
+
+// SynonymHandler 同义词管理处理器
+type SynonymHandler struct {
+	registry *synonym.Registry
+}
+
+// NewSynonymHandler 创建同义词管理处理器
+func NewSynonymHandler(registry *synonym.Registry) *SynonymHandler {
+	return &SynonymHandler{
+		registry: registry,
+	}
+}
+
+// SynonymGroup 一组同义词
+type SynonymGroup struct {
+	Canonical string   `json:"canonical" binding:"required"`
+	Aliases   []string `json:"aliases" binding:"required"`
+}
+
+// HandleList 列出所有同义词组
+func (h *SynonymHandler) HandleList(c *gin.Context) {
+	groups := h.registry.Groups()
+	c.JSON(http.StatusOK, gin.H{
+		"groups": groups,
+		"count":  len(groups),
+	})
+}
+
+// HandlePut 新增或替换一组同义词（立即生效并写回文件）
+func (h *SynonymHandler) HandlePut(c *gin.Context) {
+	var req SynonymGroup
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.registry.Put(req.Canonical, req.Aliases); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, synonym.ErrEmptyTerm) || len(req.Aliases) == 0 {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "Failed to update synonyms",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	log.Infof("📝 Synonym group updated: %s -> %v", req.Canonical, req.Aliases)
+	c.JSON(http.StatusOK, SynonymGroup{
+		Canonical: req.Canonical,
+		Aliases:   h.registry.Groups()[req.Canonical],
+	})
+}
+
+// HandleDelete 删除一组同义词
+func (h *SynonymHandler) HandleDelete(c *gin.Context) {
+	canonical := c.Param("canonical")
+
+	existed, err := h.registry.Delete(canonical)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete synonyms",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !existed {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Synonym group not found",
+		})
+		return
+	}
+
+	log.Infof("🗑️  Synonym group deleted: %s", canonical)
+	c.Status(http.StatusNoContent)
+}
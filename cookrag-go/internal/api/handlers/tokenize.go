@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+
+	"cookrag-go/internal/core/retrieval"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TokenizeHandler 分词调试处理器
+type TokenizeHandler struct {
+	tokenizer *retrieval.Tokenizer
+}
+
+// NewTokenizeHandler 创建分词调试处理器
+func NewTokenizeHandler(tokenizer *retrieval.Tokenizer) *TokenizeHandler {
+	return &TokenizeHandler{
+		tokenizer: tokenizer,
+	}
+}
+
+// TokenizeRequest 分词请求
+type TokenizeRequest struct {
+	Text string `json:"text" binding:"required"`
+}
+
+// TokenizeResponse 分词响应
+type TokenizeResponse struct {
+	Text   string                 `json:"text"`
+	Tokens []retrieval.TokenInfo  `json:"tokens"` // jieba 原始切分及过滤结果
+	Terms  []string               `json:"terms"`  // 实际用于检索的词项
+	Stats  map[string]interface{} `json:"stats"`
+}
+
+// HandleTokenize 展示一段文本的分词结果（用于调试词典和停用词）
+func (h *TokenizeHandler) HandleTokenize(c *gin.Context) {
+	var req TokenizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, TokenizeResponse{
+		Text:   req.Text,
+		Tokens: h.tokenizer.Segment(req.Text),
+		Terms:  h.tokenizer.Tokenize(req.Text),
+		Stats:  h.tokenizer.Stats(),
+	})
+}
@@ -9,17 +9,22 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/charmbracelet/log"
 	"cookrag-go/internal/api/handlers"
+	"cookrag-go/internal/core/retrieval"
 	"cookrag-go/internal/core/router"
+	"cookrag-go/internal/synonym"
 )
 
 // Server HTTP服务器
 type Server struct {
-	router       *gin.Engine
-	httpServer   *http.Server
-	port         int
-	queryRouter  *router.QueryRouter
-	llmProvider  any // LLM provider (can be nil initially)
-	queryHandler *handlers.QueryHandler
+	router          *gin.Engine
+	httpServer      *http.Server
+	port            int
+	queryRouter     *router.QueryRouter
+	llmProvider     any // LLM provider (can be nil initially)
+	queryHandler    *handlers.QueryHandler
+	tokenizeHandler *handlers.TokenizeHandler   // 分词调试（可选）
+	synonymHandler  *handlers.SynonymHandler    // 同义词管理（可选）
+	batchHandler    *handlers.BatchQueryHandler // 批量向量检索（可选）
 }
 
 // Config 服务器配置
@@ -73,6 +78,21 @@ func NewServer(config *Config, queryRouter *router.QueryRouter, llmProvider any)
 	}
 }
 
+// SetTokenizer 注册分词调试接口（需在 Start 之前调用）
+func (s *Server) SetTokenizer(tokenizer *retrieval.Tokenizer) {
+	s.tokenizeHandler = handlers.NewTokenizeHandler(tokenizer)
+}
+
+// SetSynonyms 注册同义词管理接口（需在 Start 之前调用）
+func (s *Server) SetSynonyms(registry *synonym.Registry) {
+	s.synonymHandler = handlers.NewSynonymHandler(registry)
+}
+
+// SetBatchRetriever 注册批量向量检索接口（需在 Start 之前调用）
+func (s *Server) SetBatchRetriever(retriever *retrieval.VectorRetriever) {
+	s.batchHandler = handlers.NewBatchQueryHandler(retriever)
+}
+
 // Start 启动服务器
 func (s *Server) Start() error {
 	s.setupRoutes()
@@ -99,12 +119,29 @@ func (s *Server) setupRoutes() {
 		// 查询接口
 		api.POST("/query", s.queryHandler.HandleQuery)
 
+		// 批量向量检索
+		if s.batchHandler != nil {
+			api.POST("/query/batch", s.batchHandler.HandleBatchQuery)
+		}
+
 		// 健康检查
 		api.GET("/health", s.queryHandler.HandleHealth)
 		api.GET("/ready", s.queryHandler.HandleReady)
 
 		// 指标
 		api.GET("/metrics", s.queryHandler.HandleMetrics)
+
+		// 分词调试
+		if s.tokenizeHandler != nil {
+			api.POST("/debug/tokenize", s.tokenizeHandler.HandleTokenize)
+		}
+
+		// 同义词管理
+		if s.synonymHandler != nil {
+			api.GET("/admin/synonyms", s.synonymHandler.HandleList)
+			api.PUT("/admin/synonyms", s.synonymHandler.HandlePut)
+			api.DELETE("/admin/synonyms/:canonical", s.synonymHandler.HandleDelete)
+		}
 	}
 
 	// 根路径
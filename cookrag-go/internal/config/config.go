@@ -11,8 +11,19 @@ type Config struct {
 	Server     ServerConfig     `mapstructure:"server"`
 	Embedding  EmbeddingConfig  `mapstructure:"embedding"`
 	Milvus     MilvusConfig     `mapstructure:"milvus"`
+	VectorStore VectorStoreConfig `mapstructure:"vector_store"`
+	Vector     VectorConfig     `mapstructure:"vector"`
+	Chunking   ChunkingConfig   `mapstructure:"chunking"`
 	Neo4j      Neo4jConfig      `mapstructure:"neo4j"`
 	Redis      RedisConfig      `mapstructure:"redis"`
+	BM25       BM25Config       `mapstructure:"bm25"`
+	Hybrid     HybridConfig     `mapstructure:"hybrid"`
+	Tokenizer  TokenizerConfig  `mapstructure:"tokenizer"`
+	Synonyms   SynonymsConfig   `mapstructure:"synonyms"`
+	Fuzzy      FuzzyConfig      `mapstructure:"fuzzy"`
+	MMR        MMRConfig        `mapstructure:"mmr"`
+	Rerank     RerankConfig     `mapstructure:"rerank"`
+	Router     RouterConfig     `mapstructure:"router"`
 	LLM        LLMConfig        `mapstructure:"llm"`
 	Observability ObservabilityConfig `mapstructure:"observability"`
 }
@@ -34,15 +45,61 @@ type EmbeddingConfig struct {
 }
 
 type MilvusConfig struct {
-	Host           string `mapstructure:"host"`
-	Port           string `mapstructure:"port"`
-	Username       string `mapstructure:"username"`
-	Password       string `mapstructure:"password"`
-	Database       string `mapstructure:"database"`
-	CollectionName string `mapstructure:"collection_name"`
-	Dimension      int    `mapstructure:"dimension"`
-	IndexType      string `mapstructure:"index_type"`
-	MetricType     string `mapstructure:"metric_type"`
+	Host           string             `mapstructure:"host"`
+	Port           string             `mapstructure:"port"`
+	Username       string             `mapstructure:"username"`
+	Password       string             `mapstructure:"password"`
+	Database       string             `mapstructure:"database"`
+	CollectionName string             `mapstructure:"collection_name"`
+	Dimension      int                `mapstructure:"dimension"`
+	IndexType      string             `mapstructure:"index_type"`
+	MetricType     string             `mapstructure:"metric_type"`
+	IndexParams    MilvusIndexParams  `mapstructure:"index_params"`
+	SearchParams   MilvusSearchParams `mapstructure:"search_params"`
+}
+
+type MilvusIndexParams struct {
+	NList          int `mapstructure:"nlist"`
+	M              int `mapstructure:"m"`
+	EfConstruction int `mapstructure:"ef_construction"`
+}
+
+type MilvusSearchParams struct {
+	NProbe int `mapstructure:"nprobe"`
+	Ef     int `mapstructure:"ef"`
+}
+
+type VectorStoreConfig struct {
+	Backend string     `mapstructure:"backend"`
+	Path    string     `mapstructure:"path"`
+	Index   string     `mapstructure:"index"`
+	HNSW    HNSWConfig `mapstructure:"hnsw"`
+}
+
+type VectorConfig struct {
+	SentenceHighlight   bool `mapstructure:"sentence_highlight"`
+	MaxSnippetSentences int  `mapstructure:"max_snippet_sentences"`
+}
+
+type HNSWConfig struct {
+	M              int `mapstructure:"m"`
+	EfConstruction int `mapstructure:"ef_construction"`
+	EfSearch       int `mapstructure:"ef_search"`
+}
+
+type ChunkingConfig struct {
+	Enabled        bool                 `mapstructure:"enabled"`
+	MaxTokens      int                  `mapstructure:"max_tokens"`
+	OverlapTokens  int                  `mapstructure:"overlap_tokens"`
+	MinTokens      int                  `mapstructure:"min_tokens"`
+	HeadingLevel   int                  `mapstructure:"heading_level"`
+	ParentDocument ParentDocumentConfig `mapstructure:"parent_document"`
+}
+
+type ParentDocumentConfig struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	ScoreMode        string `mapstructure:"score_mode"`
+	MaxContextTokens int    `mapstructure:"max_context_tokens"`
 }
 
 type Neo4jConfig struct {
@@ -59,6 +116,96 @@ type RedisConfig struct {
 	DB       int    `mapstructure:"db"`
 }
 
+type BM25Config struct {
+	K1           float64                    `mapstructure:"k1"`
+	B            float64                    `mapstructure:"b"`
+	Variant      string                     `mapstructure:"variant"`
+	Delta        float64                    `mapstructure:"delta"`
+	DocStorePath string                     `mapstructure:"doc_store_path"`
+	SnapshotPath string                     `mapstructure:"snapshot_path"`
+	Fields       map[string]BM25FieldConfig `mapstructure:"fields"`
+}
+
+type BM25FieldConfig struct {
+	Weight float64 `mapstructure:"weight"`
+	B      float64 `mapstructure:"b"`
+}
+
+type HybridConfig struct {
+	Fusion          string                `mapstructure:"fusion"`
+	RRFK            int                   `mapstructure:"rrf_k"`
+	VectorWeight    float64               `mapstructure:"vector_weight"`
+	BM25Weight      float64               `mapstructure:"bm25_weight"`
+	GraphWeight     float64               `mapstructure:"graph_weight"`
+	SourceTimeoutMs int                   `mapstructure:"source_timeout_ms"`
+	Expansion       HybridExpansionConfig `mapstructure:"expansion"`
+}
+
+type HybridExpansionConfig struct {
+	Enabled        bool    `mapstructure:"enabled"`
+	Paraphrases    int     `mapstructure:"paraphrases"`
+	HyDE           bool    `mapstructure:"hyde"`
+	MaxHyDERunes   int     `mapstructure:"max_hyde_runes"`
+	Timeout        int     `mapstructure:"timeout"`
+	OriginalWeight float64 `mapstructure:"original_weight"`
+	VariantWeight  float64 `mapstructure:"variant_weight"`
+}
+
+type TokenizerConfig struct {
+	UserDictPaths  []string `mapstructure:"user_dict_paths"`
+	StopWordsPaths []string `mapstructure:"stop_words_paths"`
+}
+
+type SynonymsConfig struct {
+	Path string `mapstructure:"path"`
+}
+
+type FuzzyConfig struct {
+	Enabled           bool     `mapstructure:"enabled"`
+	Mode              string   `mapstructure:"mode"`
+	MaxEditDistance   int      `mapstructure:"max_edit_distance"`
+	MinEditTermLength int      `mapstructure:"min_edit_term_length"`
+	MaxPinyinDistance int      `mapstructure:"max_pinyin_distance"`
+	VocabularyPaths   []string `mapstructure:"vocabulary_paths"`
+}
+
+type MMRConfig struct {
+	Enabled         bool    `mapstructure:"enabled"`
+	Stage           string  `mapstructure:"stage"`
+	Lambda          float64 `mapstructure:"lambda"`
+	TopK            int     `mapstructure:"top_k"`
+	CandidateFactor int     `mapstructure:"candidate_factor"`
+	Similarity      string  `mapstructure:"similarity"`
+}
+
+type RerankConfig struct {
+	Enabled  bool             `mapstructure:"enabled"`
+	Provider string           `mapstructure:"provider"`
+	TopN     int              `mapstructure:"top_n"`
+	TopK     int              `mapstructure:"top_k"`
+	HTTP     RerankHTTPConfig `mapstructure:"http"`
+	LLM      RerankLLMConfig  `mapstructure:"llm"`
+}
+
+type RerankHTTPConfig struct {
+	URL              string `mapstructure:"url"`
+	APIKey           string `mapstructure:"api_key"`
+	Model            string `mapstructure:"model"`
+	Timeout          int    `mapstructure:"timeout"`
+	MaxDocumentRunes int    `mapstructure:"max_document_runes"`
+}
+
+type RerankLLMConfig struct {
+	Concurrency      int `mapstructure:"concurrency"`
+	MaxDocumentRunes int `mapstructure:"max_document_runes"`
+}
+
+type RouterConfig struct {
+	Fallbacks   map[string][]string `mapstructure:"fallbacks"`
+	MinTopScore map[string]float64  `mapstructure:"min_top_score"`
+	BM25TopK    int                 `mapstructure:"bm25_top_k"`
+}
+
 type LLMConfig struct {
 	Provider    string `mapstructure:"provider"`
 	Model       string `mapstructure:"model"`
@@ -122,6 +269,7 @@ func Load(configPath string) (*Config, error) {
 
 	config.Embedding.APIKey = getEnvValue(config.Embedding.APIKey)
 	config.LLM.APIKey = getEnvValue(config.LLM.APIKey)
+	config.Rerank.HTTP.APIKey = getEnvValue(config.Rerank.HTTP.APIKey)
 	config.Neo4j.Username = getEnvValue(config.Neo4j.Username)
 	config.Neo4j.Password = getEnvValue(config.Neo4j.Password)
 	config.Redis.Password = getEnvValue(config.Redis.Password)
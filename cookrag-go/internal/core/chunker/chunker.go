@@ -0,0 +1,414 @@
+package chunker
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"cookrag-go/internal/core/retrieval"
+	"cookrag-go/internal/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// dishTitleSuffix 菜谱一级标题的后缀（"# 红烧肉的做法" 的菜名为 红烧肉）
+const dishTitleSuffix = "的做法"
+
+// ChunkerConfig 分块配置（长度均按 token 计算）
+type ChunkerConfig struct {
+	MaxTokens       int  // 每个分块的最大 token 数（含标题前缀）
+	OverlapTokens   int  // 同一章节拆成多块时，相邻分块重叠的 token 数（不同章节之间不重叠）
+	MinTokens       int  // 不足该长度的分块与下一章节合并
+	HeadingLevel    int  // 按 1 到 HeadingLevel 级标题切分章节，更深的标题视为正文
+	IncludeHeadings bool // 每个分块开头补上菜名和上级章节标题，分块脱离原文也能看出出处
+}
+
+// DefaultChunkerConfig 默认分块配置（512 token，重叠 64 token，按三级以内标题切分）
+func DefaultChunkerConfig() *ChunkerConfig {
+	return &ChunkerConfig{
+		MaxTokens:       512,
+		OverlapTokens:   64,
+		MinTokens:       48,
+		HeadingLevel:    3,
+		IncludeHeadings: true,
+	}
+}
+
+// TokenCounter 计算文本的 token 数
+type TokenCounter func(text string) int
+
+// Chunker Markdown 菜谱分块器
+// 先按标题把文档切成章节，过短的章节与后续章节合并，超长的章节依次按段落、行、句子拆分
+type Chunker struct {
+	config  *ChunkerConfig
+	counter TokenCounter
+}
+
+// NewChunker 创建分块器（默认使用 retrieval.EstimateTokens 估算 token 数）
+func NewChunker(config *ChunkerConfig) *Chunker {
+	if config == nil {
+		config = DefaultChunkerConfig()
+	}
+
+	return &Chunker{
+		config:  config,
+		counter: retrieval.EstimateTokens,
+	}
+}
+
+// SetTokenCounter 设置 token 计数方式（如与 Embedding 模型一致的分词器）
+func (c *Chunker) SetTokenCounter(counter TokenCounter) {
+	if counter != nil {
+		c.counter = counter
+	}
+}
+
+// heading 标题
+type heading struct {
+	seq   int    // 在文档中的序号（同名标题也能区分）
+	level int    // 标题级别（# 为 1）
+	line  string // 原始标题行
+	text  string // 标题文本
+}
+
+// piece 分块的组成单元：一个章节或超长章节拆出的一段
+type piece struct {
+	path   []heading // 标题路径（从一级标题到所在章节）
+	body   string    // 正文
+	tokens int       // 正文和标题路径的 token 数
+}
+
+// Split 把文档切分为分块
+// 分块ID为 原文档ID#序号，元数据复制原文档并写入 parent_id、section、dish、chunk_index、chunk_count
+func (c *Chunker) Split(doc models.Document) []models.Document {
+	if strings.TrimSpace(doc.Content) == "" {
+		return nil
+	}
+
+	sections := parseSections(doc.Content, c.config.HeadingLevel)
+	groups := c.merge(c.pieces(sections))
+
+	parentID := retrieval.StableDocumentID(doc)
+	dish := dishName(doc, sections)
+
+	chunks := make([]models.Document, 0, len(groups))
+	var emitted []heading
+	for i, group := range groups {
+		if c.config.IncludeHeadings {
+			emitted = nil
+		}
+
+		var content strings.Builder
+		labels := make([]string, 0, len(group))
+		for _, p := range group {
+			emitted = writePiece(&content, emitted, p)
+			labels = appendUnique(labels, sectionLabel(p.path))
+		}
+
+		metadata := make(map[string]interface{}, len(doc.Metadata)+5)
+		for k, v := range doc.Metadata {
+			metadata[k] = v
+		}
+		metadata[retrieval.ParentMetadataKey] = parentID
+		metadata[retrieval.ChunkMetadataKey] = i
+		metadata[retrieval.ChunkCountMetadataKey] = len(groups)
+		metadata[retrieval.SectionMetadataKey] = strings.Join(labels, "、")
+		if dish != "" {
+			metadata[retrieval.DishMetadataKey] = dish
+		}
+
+		chunks = append(chunks, models.Document{
+			ID:       fmt.Sprintf("%s#%d", parentID, i),
+			Content:  strings.TrimSpace(content.String()),
+			Metadata: metadata,
+		})
+	}
+	return chunks
+}
+
+// SplitAll 切分多篇文档
+func (c *Chunker) SplitAll(documents []models.Document) []models.Document {
+	chunks := make([]models.Document, 0, len(documents))
+	for _, doc := range documents {
+		chunks = append(chunks, c.Split(doc)...)
+	}
+
+	log.Infof("✂️  Split %d documents into %d chunks (max_tokens=%d, overlap=%d)",
+		len(documents), len(chunks), c.config.MaxTokens, c.config.OverlapTokens)
+	return chunks
+}
+
+// pieces 把章节转换为分块单元，超过长度上限的章节拆成多段（段与段之间重叠 OverlapTokens）
+func (c *Chunker) pieces(sections []piece) []piece {
+	result := make([]piece, 0, len(sections))
+	for _, section := range sections {
+		prefixTokens := 0
+		if c.config.IncludeHeadings {
+			prefixTokens = c.pathTokens(section.path)
+		}
+		// 标题本身过长时至少给正文留出四分之一的空间
+		budget := max(c.config.MaxTokens-prefixTokens, c.config.MaxTokens/4, 1)
+
+		bodyTokens := c.counter(section.body)
+		if bodyTokens <= budget {
+			section.tokens = bodyTokens + c.pathTokens(section.path)
+			result = append(result, section)
+			continue
+		}
+
+		for _, body := range c.pack(c.units(section.body, budget, 0), budget) {
+			result = append(result, piece{
+				path:   section.path,
+				body:   body,
+				tokens: c.counter(body) + c.pathTokens(section.path),
+			})
+		}
+	}
+	return result
+}
+
+// merge 把不足 MinTokens 的分块与下一个单元合并（合并后不超过 MaxTokens），最后一块过短时并入前一块
+func (c *Chunker) merge(pieces []piece) [][]piece {
+	groups := make([][]piece, 0, len(pieces))
+	var current []piece
+	currentTokens := 0
+	for _, p := range pieces {
+		if len(current) > 0 && (currentTokens >= c.config.MinTokens || currentTokens+p.tokens > c.config.MaxTokens) {
+			groups = append(groups, current)
+			current, currentTokens = nil, 0
+		}
+		current = append(current, p)
+		currentTokens += p.tokens
+	}
+	if len(current) == 0 {
+		return groups
+	}
+
+	if n := len(groups); n > 0 && currentTokens < c.config.MinTokens {
+		lastTokens := 0
+		for _, p := range groups[n-1] {
+			lastTokens += p.tokens
+		}
+		if lastTokens+currentTokens <= c.config.MaxTokens {
+			groups[n-1] = append(groups[n-1], current...)
+			return groups
+		}
+	}
+	return append(groups, current)
+}
+
+// units 把超长文本依次按段落、行、句子拆开，仍然超长的句子按字符硬切
+// 每个单元保留结尾的分隔符，按顺序拼接即为原文
+func (c *Chunker) units(text string, budget, level int) []string {
+	if c.counter(text) <= budget {
+		return []string{text}
+	}
+
+	var parts []string
+	switch level {
+	case 0:
+		parts = strings.SplitAfter(text, "\n\n")
+	case 1:
+		parts = strings.SplitAfter(text, "\n")
+	case 2:
+		parts = splitSentences(text)
+	default:
+		return c.splitRunes(text, budget)
+	}
+
+	units := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		units = append(units, c.units(part, budget, level+1)...)
+	}
+	return units
+}
+
+// pack 把单元依次装入不超过 budget 的分段，新分段以上一分段末尾不超过 OverlapTokens 的单元开头
+func (c *Chunker) pack(units []string, budget int) []string {
+	segments := make([]string, 0)
+	var current []string
+	currentTokens := 0
+	for _, unit := range units {
+		tokens := c.counter(unit)
+		if len(current) > 0 && currentTokens+tokens > budget {
+			segments = append(segments, strings.Join(current, ""))
+
+			keep, keepTokens := 0, 0
+			for i := len(current) - 1; i > 0; i-- {
+				t := c.counter(current[i])
+				if keepTokens+t > c.config.OverlapTokens {
+					break
+				}
+				keep++
+				keepTokens += t
+			}
+			current = append([]string(nil), current[len(current)-keep:]...)
+			currentTokens = keepTokens
+
+			// 重叠部分加上当前单元放不下时，从前面丢弃重叠单元
+			for len(current) > 0 && currentTokens+tokens > budget {
+				currentTokens -= c.counter(current[0])
+				current = current[1:]
+			}
+		}
+		current = append(current, unit)
+		currentTokens += tokens
+	}
+	if len(current) > 0 {
+		segments = append(segments, strings.Join(current, ""))
+	}
+	return segments
+}
+
+// splitRunes 按 token 数硬切文本
+func (c *Chunker) splitRunes(text string, budget int) []string {
+	parts := make([]string, 0)
+	var current strings.Builder
+	currentTokens := 0
+	for _, r := range text {
+		tokens := c.counter(string(r))
+		if currentTokens > 0 && currentTokens+tokens > budget {
+			parts = append(parts, current.String())
+			current.Reset()
+			currentTokens = 0
+		}
+		current.WriteRune(r)
+		currentTokens += tokens
+	}
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+	return parts
+}
+
+// pathTokens 标题路径的 token 数
+func (c *Chunker) pathTokens(path []heading) int {
+	tokens := 0
+	for _, h := range path {
+		tokens += c.counter(h.line)
+	}
+	return tokens
+}
+
+// parseSections 按 1 到 maxLevel 级标题切分章节（代码块中的 # 不是标题），跳过没有正文的章节
+func parseSections(content string, maxLevel int) []piece {
+	sections := make([]piece, 0)
+	var stack []heading
+	var body strings.Builder
+	flush := func() {
+		if text := strings.Trim(body.String(), "\n"); strings.TrimSpace(text) != "" {
+			sections = append(sections, piece{path: append([]heading(nil), stack...), body: text})
+		}
+		body.Reset()
+	}
+
+	inFence := false
+	seq := 0
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence {
+			if level, text, ok := parseHeading(trimmed); ok && level <= maxLevel {
+				flush()
+				for len(stack) > 0 && stack[len(stack)-1].level >= level {
+					stack = stack[:len(stack)-1]
+				}
+				seq++
+				stack = append(stack, heading{seq: seq, level: level, line: trimmed, text: text})
+				continue
+			}
+		}
+		body.WriteString(line)
+		body.WriteString("\n")
+	}
+	flush()
+	return sections
+}
+
+// parseHeading 解析 ATX 标题行（"## 操作" 返回 2, "操作"）
+func parseHeading(line string) (int, string, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level >= len(line) || line[level] != ' ' {
+		return 0, "", false
+	}
+	text := strings.TrimSpace(strings.TrimRight(line[level:], "# "))
+	return level, text, text != ""
+}
+
+// writePiece 写入分块单元：先写出与已写出标题路径不同的标题，再写正文，返回新的已写出标题路径
+func writePiece(b *strings.Builder, emitted []heading, p piece) []heading {
+	common := 0
+	for common < len(emitted) && common < len(p.path) && emitted[common].seq == p.path[common].seq {
+		common++
+	}
+	for _, h := range p.path[common:] {
+		b.WriteString(h.line)
+		b.WriteString("\n\n")
+	}
+	b.WriteString(strings.TrimSpace(p.body))
+	b.WriteString("\n\n")
+	return p.path
+}
+
+// sectionLabel 章节名：一级标题（菜名）以下的标题路径，如 操作 > 预处理
+func sectionLabel(path []heading) string {
+	titles := make([]string, 0, len(path))
+	for _, h := range path {
+		if h.level > 1 {
+			titles = append(titles, h.text)
+		}
+	}
+	if len(titles) == 0 {
+		return retrieval.OverviewSection
+	}
+	return strings.Join(titles, " > ")
+}
+
+// dishName 菜名：优先使用元数据，否则取一级标题并去掉"的做法"
+func dishName(doc models.Document, sections []piece) string {
+	if dish, ok := doc.Metadata[retrieval.DishMetadataKey].(string); ok && dish != "" {
+		return dish
+	}
+	for _, section := range sections {
+		if len(section.path) > 0 && section.path[0].level == 1 {
+			return strings.TrimSuffix(section.path[0].text, dishTitleSuffix)
+		}
+	}
+	return ""
+}
+
+// splitSentences 在句末标点后切分（保留标点）
+func splitSentences(text string) []string {
+	sentences := make([]string, 0)
+	start := 0
+	for i, r := range text {
+		switch r {
+		case '。', '！', '？', '；', '!', '?', ';':
+			end := i + utf8.RuneLen(r)
+			sentences = append(sentences, text[start:end])
+			start = end
+		}
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
+
+// appendUnique 追加不重复的元素
+func appendUnique(items []string, item string) []string {
+	for _, existing := range items {
+		if existing == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
@@ -0,0 +1,497 @@
+package fuzzy
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"cookrag-go/internal/models"
+
+	"github.com/charmbracelet/log"
+	"github.com/mozillazg/go-pinyin"
+)
+
+// 纠错方式
+const (
+	MethodPinyin       = "pinyin"        // 拼音输入：hongshaorou → 红烧肉
+	MethodHomophone    = "homophone"     // 同音字：宫宝鸡丁 → 宫保鸡丁
+	MethodEditDistance = "edit_distance" // 错别字：西红柿炒鸡旦 → 西红柿炒鸡蛋
+)
+
+// 纠错模式
+const (
+	ModeRewrite = "rewrite" // 用纠正后的词替换原片段
+	ModeAugment = "augment" // 保留原查询，追加纠正后的词
+)
+
+// minSuffixRunes 菜名后缀的最短字数（"红烧肉"，更短的后缀如"鸡丁"容易误纠）
+const minSuffixRunes = 3
+
+// 词条类型（与 kg 生成的用户词典词性一致，菜名优先）
+const (
+	KindDish       = "nz"
+	KindIngredient = "n"
+)
+
+// CorrectorConfig 纠错配置
+type CorrectorConfig struct {
+	Mode              string // rewrite / augment
+	MaxEditDistance   int    // 汉字编辑距离上限（插入、删除、替换，只对不少于 MinEditTermLength 个字的词生效）
+	MinEditTermLength int    // 允许汉字编辑距离纠错的最短词长（短词错一个字往往是另一道菜）
+	MaxPinyinDistance int    // 拼音输入的编辑距离上限（拼音串不少于8个字母时生效）
+}
+
+// DefaultCorrectorConfig 默认纠错配置（augment：原查询仍参与检索）
+func DefaultCorrectorConfig() *CorrectorConfig {
+	return &CorrectorConfig{
+		Mode:              ModeAugment,
+		MaxEditDistance:   1,
+		MinEditTermLength: 4,
+		MaxPinyinDistance: 1,
+	}
+}
+
+// Corrector 基于菜名/食材词表的模糊纠错（拼音 + 同音字 + 编辑距离）
+type Corrector struct {
+	config *CorrectorConfig
+
+	mu       sync.RWMutex
+	kinds    map[string]string   // 词 -> 类型
+	byPinyin map[string][]string // 无声调拼音串 -> 词（同音词可能有多个）
+	// 菜名后缀的拼音串 -> 后缀（"简易红烧肉" 登记 "红烧肉"），拼音只输入菜系通称时使用
+	bySuffix map[string][]string
+	byLength map[int][]string // 字数 -> 词
+	maxRunes int
+}
+
+// NewCorrector 创建纠错器
+func NewCorrector(config *CorrectorConfig) *Corrector {
+	if config == nil {
+		config = DefaultCorrectorConfig()
+	}
+
+	return &Corrector{
+		config:   config,
+		kinds:    make(map[string]string),
+		byPinyin: make(map[string][]string),
+		bySuffix: make(map[string][]string),
+		byLength: make(map[int][]string),
+	}
+}
+
+// AddTerms 添加词表
+func (c *Corrector) AddTerms(kind string, terms ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		runes := []rune(term)
+		if len(runes) < 2 || !isHan(term) {
+			continue
+		}
+
+		if existing, ok := c.kinds[term]; ok {
+			if existing != KindDish && kind == KindDish {
+				c.kinds[term] = kind
+			}
+			continue
+		}
+
+		c.kinds[term] = kind
+		syllables := pinyin.LazyPinyin(term, pinyinArgs)
+		key := strings.Join(syllables, "")
+		c.byPinyin[key] = append(c.byPinyin[key], term)
+		if kind == KindDish && len(syllables) == len(runes) {
+			for start := 1; len(runes)-start >= minSuffixRunes; start++ {
+				suffixKey := strings.Join(syllables[start:], "")
+				c.bySuffix[suffixKey] = appendUnique(c.bySuffix[suffixKey], string(runes[start:]))
+			}
+		}
+		c.byLength[len(runes)] = append(c.byLength[len(runes)], term)
+		if len(runes) > c.maxRunes {
+			c.maxRunes = len(runes)
+		}
+	}
+}
+
+// LoadVocabulary 从 jieba 用户词典加载词表（词 [词频] [词性]，词性 nz 视为菜名）
+func (c *Corrector) LoadVocabulary(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open vocabulary: %w", err)
+	}
+	defer file.Close()
+
+	dishes, ingredients := make([]string, 0), make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if fields[len(fields)-1] == KindDish {
+			dishes = append(dishes, fields[0])
+		} else {
+			ingredients = append(ingredients, fields[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read vocabulary: %w", err)
+	}
+
+	c.AddTerms(KindDish, dishes...)
+	c.AddTerms(KindIngredient, ingredients...)
+	log.Infof("📖 Loaded fuzzy vocabulary: %s (%d dishes, %d ingredients)", path, len(dishes), len(ingredients))
+	return nil
+}
+
+// Size 词表大小
+func (c *Corrector) Size() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.kinds)
+}
+
+// Correct 纠正查询中的拼音、同音字和错别字
+// 返回用于检索的查询和纠错记录；没有纠错时原样返回查询
+func (c *Corrector) Correct(query string) (string, []models.QueryCorrection) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.kinds) == 0 {
+		return query, nil
+	}
+
+	corrections := make([]models.QueryCorrection, 0)
+	var rewritten strings.Builder
+
+	for _, segment := range splitSegments(query) {
+		switch segment.kind {
+		case segmentLatin:
+			text, found := c.correctPinyin(segment.text)
+			corrections = append(corrections, found...)
+			rewritten.WriteString(text)
+		case segmentHan:
+			text, found := c.correctHan(segment.text)
+			corrections = append(corrections, found...)
+			rewritten.WriteString(text)
+		default:
+			rewritten.WriteString(segment.text)
+		}
+	}
+
+	if len(corrections) == 0 {
+		return query, nil
+	}
+
+	if c.config.Mode == ModeAugment {
+		terms := make([]string, 0, len(corrections))
+		for _, correction := range corrections {
+			terms = append(terms, correction.Corrected)
+		}
+		return query + " " + strings.Join(terms, " "), corrections
+	}
+
+	return rewritten.String(), corrections
+}
+
+// correctPinyin 把拼音片段（可能由空格分开，如 "hong shao rou"）替换成词表中的词
+// 从最长的连续单词组合开始匹配，匹配不上的单词原样保留
+func (c *Corrector) correctPinyin(text string) (string, []models.QueryCorrection) {
+	words := strings.Fields(text)
+	corrections := make([]models.QueryCorrection, 0)
+	output := make([]string, 0, len(words))
+
+	for i := 0; i < len(words); {
+		matched := false
+		for j := len(words); j > i; j-- {
+			original := strings.Join(words[i:j], " ")
+			key := normalizePinyin(original)
+			term, distance, ok := c.lookupPinyin(key)
+			if !ok {
+				continue
+			}
+
+			corrections = append(corrections, models.QueryCorrection{
+				Original:  original,
+				Corrected: term,
+				Method:    MethodPinyin,
+				Distance:  distance,
+			})
+			output = append(output, term)
+			i = j
+			matched = true
+			break
+		}
+		if !matched {
+			output = append(output, words[i])
+			i++
+		}
+	}
+
+	// 保留片段首尾的空白
+	prefix := text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+	suffix := text[len(strings.TrimRight(text, " \t")):]
+	return prefix + strings.Join(output, " ") + suffix, corrections
+}
+
+// lookupPinyin 按拼音串查词：先精确匹配词和菜名后缀，长拼音串再允许少量编辑距离
+func (c *Corrector) lookupPinyin(key string) (string, int, bool) {
+	if len(key) < 4 {
+		return "", 0, false
+	}
+
+	if terms, ok := c.byPinyin[key]; ok {
+		return c.preferred(terms), 0, true
+	}
+	if suffixes, ok := c.bySuffix[key]; ok {
+		sorted := append([]string(nil), suffixes...)
+		sort.Strings(sorted)
+		return sorted[0], 0, true
+	}
+
+	if len(key) < 8 || c.config.MaxPinyinDistance <= 0 {
+		return "", 0, false
+	}
+
+	best, bestDistance := make([]string, 0), c.config.MaxPinyinDistance+1
+	for candidate, terms := range c.byPinyin {
+		if abs(len(candidate)-len(key)) > c.config.MaxPinyinDistance {
+			continue
+		}
+		distance := levenshtein([]rune(candidate), []rune(key))
+		if distance > c.config.MaxPinyinDistance {
+			continue
+		}
+		if distance < bestDistance {
+			best, bestDistance = append(best[:0], terms...), distance
+		} else if distance == bestDistance {
+			best = append(best, terms...)
+		}
+	}
+	if len(best) == 0 {
+		return "", 0, false
+	}
+	return c.preferred(best), bestDistance, true
+}
+
+// correctHan 纠正汉字片段：从左到右取最长窗口，词表中存在则跳过，否则尝试同音字和编辑距离
+func (c *Corrector) correctHan(text string) (string, []models.QueryCorrection) {
+	runes := []rune(text)
+	corrections := make([]models.QueryCorrection, 0)
+	var output strings.Builder
+
+	for i := 0; i < len(runes); {
+		advanced := false
+		// 窗口最长比最长的词多 MaxEditDistance 个字（多字的错别字）
+		for length := min(c.maxRunes+max(c.config.MaxEditDistance, 0), len(runes)-i); length >= 2; length-- {
+			window := string(runes[i : i+length])
+
+			// 已经是正确的词
+			if _, ok := c.kinds[window]; ok {
+				output.WriteString(window)
+				i += length
+				advanced = true
+				break
+			}
+
+			if correction, ok := c.matchWindow(window, length); ok {
+				corrections = append(corrections, correction)
+				output.WriteString(correction.Corrected)
+				i += length
+				advanced = true
+				break
+			}
+		}
+		if !advanced {
+			output.WriteRune(runes[i])
+			i++
+		}
+	}
+
+	return output.String(), corrections
+}
+
+// matchWindow 同音字或编辑距离匹配
+func (c *Corrector) matchWindow(window string, length int) (models.QueryCorrection, bool) {
+	// 同音字：拼音完全相同（宫宝鸡丁 / 宫保鸡丁）
+	if terms, ok := c.byPinyin[toPinyin(window)]; ok {
+		return models.QueryCorrection{
+			Original:  window,
+			Corrected: c.preferred(terms),
+			Method:    MethodHomophone,
+		}, true
+	}
+
+	// 错别字：错字、漏字或多字不超过 MaxEditDistance 个（短词不做，避免把"红烧鱼"纠成"红烧肉"）
+	maxDistance := c.config.MaxEditDistance
+	if length < c.config.MinEditTermLength || maxDistance <= 0 {
+		return models.QueryCorrection{}, false
+	}
+
+	windowRunes := []rune(window)
+	best, bestDistance := make([]string, 0), maxDistance+1
+	for termLength := max(length-maxDistance, c.config.MinEditTermLength); termLength <= length+maxDistance; termLength++ {
+		for _, term := range c.byLength[termLength] {
+			termRunes := []rune(term)
+			// 窗口比词长时首尾字必须相同，否则多出来的是相邻的字（"西红柿炒鸡蛋做" 不纠成 "西红柿炒鸡蛋"）
+			if length > termLength && (windowRunes[0] != termRunes[0] || windowRunes[length-1] != termRunes[termLength-1]) {
+				continue
+			}
+
+			distance := levenshtein(windowRunes, termRunes)
+			if distance > maxDistance {
+				continue
+			}
+			if distance < bestDistance {
+				best, bestDistance = append(best[:0], term), distance
+			} else if distance == bestDistance {
+				best = append(best, term)
+			}
+		}
+	}
+	if len(best) == 0 {
+		return models.QueryCorrection{}, false
+	}
+
+	return models.QueryCorrection{
+		Original:  window,
+		Corrected: c.preferred(best),
+		Method:    MethodEditDistance,
+		Distance:  bestDistance,
+	}, true
+}
+
+// preferred 多个候选时优先菜名，其次按字典序（保证结果稳定）
+func (c *Corrector) preferred(terms []string) string {
+	sorted := append([]string(nil), terms...)
+	sort.Slice(sorted, func(i, j int) bool {
+		di, dj := c.kinds[sorted[i]] == KindDish, c.kinds[sorted[j]] == KindDish
+		if di != dj {
+			return di
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted[0]
+}
+
+// 查询片段类型
+const (
+	segmentOther = iota
+	segmentHan
+	segmentLatin
+)
+
+// segment 查询片段（连续的汉字 / 连续的字母和空格 / 其他字符）
+type segment struct {
+	kind int
+	text string
+}
+
+// splitSegments 把查询切成汉字片段、字母片段和其他字符
+func splitSegments(query string) []segment {
+	segments := make([]segment, 0)
+	var current strings.Builder
+	currentKind := -1
+
+	flush := func() {
+		if current.Len() > 0 {
+			segments = append(segments, segment{kind: currentKind, text: current.String()})
+			current.Reset()
+		}
+	}
+
+	for _, r := range query {
+		kind := segmentOther
+		switch {
+		case unicode.Is(unicode.Han, r):
+			kind = segmentHan
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || r == '\''):
+			kind = segmentLatin
+		case r == ' ' && currentKind == segmentLatin:
+			kind = segmentLatin // 拼音之间的空格归入字母片段
+		}
+
+		if kind != currentKind {
+			flush()
+			currentKind = kind
+		}
+		current.WriteRune(r)
+	}
+	flush()
+
+	return segments
+}
+
+// pinyinArgs 无声调拼音，多音字取常用读音
+var pinyinArgs = pinyin.NewArgs()
+
+// toPinyin 汉字转无声调拼音串，如 "红烧肉" → "hongshaorou"
+func toPinyin(text string) string {
+	return strings.Join(pinyin.LazyPinyin(text, pinyinArgs), "")
+}
+
+// normalizePinyin 规范化拼音输入：小写，去掉空格和隔音符号
+func normalizePinyin(text string) string {
+	text = strings.ToLower(text)
+	text = strings.ReplaceAll(text, "'", "")
+	return strings.Join(strings.Fields(text), "")
+}
+
+// isHan 是否全部由汉字组成
+func isHan(text string) bool {
+	for _, r := range text {
+		if !unicode.Is(unicode.Han, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// levenshtein 编辑距离（插入、删除、替换）
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+// appendUnique 追加不重复的词
+func appendUnique(terms []string, term string) []string {
+	for _, t := range terms {
+		if t == term {
+			return terms
+		}
+	}
+	return append(terms, term)
+}
+
+// abs 绝对值
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,145 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"cookrag-go/internal/models"
+)
+
+// HTTPConfig 远程重排接口配置
+// 兼容 Jina / Cohere 风格的 rerank 接口（智谱、SiliconFlow、vLLM、TEI 等 OpenAI 兼容服务的 /rerank 也是同一格式）：
+// 请求 {"model", "query", "documents": [...], "top_n"}，响应 {"results": [{"index", "relevance_score"}]}
+type HTTPConfig struct {
+	URL              string        // 完整接口地址，如 https://api.jina.ai/v1/rerank
+	APIKey           string        // Bearer Token（为空时不发送 Authorization）
+	Model            string        // 重排模型，如 jina-reranker-v2-base-multilingual / rerank
+	Timeout          time.Duration // 请求超时
+	MaxDocumentRunes int           // 每篇文档送入模型的最多字数
+}
+
+// DefaultHTTPConfig 默认配置（智谱 rerank 接口）
+func DefaultHTTPConfig() *HTTPConfig {
+	return &HTTPConfig{
+		URL:              "https://open.bigmodel.cn/api/paas/v4/rerank",
+		Model:            "rerank",
+		Timeout:          10 * time.Second,
+		MaxDocumentRunes: 1024,
+	}
+}
+
+// HTTPReranker 调用远程 Cross-Encoder 重排接口
+type HTTPReranker struct {
+	config *HTTPConfig
+	client *http.Client
+}
+
+// NewHTTPReranker 创建远程重排器
+func NewHTTPReranker(config *HTTPConfig) *HTTPReranker {
+	if config == nil {
+		config = DefaultHTTPConfig()
+	}
+
+	return &HTTPReranker{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Name 重排器名称
+func (r *HTTPReranker) Name() string {
+	return "http"
+}
+
+// rerankRequest 重排请求
+type rerankRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n"`
+}
+
+// rerankResult 单篇文档的重排结果（不同服务分别使用 relevance_score 或 score）
+type rerankResult struct {
+	Index          int      `json:"index"`
+	RelevanceScore *float64 `json:"relevance_score"`
+	Score          *float64 `json:"score"`
+}
+
+// rerankResponse 重排响应（results 或 data）
+type rerankResponse struct {
+	Results []rerankResult `json:"results"`
+	Data    []rerankResult `json:"data"`
+}
+
+// Rerank 调用远程接口计算相关性分数（没有返回分数的文档记为 0）
+func (r *HTTPReranker) Rerank(ctx context.Context, query string, documents []models.Document) ([]float64, error) {
+	texts := make([]string, 0, len(documents))
+	for _, doc := range documents {
+		texts = append(texts, documentText(doc, r.config.MaxDocumentRunes))
+	}
+
+	body, err := json.Marshal(rerankRequest{
+		Model:     r.config.Model,
+		Query:     query,
+		Documents: texts,
+		TopN:      len(texts),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rerank request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rerank request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.config.APIKey)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rerank request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rerank response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rerank request failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(payload))
+	}
+
+	var decoded rerankResponse
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode rerank response: %w", err)
+	}
+	results := decoded.Results
+	if len(results) == 0 {
+		results = decoded.Data
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("rerank response contains no results")
+	}
+
+	scores := make([]float64, len(documents))
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= len(scores) {
+			return nil, fmt.Errorf("rerank response index %d out of range", result.Index)
+		}
+		switch {
+		case result.RelevanceScore != nil:
+			scores[result.Index] = *result.RelevanceScore
+		case result.Score != nil:
+			scores[result.Index] = *result.Score
+		}
+	}
+	return scores, nil
+}
@@ -0,0 +1,109 @@
+package rerank
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"cookrag-go/internal/core/retrieval"
+	"cookrag-go/internal/models"
+)
+
+// titleWeight 菜名命中的额外权重（菜名命中查询词的文档通常就是要找的菜）
+const titleWeight = 0.5
+
+// LexicalReranker 基于查询词覆盖率的离线重排：不依赖任何模型，作为远程重排失败时的兜底
+// 分数 = (正文覆盖的查询词比例 + titleWeight × 菜名覆盖的查询词比例) / (1 + titleWeight)
+type LexicalReranker struct {
+	tokenize func(text string) []string // 查询分词（为 nil 时使用字符二元组）
+}
+
+// NewLexicalReranker 创建词项重排器（tokenize 通常为 BM25Retriever.Tokenize，可为nil）
+func NewLexicalReranker(tokenize func(text string) []string) *LexicalReranker {
+	return &LexicalReranker{
+		tokenize: tokenize,
+	}
+}
+
+// Name 重排器名称
+func (r *LexicalReranker) Name() string {
+	return "lexical"
+}
+
+// Rerank 计算查询词覆盖率
+func (r *LexicalReranker) Rerank(ctx context.Context, query string, documents []models.Document) ([]float64, error) {
+	terms := r.queryTerms(query)
+	scores := make([]float64, len(documents))
+	if len(terms) == 0 {
+		return scores, nil
+	}
+
+	for i, doc := range documents {
+		content := strings.ToLower(doc.Content)
+		title := strings.ToLower(documentTitle(doc))
+
+		contentHits, titleHits := 0, 0
+		for _, term := range terms {
+			if strings.Contains(content, term) {
+				contentHits++
+			}
+			if title != "" && strings.Contains(title, term) {
+				titleHits++
+			}
+		}
+		coverage := float64(contentHits) / float64(len(terms))
+		titleCoverage := float64(titleHits) / float64(len(terms))
+		scores[i] = (coverage + titleWeight*titleCoverage) / (1 + titleWeight)
+	}
+	return scores, nil
+}
+
+// queryTerms 去重后的查询词（小写）
+func (r *LexicalReranker) queryTerms(query string) []string {
+	var terms []string
+	if r.tokenize != nil {
+		terms = r.tokenize(query)
+	}
+	if len(terms) == 0 {
+		terms = bigrams(query)
+	}
+
+	seen := make(map[string]bool, len(terms))
+	unique := make([]string, 0, len(terms))
+	for _, term := range terms {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if term != "" && !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+// documentTitle 文档的菜名（元数据 dish，没有时取第一行）
+func documentTitle(doc models.Document) string {
+	if dish, ok := doc.Metadata[retrieval.DishMetadataKey].(string); ok && dish != "" {
+		return dish
+	}
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(doc.Content), "\n")
+	return strings.TrimLeft(firstLine, "# ")
+}
+
+// bigrams 字符二元组（只有一个字时返回该字），忽略空白和标点
+func bigrams(text string) []string {
+	runes := make([]rune, 0, len(text))
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	if len(runes) == 1 {
+		return []string{string(runes)}
+	}
+
+	grams := make([]string, 0, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
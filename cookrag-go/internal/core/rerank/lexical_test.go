@@ -0,0 +1,67 @@
+package rerank
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"cookrag-go/internal/core/retrieval"
+	"cookrag-go/internal/models"
+)
+
+func TestLexicalRerankerOrdering(t *testing.T) {
+	documents := []models.Document{
+		{ID: "unrelated", Content: "# 番茄炒蛋\n番茄 鸡蛋 盐"},
+		{ID: "mention", Content: "# 梅菜扣肉\n做法和红烧肉类似，五花肉先焯水"},
+		{ID: "dish", Content: "红烧肉做法：五花肉切块", Metadata: map[string]interface{}{retrieval.DishMetadataKey: "红烧肉"}},
+	}
+	tokenize := func(text string) []string {
+		return []string{"红烧肉", "五花肉"}
+	}
+
+	scores, err := NewLexicalReranker(tokenize).Rerank(context.Background(), "红烧肉 五花肉", documents)
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	if len(scores) != len(documents) {
+		t.Fatalf("got %d scores, want %d", len(scores), len(documents))
+	}
+
+	// 菜名命中 > 只在正文命中 > 不命中
+	if !(scores[2] > scores[1] && scores[1] > scores[0]) {
+		t.Errorf("scores = %v, want dish > mention > unrelated", scores)
+	}
+	if scores[0] != 0 {
+		t.Errorf("unrelated score = %v, want 0", scores[0])
+	}
+	// 正文覆盖 2/2，菜名覆盖 1/2：(1 + 0.5×0.5) / 1.5
+	if want := (1 + titleWeight*0.5) / (1 + titleWeight); math.Abs(scores[2]-want) > 1e-9 {
+		t.Errorf("dish score = %v, want %v", scores[2], want)
+	}
+}
+
+func TestLexicalRerankerBigramFallback(t *testing.T) {
+	documents := []models.Document{
+		{ID: "other", Content: "# 可乐鸡翅\n鸡翅 可乐"},
+		{ID: "match", Content: "# 红烧肉\n五花肉 冰糖"},
+	}
+
+	// 没有分词器时按字符二元组匹配
+	scores, err := NewLexicalReranker(nil).Rerank(context.Background(), "红烧肉", documents)
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	if !(scores[1] > scores[0]) {
+		t.Errorf("scores = %v, want match ranked above other", scores)
+	}
+}
+
+func TestLexicalRerankerEmptyQuery(t *testing.T) {
+	scores, err := NewLexicalReranker(nil).Rerank(context.Background(), "？！", []models.Document{{ID: "a", Content: "红烧肉"}})
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	if len(scores) != 1 || scores[0] != 0 {
+		t.Errorf("scores = %v, want [0]", scores)
+	}
+}
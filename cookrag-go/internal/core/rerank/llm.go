@@ -0,0 +1,109 @@
+package rerank
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"cookrag-go/internal/models"
+	"cookrag-go/pkg/ml/llm"
+)
+
+// LLMConfig 基于提示词的逐篇打分重排配置
+type LLMConfig struct {
+	Concurrency      int // 同时打分的文档数
+	MaxDocumentRunes int // 每篇文档放入提示词的最多字数
+}
+
+// DefaultLLMConfig 默认配置
+func DefaultLLMConfig() *LLMConfig {
+	return &LLMConfig{
+		Concurrency:      4,
+		MaxDocumentRunes: 800,
+	}
+}
+
+// LLMReranker 逐篇（pointwise）让 LLM 给出 0-10 的相关性分数
+// 不需要专门的重排模型，但每篇文档一次 LLM 调用，适合候选较少的场景
+type LLMReranker struct {
+	config   *LLMConfig
+	provider llm.Provider
+}
+
+// NewLLMReranker 创建 LLM 重排器
+func NewLLMReranker(config *LLMConfig, provider llm.Provider) *LLMReranker {
+	if config == nil {
+		config = DefaultLLMConfig()
+	}
+
+	return &LLMReranker{
+		config:   config,
+		provider: provider,
+	}
+}
+
+// Name 重排器名称
+func (r *LLMReranker) Name() string {
+	return "llm"
+}
+
+// Rerank 并发为每篇文档打分（分数归一化到 0-1，任一文档失败时返回错误）
+func (r *LLMReranker) Rerank(ctx context.Context, query string, documents []models.Document) ([]float64, error) {
+	scores := make([]float64, len(documents))
+	errs := make([]error, len(documents))
+	sem := make(chan struct{}, max(r.config.Concurrency, 1))
+	var wg sync.WaitGroup
+	for i, doc := range documents {
+		wg.Add(1)
+		go func(i int, doc models.Document) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			answer, err := r.provider.Generate(ctx, r.buildPrompt(query, doc))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			scores[i], errs[i] = parseRelevance(answer)
+		}(i, doc)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("llm rerank failed: %w", err)
+	}
+	return scores, nil
+}
+
+// buildPrompt 构建打分提示词
+func (r *LLMReranker) buildPrompt(query string, doc models.Document) string {
+	return fmt.Sprintf(`你是菜谱检索的相关性评估员。请判断下面的文档能在多大程度上回答用户的问题。
+
+问题：%s
+
+文档：
+%s
+
+请只输出一个 0 到 10 的整数：10 表示文档直接完整地回答了问题，0 表示完全无关。
+分数：`, query, documentText(doc, r.config.MaxDocumentRunes))
+}
+
+// relevancePattern 回答中的第一个数字
+var relevancePattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// parseRelevance 从 LLM 回答中解析 0-10 的分数并归一化到 0-1
+func parseRelevance(answer string) (float64, error) {
+	match := relevancePattern.FindString(answer)
+	if match == "" {
+		return 0, fmt.Errorf("no relevance score in answer: %q", answer)
+	}
+	score, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid relevance score %q: %w", match, err)
+	}
+	return min(max(score, 0), 10) / 10, nil
+}
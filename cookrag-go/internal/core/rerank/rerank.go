@@ -0,0 +1,159 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cookrag-go/internal/core/retrieval"
+	"cookrag-go/internal/models"
+	"cookrag-go/internal/observability"
+
+	"github.com/charmbracelet/log"
+)
+
+// Reranker 重排模型：给出查询与每篇文档的相关性分数（与 documents 一一对应，越大越相关）
+type Reranker interface {
+	// Name 重排器名称（写入链路追踪和分数解释）
+	Name() string
+	// Rerank 计算相关性分数
+	Rerank(ctx context.Context, query string, documents []models.Document) ([]float64, error)
+}
+
+// StageConfig 重排阶段配置
+type StageConfig struct {
+	TopN int // 参与重排的候选数量（检索结果的前 N 篇）
+	TopK int // 重排后保留的数量
+}
+
+// DefaultStageConfig 默认配置（前 20 篇重排后保留 5 篇）
+func DefaultStageConfig() *StageConfig {
+	return &StageConfig{
+		TopN: 20,
+		TopK: 5,
+	}
+}
+
+// Stage 检索后的重排阶段：把前 TopN 个候选交给重排模型打分，按新分数保留 TopK
+// 重排模型失败时使用 fallback（通常是离线的 LexicalReranker），都失败时保留检索顺序
+type Stage struct {
+	config   *StageConfig
+	reranker Reranker
+	fallback Reranker // 可为nil
+}
+
+// NewStage 创建重排阶段
+func NewStage(config *StageConfig, reranker Reranker, fallback Reranker) *Stage {
+	if config == nil {
+		config = DefaultStageConfig()
+	}
+
+	return &Stage{
+		config:   config,
+		reranker: reranker,
+		fallback: fallback,
+	}
+}
+
+// Apply 重排检索结果，返回最多 TopK 篇（文档分数替换为重排分数，explain 模式下保留检索分数和排名）
+// used 为实际给出分数的重排器名称（主重排器失败时为 fallback），都失败、保留检索顺序时为空
+func (s *Stage) Apply(ctx context.Context, query string, documents []models.Document) (reranked []models.Document, used string) {
+	if len(documents) == 0 {
+		return documents, ""
+	}
+
+	candidates := documents
+	if s.config.TopN > 0 && len(candidates) > s.config.TopN {
+		candidates = candidates[:s.config.TopN]
+	}
+	topK := s.config.TopK
+	if topK <= 0 || topK > len(candidates) {
+		topK = len(candidates)
+	}
+
+	span := observability.GlobalTracer.StartSpan(ctx, "rerank", map[string]interface{}{
+		"reranker":   s.reranker.Name(),
+		"candidates": len(candidates),
+		"top_k":      topK,
+	})
+	defer span.End()
+
+	reranker := s.reranker
+	scores, err := score(ctx, reranker, query, candidates)
+	if err != nil && s.fallback != nil {
+		log.Warnf("⚠️  Rerank with %s failed, falling back to %s: %v", reranker.Name(), s.fallback.Name(), err)
+		span.AddMetadata("fallback", s.fallback.Name())
+		reranker = s.fallback
+		scores, err = score(ctx, reranker, query, candidates)
+	}
+	if err != nil {
+		// 重排失败不影响检索结果，保留原顺序
+		log.Warnf("⚠️  Rerank failed, keeping retrieval order: %v", err)
+		span.SetError(err)
+		return candidates[:topK], ""
+	}
+
+	explain := retrieval.ExplainEnabled(ctx)
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	reranked = make([]models.Document, 0, topK)
+	moved := 0
+	for rank, i := range order[:topK] {
+		doc := candidates[i]
+		if rank != i {
+			moved++
+		}
+		if explain {
+			explanation := &models.Explanation{}
+			if doc.Explanation != nil {
+				*explanation = *doc.Explanation // 复制，避免修改检索器返回的文档
+			}
+			explanation.Rerank = &models.RerankExplanation{
+				Reranker:       reranker.Name(),
+				Score:          scores[i],
+				RetrievalRank:  i + 1,
+				RetrievalScore: doc.Score,
+			}
+			doc.Explanation = explanation
+		}
+		doc.Score = float32(scores[i])
+		reranked = append(reranked, doc)
+	}
+
+	span.AddMetadata("reranker_used", reranker.Name())
+	span.AddMetadata("moved", moved)
+	log.Infof("🏅 Reranked %d candidates → %d with %s (%d moved)", len(candidates), len(reranked), reranker.Name(), moved)
+	return reranked, reranker.Name()
+}
+
+// score 调用重排器并检查分数数量与文档一致
+func score(ctx context.Context, reranker Reranker, query string, documents []models.Document) ([]float64, error) {
+	scores, err := reranker.Rerank(ctx, query, documents)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) != len(documents) {
+		return nil, fmt.Errorf("reranker %s returned %d scores for %d documents", reranker.Name(), len(scores), len(documents))
+	}
+	return scores, nil
+}
+
+// documentText 送入重排模型的文档文本（超过 maxRunes 个字时截断，maxRunes<=0 表示不截断）
+func documentText(doc models.Document, maxRunes int) string {
+	text := strings.TrimSpace(doc.Content)
+	if maxRunes <= 0 {
+		return text
+	}
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+	return string(runes[:maxRunes])
+}
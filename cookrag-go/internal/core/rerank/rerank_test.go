@@ -0,0 +1,170 @@
+package rerank
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cookrag-go/internal/core/retrieval"
+	"cookrag-go/internal/models"
+)
+
+// fakeReranker 返回固定分数或错误的重排器
+type fakeReranker struct {
+	name   string
+	scores []float64
+	err    error
+	calls  int
+	seen   int // 最近一次调用收到的文档数
+}
+
+func (f *fakeReranker) Name() string {
+	return f.name
+}
+
+func (f *fakeReranker) Rerank(ctx context.Context, query string, documents []models.Document) ([]float64, error) {
+	f.calls++
+	f.seen = len(documents)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.scores, nil
+}
+
+// candidates 按检索分数从高到低的候选文档 d0, d1, ...
+func candidates(n int) []models.Document {
+	documents := make([]models.Document, n)
+	for i := range documents {
+		documents[i] = models.Document{
+			ID:    string(rune('a' + i)),
+			Score: float32(n - i),
+		}
+	}
+	return documents
+}
+
+func ids(documents []models.Document) []string {
+	result := make([]string, len(documents))
+	for i, doc := range documents {
+		result[i] = doc.ID
+	}
+	return result
+}
+
+func equalIDs(t *testing.T, got []models.Document, want ...string) {
+	t.Helper()
+	gotIDs := ids(got)
+	if len(gotIDs) != len(want) {
+		t.Fatalf("got %v, want %v", gotIDs, want)
+	}
+	for i := range want {
+		if gotIDs[i] != want[i] {
+			t.Fatalf("got %v, want %v", gotIDs, want)
+		}
+	}
+}
+
+func TestStageApplyTruncatesToTopNAndTopK(t *testing.T) {
+	primary := &fakeReranker{name: "primary", scores: []float64{0.1, 0.9, 0.5}}
+	stage := NewStage(&StageConfig{TopN: 3, TopK: 2}, primary, nil)
+
+	reranked, used := stage.Apply(context.Background(), "红烧肉", candidates(5))
+
+	if primary.seen != 3 {
+		t.Errorf("reranker saw %d documents, want TopN=3", primary.seen)
+	}
+	equalIDs(t, reranked, "b", "c")
+	if used != "primary" {
+		t.Errorf("used = %q, want primary", used)
+	}
+	if reranked[0].Score != 0.9 {
+		t.Errorf("score = %v, want rerank score 0.9", reranked[0].Score)
+	}
+}
+
+func TestStageApplyFallsBackOnError(t *testing.T) {
+	primary := &fakeReranker{name: "primary", err: errors.New("unavailable")}
+	fallback := &fakeReranker{name: "lexical", scores: []float64{0.2, 0.3, 0.8}}
+	stage := NewStage(&StageConfig{TopN: 3, TopK: 3}, primary, fallback)
+
+	reranked, used := stage.Apply(context.Background(), "红烧肉", candidates(3))
+
+	if fallback.calls != 1 {
+		t.Fatalf("fallback called %d times, want 1", fallback.calls)
+	}
+	equalIDs(t, reranked, "c", "b", "a")
+	if used != "lexical" {
+		t.Errorf("used = %q, want lexical", used)
+	}
+}
+
+func TestStageApplyFallsBackOnScoreCountMismatch(t *testing.T) {
+	primary := &fakeReranker{name: "primary", scores: []float64{0.9}}
+	fallback := &fakeReranker{name: "lexical", scores: []float64{0.1, 0.7}}
+	stage := NewStage(&StageConfig{TopN: 2, TopK: 2}, primary, fallback)
+
+	reranked, used := stage.Apply(context.Background(), "红烧肉", candidates(2))
+
+	equalIDs(t, reranked, "b", "a")
+	if used != "lexical" {
+		t.Errorf("used = %q, want lexical", used)
+	}
+}
+
+func TestStageApplyKeepsOrderWhenAllRerankersFail(t *testing.T) {
+	primary := &fakeReranker{name: "primary", err: errors.New("unavailable")}
+	fallback := &fakeReranker{name: "lexical", scores: []float64{0.5}} // 分数数量不对
+	stage := NewStage(&StageConfig{TopN: 4, TopK: 2}, primary, fallback)
+
+	documents := candidates(4)
+	reranked, used := stage.Apply(context.Background(), "红烧肉", documents)
+
+	equalIDs(t, reranked, "a", "b")
+	if used != "" {
+		t.Errorf("used = %q, want empty when reranking failed", used)
+	}
+	if reranked[0].Score != documents[0].Score {
+		t.Errorf("score = %v, want retrieval score %v", reranked[0].Score, documents[0].Score)
+	}
+}
+
+func TestStageApplyExplain(t *testing.T) {
+	primary := &fakeReranker{name: "primary", scores: []float64{0.1, 0.9}}
+	stage := NewStage(&StageConfig{TopN: 2, TopK: 2}, primary, nil)
+
+	documents := candidates(2)
+	documents[1].Explanation = &models.Explanation{Fusion: &models.FusionExplanation{Method: retrieval.FusionRRF}}
+	ctx := retrieval.WithExplain(context.Background())
+	reranked, _ := stage.Apply(ctx, "红烧肉", documents)
+
+	top := reranked[0]
+	if top.Explanation == nil || top.Explanation.Rerank == nil {
+		t.Fatalf("missing rerank explanation: %+v", top.Explanation)
+	}
+	want := models.RerankExplanation{Reranker: "primary", Score: 0.9, RetrievalRank: 2, RetrievalScore: 1}
+	if *top.Explanation.Rerank != want {
+		t.Errorf("rerank explanation = %+v, want %+v", *top.Explanation.Rerank, want)
+	}
+	if top.Explanation.Fusion == nil {
+		t.Error("fusion explanation from retrieval was dropped")
+	}
+	if documents[1].Explanation.Rerank != nil {
+		t.Error("input document explanation was modified")
+	}
+
+	// 未开启 explain 时不附带明细
+	reranked, _ = stage.Apply(context.Background(), "红烧肉", candidates(2))
+	if reranked[0].Explanation != nil {
+		t.Errorf("unexpected explanation without explain: %+v", reranked[0].Explanation)
+	}
+}
+
+func TestStageApplyEmpty(t *testing.T) {
+	primary := &fakeReranker{name: "primary"}
+	stage := NewStage(nil, primary, nil)
+
+	reranked, used := stage.Apply(context.Background(), "红烧肉", nil)
+	if len(reranked) != 0 || used != "" || primary.calls != 0 {
+		t.Errorf("got %d documents, used %q, %d calls; want no reranking", len(reranked), used, primary.calls)
+	}
+}
@@ -3,52 +3,46 @@ package retrieval
 import (
 	"context"
 	"fmt"
-	"math"
 	"sort"
-	"strings"
-	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"cookrag-go/internal/models"
 	"cookrag-go/internal/observability"
-	"github.com/yanyiwu/gojieba"
+	"cookrag-go/internal/synonym"
+	"cookrag-go/pkg/storage/docstore"
 )
 
 // BM25Config BM25配置参数
 type BM25Config struct {
-	K1 float64 // 词频饱和参数 (通常1.2-2.0)
-	B  float64 // 长度惩罚参数 (通常0.75)
+	K1            float64                    // 词频饱和参数 (通常1.2-2.0)
+	B             float64                    // 长度惩罚参数 (通常0.75，未单独配置的字段使用该值)
+	Variant       BM25Variant                // 评分变体：bm25 / bm25+ / bm25l
+	Delta         float64                    // BM25+ / BM25L 的下界参数δ（为0时使用变体默认值）
+	DocStorePath  string                     // 文档存储文件路径（为空则只保存在内存中）
+	SnapshotPath  string                     // 索引快照文件路径（为空则每次启动重建索引）
+	Fields        map[string]BM25FieldConfig // BM25F 字段权重和长度归一化参数
+	Tokenizer     *TokenizerConfig           // 分词器配置（用户词典、停用词表）
+	GroupByParent bool                       // 索引的是分块时，按原文档聚合命中（topK 按原文档计数）
 }
 
 // DefaultBM25Config 默认BM25配置
 func DefaultBM25Config() *BM25Config {
 	return &BM25Config{
-		K1: 1.5,
-		B:  0.75,
+		K1:      1.5,
+		B:       0.75,
+		Variant: BM25Classic,
+		Fields:  DefaultBM25Fields(),
 	}
 }
 
-// InvertedIndex 倒排索引
-type InvertedIndex struct {
-	mu       sync.RWMutex
-	// 词项 -> 文档ID列表
-	Postings map[string][]int64
-	// 词项 -> 文档频率
-	DocFreq map[string]int
-	// 文档ID -> 文档长度
-	DocLengths map[int64]int
-	// 平均文档长度
-	AvgDocLength float64
-	// 总文档数
-	TotalDocs int
-}
-
 // BM25Retriever BM25检索器
 type BM25Retriever struct {
 	config    *BM25Config
 	index     *InvertedIndex
-	tokenizer *gojieba.Jieba
+	tokenizer *Tokenizer
+	docStore  docstore.Store    // 原始文档存储（检索命中后回查内容和元数据）
+	synonyms  *synonym.Registry // 同义词表（查询时扩展，为空则不扩展）
 }
 
 // NewBM25Retriever 创建BM25检索器
@@ -56,196 +50,242 @@ func NewBM25Retriever(config *BM25Config) *BM25Retriever {
 	if config == nil {
 		config = DefaultBM25Config()
 	}
+	if err := ValidateBM25Variant(config.Variant); err != nil {
+		log.Warnf("⚠️  %v, using %s", err, BM25Classic)
+		validated := *config
+		validated.Variant = BM25Classic
+		config = &validated
+	}
+
+	// 初始化 jieba 分词器（加载领域用户词典和停用词表，失败时使用默认词典）
+	tokenizer, err := NewTokenizer(config.Tokenizer)
+	if err != nil {
+		log.Warnf("⚠️  Failed to load tokenizer dictionaries, using defaults: %v", err)
+		tokenizer, err = NewTokenizer(nil)
+		if err != nil {
+			log.Fatalf("❌ Failed to create tokenizer: %v", err)
+		}
+	}
 
-	// 初始化 jieba 分词器
-	tokenizer := gojieba.NewJieba()
+	// 初始化文档存储：配置了路径则落盘，否则使用内存存储
+	var store docstore.Store = docstore.NewMemoryStore()
+	if config.DocStorePath != "" {
+		fileStore, err := docstore.NewFileStore(config.DocStorePath)
+		if err != nil {
+			log.Warnf("⚠️  Failed to open document store %s, falling back to memory: %v", config.DocStorePath, err)
+		} else {
+			store = fileStore
+			log.Infof("📂 BM25 document store: %s (%d docs)", config.DocStorePath, fileStore.Count())
+		}
+	}
 
 	return &BM25Retriever{
 		config:    config,
 		tokenizer: tokenizer,
-		index: &InvertedIndex{
-			Postings:     make(map[string][]int64),
-			DocFreq:      make(map[string]int),
-			DocLengths:   make(map[int64]int),
-			AvgDocLength: 0,
-			TotalDocs:    0,
-		},
+		docStore:  store,
+		index:     newInvertedIndex(),
 	}
 }
 
-// Tokenize 使用 jieba 进行中文分词
-func (r *BM25Retriever) Tokenize(text string) []string {
-	// 使用 jieba 分词，搜索模式 (HMM=true)
-	words := r.tokenizer.Cut(text, true)
-
-	// 停用词过滤（简化版）
-	stopWords := map[string]bool{
-		"的": true, "了": true, "在": true, "是": true, "我": true,
-		"有": true, "和": true, "就": true, "不": true, "人": true,
-		"之": true, "与": true, "及": true, "等": true, "或": true,
-		"吗": true, "呢": true, "吧": true, "啊": true, "呀": true,
-		// 英文停用词
-		"the": true, "a": true, "an": true, "and": true, "or": true,
-		"but": true, "in": true, "on": true, "at": true, "to": true,
-		"of": true, "for": true, "with": true, "by": true, "from": true,
-	}
-
-	filtered := make([]string, 0)
-	for _, word := range words {
-		word = strings.TrimSpace(word)
-		// 过滤停用词、单字符、纯数字/标点
-		if word != "" && !stopWords[word] && len(word) > 1 && !isPunctuation(word) {
-			filtered = append(filtered, word)
-		}
+// Close 保存文档存储中未落盘的修改（磁盘存储退出前必须调用）
+func (r *BM25Retriever) Close(ctx context.Context) error {
+	if closer, ok := r.docStore.(docstore.Closer); ok {
+		return closer.Close(ctx)
 	}
+	return nil
+}
 
-	return filtered
+// SetSynonyms 设置查询时使用的同义词表（如 番茄 ↔ 西红柿）
+func (r *BM25Retriever) SetSynonyms(registry *synonym.Registry) {
+	r.synonyms = registry
 }
 
-// isPunctuation 判断是否是标点符号
-// 只有整个字符串都是标点符号才返回true，只要有一个有效字符就保留
-func isPunctuation(s string) bool {
-	if len(s) == 0 {
-		return true
-	}
+// Tokenize 使用 jieba 进行中文分词（过滤停用词、单字符和标点）
+func (r *BM25Retriever) Tokenize(text string) []string {
+	return r.tokenizer.Tokenize(text)
+}
 
-	hasValidChar := false
-	for _, r := range s {
-		isAlpha := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
-		isDigit := r >= '0' && r <= '9'
-		isChinese := r >= 0x4e00 && r <= 0x9fa5
+// Tokenizer 返回BM25使用的分词器
+func (r *BM25Retriever) Tokenizer() *Tokenizer {
+	return r.tokenizer
+}
 
-		if isAlpha || isDigit || isChinese {
-			hasValidChar = true
-		}
+// IndexDocuments 索引文档（已存在的文档ID会被覆盖）
+// 没有ID的文档使用 StableDocumentID（来源路径+分块序号或内容哈希），重复索引同一文档不会产生重复文档
+func (r *BM25Retriever) IndexDocuments(ctx context.Context, documents []models.Document) error {
+	docs := make([]models.Document, len(documents))
+	for i, doc := range documents {
+		doc.ID = StableDocumentID(doc)
+		docs[i] = doc
 	}
-
-	return !hasValidChar // 没有任何有效字符才算标点
+	return r.Upsert(ctx, docs)
 }
 
-// IndexDocuments 索引文档
-func (r *BM25Retriever) IndexDocuments(ctx context.Context, documents []models.Document) error {
+// Upsert 增量新增或更新文档
+// 已存在的文档会先从倒排表中移除再重新索引，平均长度和文档频率随之增量更新，无需重建全部索引
+func (r *BM25Retriever) Upsert(ctx context.Context, documents []models.Document) error {
 	log.Infof("📝 Indexing %d documents with BM25", len(documents))
 
-	r.index.mu.Lock()
-	defer r.index.mu.Unlock()
+	// 分词在加锁前完成，避免长时间阻塞检索
+	storedDocs := make([]models.Document, 0, len(documents))
+	termPositions := make([]map[string]FieldPositions, 0, len(documents))
+	fieldLengths := make([]map[string]int, 0, len(documents))
 
-	totalLength := 0 // 累计所有文档的总词数（用于计算平均文档长度）
-
-	for _, doc := range documents {
-		docID := doc.ID
-		if docID == "" {
-			docID = fmt.Sprintf("doc_%d", r.index.TotalDocs)
-		}
-
-		// 分词
-		words := r.Tokenize(doc.Content)
-		docLength := len(words) // 当前文档的词数
-		docIDInt := int64(r.index.TotalDocs) // TotalDocs 当前值就是当前文档的ID（0, 1, 2...）
-		r.index.DocLengths[docIDInt] = docLength
-		totalLength += docLength // 累加总词数：例：文档0有50词，文档1有30词 → totalLength=80
-
-		// 构建倒排索引（词 → 文档列表 的映射）
-		// 例：{"红烧": [0, 5, 12], "肉": [0, 5, 12, 23]} 表示这些词出现在哪些文档中
-		termFreq := make(map[string]int) // 统计当前文档中每个词的出现次数
-		for _, word := range words {
-			termFreq[word]++ // 例：{"红烧": 2, "肉": 3, "怎么": 1, "做": 1}
+	for i, doc := range documents {
+		// 使用调用方的文档ID，保证 BM25 / Milvus / Neo4j 返回的ID一致
+		if doc.ID == "" {
+			return fmt.Errorf("document at index %d has no id", i)
 		}
-
-		// 更新倒排表（记录每个词出现在哪些文档中）
-		for term := range termFreq { // 遍历当前文档中的每个唯一词
-			if _, exists := r.index.Postings[term]; !exists {
-				r.index.Postings[term] = make([]int64, 0) // 初始化该词的文档列表
+		storedDocs = append(storedDocs, doc)
+
+		// 按字段分词，记录每个词在各字段中出现的位置
+		termPosition := make(map[string]FieldPositions)
+		fieldLength := make(map[string]int)
+		for field, text := range extractFields(doc) {
+			tokens := r.tokenizer.Analyze(text)
+			if len(tokens) == 0 {
+				continue
 			}
-			// 将当前文档ID添加到该词的倒排列表
-			// TotalDocs 作为计数器：处理文档0时是0，处理完后++变成1（下一个文档的ID）
-			r.index.Postings[term] = append(r.index.Postings[term], int64(r.index.TotalDocs))
+			for _, token := range tokens {
+				positions, ok := termPosition[token.Text]
+				if !ok {
+					positions = make(FieldPositions)
+					termPosition[token.Text] = positions
+				}
+				positions[field] = append(positions[field], token.Position) // 例：{"红烧肉": {"title": [0], "steps": [3, 8]}}
+			}
+			fieldLength[field] = tokens[len(tokens)-1].Position + 1 // 字段长度按词数计算，不含子词
 		}
-
-		r.index.TotalDocs++ // 处理完当前文档后递增，为下一个文档准备ID
+		termPositions = append(termPositions, termPosition)
+		fieldLengths = append(fieldLengths, fieldLength)
 	}
 
-	// 计算平均文档长度（BM25算法需要）
-	// totalLength: 所有文档的总词数（例：3000词）
-	// r.index.TotalDocs: 文档总数（例：10个文档）
-	// r.index.AvgDocLength: 平均每个文档的词数（例：3000/10=300词）
-	if r.index.TotalDocs > 0 {
-		r.index.AvgDocLength = float64(totalLength) / float64(r.index.TotalDocs)
+	// 先保存原始文档（检索时回查内容和元数据），成功后再更新索引；
+	// 两者在同一把锁内完成，检索（持有读锁回查文档）不会看到索引和文档存储不一致
+	r.index.mu.Lock()
+	if err := r.docStore.Put(ctx, storedDocs...); err != nil {
+		r.index.mu.Unlock()
+		return fmt.Errorf("failed to store documents: %w", err)
 	}
-
-	// 计算文档频率（DF: Document Frequency，即一个词出现在多少个文档中）
-	// 用于计算IDF（逆文档频率）：DF越小（词越稀有），IDF越大，权重越高
-	for term, postings := range r.index.Postings {
-		uniqueDocs := make(map[int64]bool) // 用map去重（确保同一文档只计数一次）
-		for _, docID := range postings {
-			uniqueDocs[docID] = true
+	updated := 0
+	for i, doc := range storedDocs {
+		if r.index.removeDocument(doc.ID) {
+			updated++
 		}
-		r.index.DocFreq[term] = len(uniqueDocs) // 例：Postings["红烧"]=[0,1,2] → DF=3
+		r.index.addDocument(doc.ID, termPositions[i], fieldLengths[i])
 	}
+	r.index.mu.Unlock()
 
-	log.Infof("✅ BM25 indexing completed: %d docs, avg_len: %.2f, %d unique terms",
-		r.index.TotalDocs, r.index.AvgDocLength, len(r.index.Postings))
+	stats := r.GetStats()
+	log.Infof("✅ BM25 indexing completed: %d new, %d updated, %d docs, avg_len: %.2f, %d unique terms",
+		len(storedDocs)-updated, updated, stats["total_docs"], stats["avg_doc_length"], stats["unique_terms"])
 
 	return nil
 }
 
-// Retrieve BM25检索
+// Delete 从索引和文档存储中删除文档（不存在的ID直接忽略）
+// 先删文档存储，失败时索引保持不变；两者在同一把锁内完成
+func (r *BM25Retriever) Delete(ctx context.Context, ids []string) error {
+	r.index.mu.Lock()
+	if err := r.docStore.Delete(ctx, ids...); err != nil {
+		r.index.mu.Unlock()
+		return fmt.Errorf("failed to delete documents from store: %w", err)
+	}
+	removed := 0
+	for _, id := range ids {
+		if r.index.removeDocument(id) {
+			removed++
+		}
+	}
+	r.index.mu.Unlock()
+
+	log.Infof("🗑️  BM25 deleted %d/%d documents", removed, len(ids))
+	return nil
+}
+
+// Retrieve BM25检索（ctx 中有过滤条件时跳过不满足条件的文档，见 WithFilter）
 func (r *BM25Retriever) Retrieve(ctx context.Context, query string, topK int) ([]models.Document, error) {
+	filter := FilterFromContext(ctx)
+
 	// 创建链路追踪 span
 	span := observability.GlobalTracer.StartSpan(ctx, "bm25_retrieve", map[string]interface{}{
-		"query": query,
-		"top_k": topK,
+		"query":  query,
+		"top_k":  topK,
+		"filter": filter.String(),
 	})
 	defer span.End()
 
 	startTime := time.Now()
 
-	// 分词
-	queryTerms := r.Tokenize(query)
-	if len(queryTerms) == 0 {
+	// 解析查询（短语、+必须、-排除、字段前缀），普通文本按分词结果逐词打分
+	parsed := r.ParseQuery(query)
+	if !parsed.hasPositive() {
 		return []models.Document{}, nil
 	}
 
-	log.Infof("🔍 BM25 retrieval: query='%s', terms=%d, top_k=%d", query, len(queryTerms), topK)
-	span.AddMetadata("term_count", len(queryTerms))
+	log.Infof("🔍 BM25 retrieval: query='%s', clauses=%d, top_k=%d", query, len(parsed.Clauses), topK)
+	span.AddMetadata("clause_count", len(parsed.Clauses))
+	span.AddMetadata("term_count", len(parsed.terms()))
 
 	r.index.mu.RLock()
 	defer r.index.mu.RUnlock()
 
-	// 计算每个文档的BM25分数
-	scores := make(map[int64]float64)
-
-	for _, term := range queryTerms { // 遍历查询中的每个词（如：["红烧", "肉"]）
-		postings, termExists := r.index.Postings[term] // 获取包含该词的文档列表
-		if !termExists {
-			continue // 词不在索引中，跳过
+	// 计算每个文档的BM25F分数
+	scorer := newBM25Scorer(r.config, r.index.TotalDocs, r.index.AvgFieldLengths)
+	scores := make(map[string]float64)
+	mustHits := make(map[string]int)
+	excluded := make(map[string]bool)
+
+	// explain 模式：记录每个文档各子句的得分明细
+	explain := ExplainEnabled(ctx)
+	explanations := make(map[string][]models.TermExplanation)
+	span.AddMetadata("explain", explain)
+
+	for _, clause := range parsed.Clauses { // 遍历查询子句（如：["红烧", "+五花肉", "-辣椒"]）
+		matches := r.index.matchClause(clause) // 命中子句的文档及各字段命中次数
+		if clause.Occur == OccurMustNot {
+			for docID := range matches {
+				excluded[docID] = true
+			}
+			continue
 		}
 
-		docFreq := r.index.DocFreq[term] // 该词的文档频率（出现在多少个文档中）
-		// 计算IDF（逆文档频率）：词越稀有，IDF越大
-		// 公式：log((总文档数 - 文档频率 + 0.5) / (文档频率 + 0.5))
-		idf := math.Log((float64(r.index.TotalDocs) - float64(docFreq) + 0.5) / (float64(docFreq) + 0.5))
+		// 计算IDF（逆文档频率）：词越稀有，IDF越大；短语取各词项IDF之和，同义词按合并后的文档频率计算
+		idf, docFreq := 0.0, 0
+		if clause.Phrase {
+			for _, term := range clause.Terms {
+				idf += scorer.idf(r.index.DocFreq[term])
+			}
+			docFreq = len(matches)
+		} else {
+			docFreq = r.index.clauseDocFreq(clause)
+			idf = scorer.idf(docFreq)
+		}
 
 		// 计算每个文档的分数贡献
-		for _, docID := range postings { // 遍历包含该词的所有文档
-			docLength := r.index.DocLengths[docID] // 该文档的词数
-			// 归一化因子：长文档会"惩罚"分数（避免长文档占优势）
-			// B=0.75: 如果文档长度是平均长度的2倍，因子越大，分数越低
-			normFactor := 1 - r.config.B + r.config.B*float64(docLength)/r.index.AvgDocLength
-
-			// 简化版：使用词频=1（实际应该统计词在该文档中出现的次数）
-			tf := 1.0
-			// BM25核心公式：IDF × (TF × (K1 + 1)) / (TF + K1 × 归一化因子)
-			// K1=1.5: 控制词频饱和度（TF再大，分数也不会无限增长）
-			score := idf * (tf * (r.config.K1 + 1)) / (tf + r.config.K1*normFactor)
-
-			scores[docID] += score // 累加该词对文档的分数贡献
+		for docID, freqs := range matches { // freqs 是子句在各字段中的命中次数
+			tf := scorer.fieldTF(freqs, r.index.FieldLengths[docID])
+			scores[docID] += scorer.score(idf, tf)
+			if clause.Occur == OccurMust {
+				mustHits[docID]++
+			}
+			if explain {
+				explanations[docID] = append(explanations[docID], r.index.explainClause(scorer, clause, docID, freqs, docFreq, idf))
+			}
+		}
+	}
+
+	// 过滤：排除 -词 命中的文档，有 +词 时要求全部命中
+	mustCount := parsed.mustCount()
+	for docID := range scores {
+		if excluded[docID] || mustHits[docID] < mustCount {
+			delete(scores, docID)
 		}
 	}
 
 	// 排序
 	type docScore struct {
-		DocID int64
+		DocID string
 		Score float64
 	}
 
@@ -258,13 +298,43 @@ func (r *BM25Retriever) Retrieve(ctx context.Context, query string, topK int) ([
 		return rankedDocs[i].Score > rankedDocs[j].Score
 	})
 
-	// 返回top-k结果
+	// 返回top-k结果（从文档存储回查内容和元数据，按元数据过滤）
+	// 按原文档聚合时继续收集同一菜谱的分块，直到凑满 topK 个不同的原文档
+	conditions := filter.Conditions()
 	results := make([]models.Document, 0, min(topK, len(rankedDocs)))
-	for i := 0; i < min(topK, len(rankedDocs)); i++ {
-		results = append(results, models.Document{
-			ID:    fmt.Sprintf("doc_%d", rankedDocs[i].DocID),
-			Score: float32(rankedDocs[i].Score),
-		})
+	parents := make(map[string]bool)
+	returned := func() int {
+		if r.config.GroupByParent {
+			return len(parents)
+		}
+		return len(results)
+	}
+	for i := 0; i < len(rankedDocs) && returned() < topK; i++ {
+		doc, ok := r.docStore.Get(ctx, rankedDocs[i].DocID)
+		if !ok {
+			log.Warnf("⚠️  Document %s not found in document store", rankedDocs[i].DocID)
+			doc = models.Document{ID: rankedDocs[i].DocID}
+		}
+		if len(conditions) > 0 && !conditions.Match(doc.Metadata) {
+			continue
+		}
+		doc.Score = float32(rankedDocs[i].Score)
+		r.highlight(&doc, parsed)
+		if explain {
+			doc.Explanation = &models.Explanation{
+				BM25: &models.BM25Explanation{
+					Variant: string(scorer.variant),
+					K1:      scorer.k1,
+					Score:   rankedDocs[i].Score,
+					Terms:   explanations[rankedDocs[i].DocID],
+				},
+			}
+		}
+		results = append(results, doc)
+		parents[ParentID(doc)] = true
+	}
+	if r.config.GroupByParent {
+		results = GroupByParent(results)
 	}
 
 	latency := time.Since(startTime).Milliseconds()
@@ -275,16 +345,31 @@ func (r *BM25Retriever) Retrieve(ctx context.Context, query string, topK int) ([
 	return results, nil
 }
 
+// highlight 标出文档中命中的查询词项（含同义词）并截取命中最多的句子作为摘要
+func (r *BM25Retriever) highlight(doc *models.Document, query *BM25Query) {
+	doc.Highlights = normalizeHighlights(highlightTokens(r.tokenizer, doc.Content, query.highlightTerms()))
+	doc.Snippet = snippetFor(doc.Content, doc.Highlights, defaultSnippetRunes)
+}
+
+// DocumentStore 返回BM25使用的文档存储（可共享给其他检索器回查文档）
+func (r *BM25Retriever) DocumentStore() docstore.Store {
+	return r.docStore
+}
+
 // GetStats 获取索引统计信息
 func (r *BM25Retriever) GetStats() map[string]interface{} {
 	r.index.mu.RLock()
 	defer r.index.mu.RUnlock()
 
 	return map[string]interface{}{
-		"total_docs":      r.index.TotalDocs,
-		"unique_terms":    len(r.index.Postings),
-		"avg_doc_length":  r.index.AvgDocLength,
-		"k1":              r.config.K1,
-		"b":               r.config.B,
+		"total_docs":        r.index.TotalDocs,
+		"unique_terms":      len(r.index.Postings),
+		"avg_doc_length":    r.index.AvgDocLength,
+		"avg_field_lengths": r.index.AvgFieldLengths,
+		"stored_docs":       r.docStore.Count(),
+		"k1":                r.config.K1,
+		"b":                 r.config.B,
+		"variant":           r.config.Variant,
+		"group_by_parent":   r.config.GroupByParent,
 	}
 }
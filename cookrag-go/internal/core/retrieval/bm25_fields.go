@@ -0,0 +1,103 @@
+package retrieval
+
+import (
+	"fmt"
+	"strings"
+
+	"cookrag-go/internal/models"
+)
+
+// 菜谱文档的字段（BM25F 按字段分别统计词频和长度）
+const (
+	FieldTitle       = "title"       // 菜名：一级标题 "# 炒河粉的做法" 或元数据 dish / title
+	FieldIngredients = "ingredients" // "## 必备原料和工具"
+	FieldCalculation = "calculation" // "## 计算"
+	FieldSteps       = "steps"       // "## 操作"
+	FieldBody        = "body"        // 其他内容（难度、附加内容、无结构的纯文本）
+)
+
+// recipeSectionFields 二级标题 -> 字段
+var recipeSectionFields = map[string]string{
+	"必备原料和工具": FieldIngredients,
+	"计算":      FieldCalculation,
+	"操作":      FieldSteps,
+}
+
+// titleMetadataKeys 可作为标题的元数据键
+var titleMetadataKeys = []string{"dish", "title", "name"}
+
+// BM25FieldConfig 单个字段的 BM25F 参数
+type BM25FieldConfig struct {
+	Weight float64 // 字段权重（词频乘以该权重后再累加）
+	B      float64 // 字段长度归一化参数（0 表示不做长度归一化）
+}
+
+// DefaultBM25Fields 默认字段参数：菜名命中最重要，其次是原料
+// 标题很短，长度归一化取较小的 B，避免短标题之间因长度差异拉开分数
+func DefaultBM25Fields() map[string]BM25FieldConfig {
+	return map[string]BM25FieldConfig{
+		FieldTitle:       {Weight: 5.0, B: 0.3},
+		FieldIngredients: {Weight: 2.5, B: 0.5},
+		FieldCalculation: {Weight: 1.0, B: 0.75},
+		FieldSteps:       {Weight: 1.0, B: 0.75},
+		FieldBody:        {Weight: 1.0, B: 0.75},
+	}
+}
+
+// extractFields 按 Markdown 章节把文档拆成字段文本
+// 没有章节结构的文档全部归入 body，元数据中的菜名补充到 title
+func extractFields(doc models.Document) map[string]string {
+	fields := make(map[string]*strings.Builder)
+	appendField := func(field, text string) {
+		builder, ok := fields[field]
+		if !ok {
+			builder = &strings.Builder{}
+			fields[field] = builder
+		}
+		builder.WriteString(text)
+		builder.WriteString("\n")
+	}
+
+	current := FieldBody
+	for _, line := range strings.Split(doc.Content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "# "):
+			appendField(FieldTitle, strings.TrimSpace(strings.TrimPrefix(trimmed, "# ")))
+			current = FieldBody
+			continue
+		case strings.HasPrefix(trimmed, "## "):
+			heading := strings.TrimSpace(strings.TrimPrefix(trimmed, "## "))
+			if field, ok := recipeSectionFields[heading]; ok {
+				current = field
+			} else {
+				current = FieldBody
+			}
+			continue
+		}
+		appendField(current, line)
+	}
+
+	// 元数据中的菜名：标题里已经包含时不重复计入
+	title := ""
+	if builder, ok := fields[FieldTitle]; ok {
+		title = builder.String()
+	}
+	for _, key := range titleMetadataKeys {
+		value, ok := doc.Metadata[key]
+		if !ok {
+			continue
+		}
+		name := strings.TrimSpace(fmt.Sprint(value))
+		if name != "" && !strings.Contains(title, name) {
+			appendField(FieldTitle, name)
+			title += name
+		}
+	}
+
+	result := make(map[string]string, len(fields))
+	for field, builder := range fields {
+		result[field] = builder.String()
+	}
+	return result
+}
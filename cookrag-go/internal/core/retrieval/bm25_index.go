@@ -0,0 +1,143 @@
+package retrieval
+
+import "sync"
+
+// FieldFreqs 字段 -> 词频
+// 例：{"title": 1, "ingredients": 2, "steps": 3}
+type FieldFreqs map[string]int
+
+// FieldPositions 字段 -> 词在该字段分词结果中的位置（位置个数即词频，短语/邻近查询按位置匹配）
+// 例：{"title": [0], "steps": [3, 17]}
+type FieldPositions map[string][]int
+
+// freqs 转换为各字段词频；field 不为空时只统计该字段
+func (p FieldPositions) freqs(field string) FieldFreqs {
+	freqs := make(FieldFreqs, len(p))
+	for name, positions := range p {
+		if field != "" && name != field {
+			continue
+		}
+		if len(positions) > 0 {
+			freqs[name] = len(positions)
+		}
+	}
+	return freqs
+}
+
+// InvertedIndex 倒排索引（按字段记录词的位置和字段长度，供 BM25F 评分和短语匹配）
+type InvertedIndex struct {
+	mu sync.RWMutex
+	// 词项 -> (文档ID -> 各字段中的位置)（文档ID使用调用方传入的 Document.ID）
+	// 例：{"五花肉": {"meat_dish/红烧肉.md": {"ingredients": [2], "steps": [0, 9]}}}
+	Postings map[string]map[string]FieldPositions
+	// 词项 -> 文档频率
+	DocFreq map[string]int
+	// 文档ID -> 文档长度（所有字段的词数之和）
+	DocLengths map[string]int
+	// 文档ID -> (字段 -> 字段长度)
+	FieldLengths map[string]map[string]int
+	// 文档ID -> (词项 -> 各字段中的位置)，正排表，用于删除/更新文档时定位它的倒排项
+	DocTerms map[string]map[string]FieldPositions
+	// 所有文档的总词数（增量维护平均文档长度）
+	TotalLength int
+	// 字段 -> 所有文档中该字段的总词数
+	TotalFieldLengths map[string]int
+	// 平均文档长度
+	AvgDocLength float64
+	// 字段 -> 平均字段长度（BM25F 按字段做长度归一化）
+	AvgFieldLengths map[string]float64
+	// 总文档数
+	TotalDocs int
+}
+
+// newInvertedIndex 创建空倒排索引
+func newInvertedIndex() *InvertedIndex {
+	return &InvertedIndex{
+		Postings:          make(map[string]map[string]FieldPositions),
+		DocFreq:           make(map[string]int),
+		DocLengths:        make(map[string]int),
+		FieldLengths:      make(map[string]map[string]int),
+		DocTerms:          make(map[string]map[string]FieldPositions),
+		TotalFieldLengths: make(map[string]int),
+		AvgFieldLengths:   make(map[string]float64),
+	}
+}
+
+// addDocument 添加文档到索引（调用方持有写锁，且保证文档ID不在索引中）
+func (idx *InvertedIndex) addDocument(docID string, termPositions map[string]FieldPositions, fieldLengths map[string]int) {
+	for term, positions := range termPositions {
+		postings, exists := idx.Postings[term]
+		if !exists {
+			postings = make(map[string]FieldPositions)
+			idx.Postings[term] = postings
+		}
+		postings[docID] = positions
+		idx.DocFreq[term]++ // 文档频率：该词新出现在一个文档中
+	}
+
+	docLength := 0
+	for field, length := range fieldLengths {
+		idx.TotalFieldLengths[field] += length
+		docLength += length
+	}
+
+	idx.DocTerms[docID] = termPositions
+	idx.FieldLengths[docID] = fieldLengths
+	idx.DocLengths[docID] = docLength
+	idx.TotalLength += docLength
+	idx.TotalDocs++
+	idx.updateAvgDocLength()
+}
+
+// removeDocument 从索引移除文档，返回文档是否存在（调用方持有写锁）
+func (idx *InvertedIndex) removeDocument(docID string) bool {
+	termPositions, exists := idx.DocTerms[docID]
+	if !exists {
+		return false
+	}
+
+	for term := range termPositions {
+		if postings, ok := idx.Postings[term]; ok {
+			delete(postings, docID)
+			if len(postings) == 0 {
+				delete(idx.Postings, term)
+			}
+		}
+
+		idx.DocFreq[term]--
+		if idx.DocFreq[term] <= 0 {
+			delete(idx.DocFreq, term)
+		}
+	}
+
+	for field, length := range idx.FieldLengths[docID] {
+		idx.TotalFieldLengths[field] -= length
+		if idx.TotalFieldLengths[field] <= 0 {
+			delete(idx.TotalFieldLengths, field)
+		}
+	}
+
+	idx.TotalLength -= idx.DocLengths[docID]
+	delete(idx.DocLengths, docID)
+	delete(idx.FieldLengths, docID)
+	delete(idx.DocTerms, docID)
+	idx.TotalDocs--
+	idx.updateAvgDocLength()
+
+	return true
+}
+
+// updateAvgDocLength 重新计算平均文档长度和平均字段长度（BM25算法需要）
+// 例：总词数 3000，文档数 10 → 平均每个文档 300 词
+// 字段的平均长度按全部文档计算（缺少该字段的文档长度记为0）
+func (idx *InvertedIndex) updateAvgDocLength() {
+	idx.AvgFieldLengths = make(map[string]float64, len(idx.TotalFieldLengths))
+	if idx.TotalDocs > 0 {
+		idx.AvgDocLength = float64(idx.TotalLength) / float64(idx.TotalDocs)
+		for field, total := range idx.TotalFieldLengths {
+			idx.AvgFieldLengths[field] = float64(total) / float64(idx.TotalDocs)
+		}
+	} else {
+		idx.AvgDocLength = 0
+	}
+}
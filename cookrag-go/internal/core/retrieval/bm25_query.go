@@ -0,0 +1,372 @@
+package retrieval
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ClauseOccur 查询子句的出现约束
+type ClauseOccur int
+
+const (
+	// OccurShould 可选：命中则加分（默认）
+	OccurShould ClauseOccur = iota
+	// OccurMust 必须命中（+词）
+	OccurMust
+	// OccurMustNot 必须不命中（-词），只用于过滤，不参与评分
+	OccurMustNot
+)
+
+// QueryClause 查询子句：单个词或短语
+type QueryClause struct {
+	Occur  ClauseOccur
+	Field  string   // 限定字段（为空表示所有字段）
+	Terms  []string // 分词后的词项（多个词项时按短语匹配）
+	Phrase bool     // 是否短语（引号或多个词项的 +/- 词）
+	Slop   int      // 邻近距离：相邻词项之间最多间隔的词数（"红烧 五花肉"~3）
+	// 单词子句的同义词（番茄 ↔ 西红柿），命中任一同义词即视为命中
+	Synonyms []string
+}
+
+// BM25Query 解析后的查询
+type BM25Query struct {
+	Clauses []QueryClause
+}
+
+// fieldAliases 查询中的字段前缀 -> 索引字段
+var fieldAliases = map[string]string{
+	"title":       FieldTitle,
+	"dish":        FieldTitle,
+	"name":        FieldTitle,
+	"菜名":          FieldTitle,
+	"ingredient":  FieldIngredients,
+	"ingredients": FieldIngredients,
+	"原料":          FieldIngredients,
+	"食材":          FieldIngredients,
+	"calculation": FieldCalculation,
+	"calc":        FieldCalculation,
+	"计算":          FieldCalculation,
+	"用量":          FieldCalculation,
+	"step":        FieldSteps,
+	"steps":       FieldSteps,
+	"操作":          FieldSteps,
+	"步骤":          FieldSteps,
+	"body":        FieldBody,
+}
+
+// ParseQuery 解析结构化查询
+//
+// 支持的语法（可组合）：
+//
+//	"红烧 五花肉"      短语：词项按顺序相邻出现
+//	"红烧 五花肉"~3    邻近：相邻词项之间最多间隔3个词
+//	+五花肉            必须包含
+//	-辣椒              必须不包含（包括 辣椒油 等含该词的复合词）
+//	ingredient:豆腐    限定字段（title / ingredient / calculation / step / body，也可用 原料: 等中文别名）
+//
+// 没有任何语法的普通文本与之前一样按分词结果逐词打分（OR 语义）
+func (r *BM25Retriever) ParseQuery(query string) *BM25Query {
+	parsed := &BM25Query{}
+
+	for _, raw := range splitQuery(query) {
+		clause := QueryClause{Occur: OccurShould}
+		text := raw
+
+		// 出现约束：+ / -
+		if len(text) > 1 {
+			switch text[0] {
+			case '+':
+				clause.Occur = OccurMust
+				text = text[1:]
+			case '-':
+				clause.Occur = OccurMustNot
+				text = text[1:]
+			}
+		}
+
+		// 字段前缀：field:xxx（支持中文冒号），未知字段按普通文本处理
+		if idx := strings.IndexAny(text, ":："); idx > 0 {
+			if field, ok := fieldAliases[strings.ToLower(text[:idx])]; ok {
+				clause.Field = field
+				text = strings.TrimLeft(text[idx:], ":：")
+			}
+		}
+
+		// 短语和邻近：引号包围，可带 ~N
+		if strings.HasPrefix(text, "\"") {
+			body := text[1:]
+			end := strings.LastIndex(body, "\"")
+			if end >= 0 {
+				if suffix := body[end+1:]; strings.HasPrefix(suffix, "~") {
+					if slop, err := strconv.Atoi(suffix[1:]); err == nil && slop >= 0 {
+						clause.Slop = slop
+					}
+				}
+				body = body[:end]
+			}
+			clause.Phrase = true
+			text = body
+		}
+
+		terms := r.Tokenize(text)
+		if len(terms) == 0 {
+			continue
+		}
+
+		// 普通文本：每个词一个可选子句，保持原来的 OR 语义
+		if clause.Occur == OccurShould && !clause.Phrase {
+			for _, term := range terms {
+				parsed.Clauses = append(parsed.Clauses, QueryClause{
+					Occur:    OccurShould,
+					Field:    clause.Field,
+					Terms:    []string{term},
+					Synonyms: r.synonymsOf(term),
+				})
+			}
+			continue
+		}
+
+		// +/- 词被切成多个词项时按短语处理（"+五花肉片" 要求词项连续出现）
+		clause.Terms = terms
+		clause.Phrase = len(terms) > 1
+		if !clause.Phrase {
+			clause.Synonyms = r.synonymsOf(terms[0])
+		}
+		parsed.Clauses = append(parsed.Clauses, clause)
+	}
+
+	return parsed
+}
+
+// synonymsOf 词的同义词（不含词本身）
+func (r *BM25Retriever) synonymsOf(term string) []string {
+	if r.synonyms == nil {
+		return nil
+	}
+
+	synonyms := make([]string, 0)
+	for _, synonym := range r.synonyms.Expand(term) {
+		if synonym != term {
+			synonyms = append(synonyms, synonym)
+		}
+	}
+	return synonyms
+}
+
+// hasPositive 是否有参与评分的子句（只有 -词 的查询不返回结果）
+func (q *BM25Query) hasPositive() bool {
+	for _, clause := range q.Clauses {
+		if clause.Occur != OccurMustNot {
+			return true
+		}
+	}
+	return false
+}
+
+// mustCount 必须命中的子句数
+func (q *BM25Query) mustCount() int {
+	count := 0
+	for _, clause := range q.Clauses {
+		if clause.Occur == OccurMust {
+			count++
+		}
+	}
+	return count
+}
+
+// terms 所有参与评分的词项
+func (q *BM25Query) terms() []string {
+	terms := make([]string, 0)
+	for _, clause := range q.Clauses {
+		if clause.Occur != OccurMustNot {
+			terms = append(terms, clause.Terms...)
+		}
+	}
+	return terms
+}
+
+// highlightTerms 需要在结果中标出的词项（参与评分的词项及同义词）
+func (q *BM25Query) highlightTerms() map[string]bool {
+	terms := make(map[string]bool)
+	for _, clause := range q.Clauses {
+		if clause.Occur == OccurMustNot {
+			continue
+		}
+		for _, term := range clause.Terms {
+			terms[term] = true
+		}
+		for _, synonym := range clause.Synonyms {
+			terms[synonym] = true
+		}
+	}
+	return terms
+}
+
+// splitQuery 按空白切分查询，引号内的空白不切分
+func splitQuery(query string) []string {
+	parts := make([]string, 0)
+	var current strings.Builder
+	inQuote := false
+
+	for _, ch := range query {
+		switch {
+		case ch == '"' || ch == '“' || ch == '”':
+			inQuote = !inQuote
+			current.WriteRune('"')
+		case unicode.IsSpace(ch) && !inQuote:
+			if current.Len() > 0 {
+				parts = append(parts, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(ch)
+		}
+	}
+	if current.Len() > 0 {
+		parts = append(parts, current.String())
+	}
+
+	return parts
+}
+
+// matchClause 查找命中子句的文档及各字段命中次数（调用方持有读锁）
+// 单词子句的命中次数即词频；短语子句为短语出现次数
+func (idx *InvertedIndex) matchClause(clause QueryClause) map[string]FieldFreqs {
+	matches := make(map[string]FieldFreqs)
+	if len(clause.Terms) == 0 {
+		return matches
+	}
+
+	// 排除词按包含关系匹配：-辣椒 同时排除分词为 辣椒油、小米辣椒 等复合词的文档
+	if clause.Occur == OccurMustNot && !clause.Phrase {
+		for _, excluded := range clause.termGroup() {
+			for term, postings := range idx.Postings {
+				if !strings.Contains(term, excluded) {
+					continue
+				}
+				for docID, positions := range postings {
+					if freqs := positions.freqs(clause.Field); len(freqs) > 0 {
+						matches[docID] = freqs
+					}
+				}
+			}
+		}
+		return matches
+	}
+
+	// 单词：合并词本身和同义词的命中次数
+	if !clause.Phrase {
+		for _, term := range clause.termGroup() {
+			for docID, positions := range idx.Postings[term] {
+				freqs := positions.freqs(clause.Field)
+				if len(freqs) == 0 {
+					continue
+				}
+				merged, ok := matches[docID]
+				if !ok {
+					merged = make(FieldFreqs, len(freqs))
+					matches[docID] = merged
+				}
+				for field, freq := range freqs {
+					merged[field] += freq
+				}
+			}
+		}
+		return matches
+	}
+
+	first, ok := idx.Postings[clause.Terms[0]]
+	if !ok {
+		return matches
+	}
+
+	// 短语：先取所有词项都出现的文档，再按位置逐字段匹配
+	termPostings := make([]map[string]FieldPositions, len(clause.Terms))
+	for i, term := range clause.Terms {
+		postings, ok := idx.Postings[term]
+		if !ok {
+			return matches
+		}
+		termPostings[i] = postings
+	}
+
+	for docID, firstPositions := range first {
+		freqs := make(FieldFreqs)
+		for field := range firstPositions {
+			if clause.Field != "" && field != clause.Field {
+				continue
+			}
+
+			positions := make([][]int, len(clause.Terms))
+			for i, postings := range termPostings {
+				positions[i] = postings[docID][field]
+			}
+			if count := countPhrase(positions, clause.Slop); count > 0 {
+				freqs[field] = count
+			}
+		}
+		if len(freqs) > 0 {
+			matches[docID] = freqs
+		}
+	}
+
+	return matches
+}
+
+// clauseDocFreq 子句的文档频率（单词子句为词本身及同义词命中的文档数，调用方持有读锁）
+func (idx *InvertedIndex) clauseDocFreq(clause QueryClause) int {
+	if len(clause.Synonyms) == 0 {
+		return idx.DocFreq[clause.Terms[0]]
+	}
+
+	docs := make(map[string]bool)
+	for _, term := range clause.termGroup() {
+		for docID := range idx.Postings[term] {
+			docs[docID] = true
+		}
+	}
+	return len(docs)
+}
+
+// termGroup 单词子句的词本身及同义词
+func (c QueryClause) termGroup() []string {
+	return append([]string{c.Terms[0]}, c.Synonyms...)
+}
+
+// countPhrase 统计短语出现次数
+// positions[i] 为第 i 个词项的位置（升序）；相邻词项须按顺序出现，间隔不超过 slop 个词
+func countPhrase(positions [][]int, slop int) int {
+	for _, termPositions := range positions {
+		if len(termPositions) == 0 {
+			return 0
+		}
+	}
+
+	count := 0
+	for _, start := range positions[0] {
+		if matchPhraseFrom(positions, 1, start, slop) {
+			count++
+		}
+	}
+	return count
+}
+
+// matchPhraseFrom 从第 i 个词项开始，检查能否接在位置 prev 之后
+func matchPhraseFrom(positions [][]int, i, prev, slop int) bool {
+	if i == len(positions) {
+		return true
+	}
+	for _, pos := range positions[i] {
+		if pos <= prev {
+			continue
+		}
+		if pos-prev-1 > slop {
+			break
+		}
+		if matchPhraseFrom(positions, i+1, pos, slop) {
+			return true
+		}
+	}
+	return false
+}
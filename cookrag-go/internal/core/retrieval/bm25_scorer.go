@@ -0,0 +1,147 @@
+package retrieval
+
+import (
+	"fmt"
+	"math"
+)
+
+// BM25Variant BM25评分变体
+type BM25Variant string
+
+const (
+	// BM25Classic 经典BM25（Robertson / Lucene 形式）
+	BM25Classic BM25Variant = "bm25"
+	// BM25Plus BM25+：给出现过的词一个下界δ，避免长文档中的匹配词得分趋近于0
+	BM25Plus BM25Variant = "bm25+"
+	// BM25L BM25L：对长度归一化后的词频整体平移δ，减轻对长文档的过度惩罚
+	BM25L BM25Variant = "bm25l"
+)
+
+// BM25Variants 支持的评分变体
+var BM25Variants = []BM25Variant{BM25Classic, BM25Plus, BM25L}
+
+// ValidateBM25Variant 检查评分变体名（空字符串表示使用默认的经典BM25）
+func ValidateBM25Variant(variant BM25Variant) error {
+	switch variant {
+	case "", BM25Classic, BM25Plus, BM25L:
+		return nil
+	default:
+		return fmt.Errorf("unsupported bm25 variant: %q (supported: %v)", variant, BM25Variants)
+	}
+}
+
+// 各变体δ的默认值（取自 Lv & Zhai 的论文推荐值）
+const (
+	defaultBM25PlusDelta = 1.0
+	defaultBM25LDelta    = 0.5
+)
+
+// bm25Scorer BM25评分器（一次检索内共享索引统计量）
+// 词频按 BM25F 计算：各字段词频先按字段长度归一化、乘以字段权重后累加成伪词频，再统一做饱和
+type bm25Scorer struct {
+	k1              float64
+	b               float64
+	delta           float64
+	variant         BM25Variant
+	totalDocs       float64
+	fields          map[string]BM25FieldConfig
+	avgFieldLengths map[string]float64
+}
+
+// newBM25Scorer 创建评分器
+func newBM25Scorer(config *BM25Config, totalDocs int, avgFieldLengths map[string]float64) *bm25Scorer {
+	variant := config.Variant
+	if variant == "" {
+		variant = BM25Classic
+	}
+
+	delta := config.Delta
+	if delta <= 0 {
+		switch variant {
+		case BM25Plus:
+			delta = defaultBM25PlusDelta
+		case BM25L:
+			delta = defaultBM25LDelta
+		}
+	}
+
+	return &bm25Scorer{
+		k1:              config.K1,
+		b:               config.B,
+		delta:           delta,
+		variant:         variant,
+		totalDocs:       float64(totalDocs),
+		fields:          config.Fields,
+		avgFieldLengths: avgFieldLengths,
+	}
+}
+
+// idf 逆文档频率（词越稀有，IDF越大）
+func (s *bm25Scorer) idf(docFreq int) float64 {
+	df := float64(docFreq)
+	if df <= 0 {
+		return 0
+	}
+
+	switch s.variant {
+	case BM25Plus:
+		// log((N+1)/df)
+		return math.Log((s.totalDocs + 1) / df)
+	case BM25L:
+		// log((N+1)/(df+0.5))
+		return math.Log((s.totalDocs + 1) / (df + 0.5))
+	default:
+		// log(1 + (N-df+0.5)/(df+0.5))：加1保证常见词的IDF不为负
+		return math.Log(1 + (s.totalDocs-df+0.5)/(df+0.5))
+	}
+}
+
+// fieldConfig 字段参数（未配置的字段权重为1，长度归一化使用全局B）
+func (s *bm25Scorer) fieldConfig(field string) BM25FieldConfig {
+	if fieldConfig, ok := s.fields[field]; ok {
+		return fieldConfig
+	}
+	return BM25FieldConfig{Weight: 1, B: s.b}
+}
+
+// lengthNorm 长度归一化因子：1 - b + b × 字段长度 / 平均字段长度
+// 长字段的因子大于1，会"惩罚"分数（避免长文档占优势）
+func (s *bm25Scorer) lengthNorm(b float64, length int, avgLength float64) float64 {
+	if avgLength <= 0 {
+		return 1
+	}
+	return 1 - b + b*float64(length)/avgLength
+}
+
+// fieldTF BM25F 伪词频：Σ 字段权重 × 字段词频 / 字段长度归一化因子
+// 例：菜名中出现1次（权重5）比步骤中出现3次（权重1）贡献更大
+func (s *bm25Scorer) fieldTF(freqs FieldFreqs, fieldLengths map[string]int) float64 {
+	tf := 0.0
+	for field, freq := range freqs {
+		fieldConfig := s.fieldConfig(field)
+		norm := s.lengthNorm(fieldConfig.B, fieldLengths[field], s.avgFieldLengths[field])
+		tf += fieldConfig.Weight * float64(freq) / norm
+	}
+	return tf
+}
+
+// score 单个词对单个文档的分数贡献
+// tf: 长度归一化后的（伪）词频
+func (s *bm25Scorer) score(idf, tf float64) float64 {
+	if tf <= 0 {
+		return 0
+	}
+
+	switch s.variant {
+	case BM25Plus:
+		// IDF × (TF × (K1 + 1) / (TF + K1) + δ)
+		return idf * (tf*(s.k1+1)/(tf+s.k1) + s.delta)
+	case BM25L:
+		// IDF × (K1 + 1) × (TF + δ) / (K1 + TF + δ)
+		return idf * (s.k1 + 1) * (tf + s.delta) / (s.k1 + tf + s.delta)
+	default:
+		// IDF × (TF × (K1 + 1)) / (TF + K1)
+		// K1 控制词频饱和度（TF再大，分数也不会无限增长）
+		return idf * (tf * (s.k1 + 1)) / (tf + s.k1)
+	}
+}
@@ -0,0 +1,351 @@
+package retrieval
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"cookrag-go/internal/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// bm25SnapshotMagic 快照文件魔数
+const bm25SnapshotMagic = "CRBM25"
+
+// bm25SnapshotVersion 快照格式版本（索引结构变化时递增，旧快照会被判定为失效）
+const bm25SnapshotVersion = 4
+
+var (
+	// ErrSnapshotVersion 快照格式版本不匹配
+	ErrSnapshotVersion = errors.New("bm25 snapshot format version mismatch")
+	// ErrSnapshotStale 快照由不同的分词词典构建，需要重建
+	ErrSnapshotStale = errors.New("bm25 snapshot built with a different tokenizer dictionary")
+	// ErrSnapshotCorrupted 快照校验失败
+	ErrSnapshotCorrupted = errors.New("bm25 snapshot checksum mismatch")
+)
+
+func init() {
+	// 文档元数据是 map[string]interface{}，其中的列表值（如原料列表）需要注册后 gob 才能编码
+	gob.Register([]string{})
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// bm25SnapshotHeader 快照头（版本、词典指纹、数据校验和）
+type bm25SnapshotHeader struct {
+	Version              int
+	TokenizerFingerprint string
+	Checksum             [sha256.Size]byte
+	CreatedAt            time.Time
+	TotalDocs            int
+}
+
+// bm25SnapshotPayload 快照数据（索引 + 文档 + 构建时的配置）
+type bm25SnapshotPayload struct {
+	Config            BM25Config
+	Postings          map[string]map[string]FieldPositions
+	DocFreq           map[string]int
+	DocLengths        map[string]int
+	FieldLengths      map[string]map[string]int
+	DocTerms          map[string]map[string]FieldPositions
+	TotalLength       int
+	TotalFieldLengths map[string]int
+	TotalDocs         int
+	Documents         []models.Document
+}
+
+// SaveSnapshot 将倒排索引、文档存储和配置保存为快照文件
+// 文件格式：魔数 | 头长度(uint32) | 头(gob) | 数据(gob)，头中记录数据的 SHA-256 校验和
+func (r *BM25Retriever) SaveSnapshot(ctx context.Context, path string) error {
+	startTime := time.Now()
+
+	r.index.mu.RLock()
+	payload := bm25SnapshotPayload{
+		Config:            *r.config,
+		Postings:          r.index.Postings,
+		DocFreq:           r.index.DocFreq,
+		DocLengths:        r.index.DocLengths,
+		FieldLengths:      r.index.FieldLengths,
+		DocTerms:          r.index.DocTerms,
+		TotalLength:       r.index.TotalLength,
+		TotalFieldLengths: r.index.TotalFieldLengths,
+		TotalDocs:         r.index.TotalDocs,
+	}
+	for _, id := range r.docStore.IDs(ctx) {
+		if doc, ok := r.docStore.Get(ctx, id); ok {
+			payload.Documents = append(payload.Documents, doc)
+		}
+	}
+
+	var payloadBuf bytes.Buffer
+	err := gob.NewEncoder(&payloadBuf).Encode(&payload)
+	r.index.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode bm25 snapshot: %w", err)
+	}
+
+	fingerprint := r.tokenizer.Fingerprint()
+
+	header := bm25SnapshotHeader{
+		Version:              bm25SnapshotVersion,
+		TokenizerFingerprint: fingerprint,
+		Checksum:             sha256.Sum256(payloadBuf.Bytes()),
+		CreatedAt:            time.Now(),
+		TotalDocs:            payload.TotalDocs,
+	}
+	var headerBuf bytes.Buffer
+	if err := gob.NewEncoder(&headerBuf).Encode(&header); err != nil {
+		return fmt.Errorf("failed to encode bm25 snapshot header: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+	}
+
+	// 先写临时文件再 rename，避免进程中断留下半个快照
+	tmpPath := path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+
+	w := bufio.NewWriter(file)
+	w.WriteString(bm25SnapshotMagic)
+	binary.Write(w, binary.BigEndian, uint32(headerBuf.Len()))
+	w.Write(headerBuf.Bytes())
+	w.Write(payloadBuf.Bytes())
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace snapshot file: %w", err)
+	}
+
+	log.Infof("💾 BM25 snapshot saved: %s (%d docs, %d bytes, %dms)",
+		path, payload.TotalDocs, payloadBuf.Len(), time.Since(startTime).Milliseconds())
+	return nil
+}
+
+// LoadSnapshot 从快照文件恢复索引和文档存储
+// 格式版本或分词词典指纹不一致时返回 ErrSnapshotVersion / ErrSnapshotStale，调用方应重建索引
+func (r *BM25Retriever) LoadSnapshot(ctx context.Context, path string) error {
+	startTime := time.Now()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	magic := make([]byte, len(bm25SnapshotMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != bm25SnapshotMagic {
+		return fmt.Errorf("%s is not a bm25 snapshot: %w", path, ErrSnapshotCorrupted)
+	}
+
+	var headerLen uint32
+	if err := binary.Read(reader, binary.BigEndian, &headerLen); err != nil {
+		return fmt.Errorf("failed to read snapshot header: %w", err)
+	}
+	headerBytes := make([]byte, headerLen)
+	if _, err := io.ReadFull(reader, headerBytes); err != nil {
+		return fmt.Errorf("failed to read snapshot header: %w", err)
+	}
+
+	var header bm25SnapshotHeader
+	if err := gob.NewDecoder(bytes.NewReader(headerBytes)).Decode(&header); err != nil {
+		return fmt.Errorf("failed to decode snapshot header: %w", err)
+	}
+
+	if header.Version != bm25SnapshotVersion {
+		return fmt.Errorf("snapshot version %d, expected %d: %w", header.Version, bm25SnapshotVersion, ErrSnapshotVersion)
+	}
+
+	fingerprint := r.tokenizer.Fingerprint()
+	if header.TokenizerFingerprint != fingerprint {
+		return ErrSnapshotStale
+	}
+
+	payloadBytes, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot payload: %w", err)
+	}
+	if sha256.Sum256(payloadBytes) != header.Checksum {
+		return ErrSnapshotCorrupted
+	}
+
+	var payload bm25SnapshotPayload
+	if err := gob.NewDecoder(bytes.NewReader(payloadBytes)).Decode(&payload); err != nil {
+		return fmt.Errorf("failed to decode snapshot payload: %w", err)
+	}
+
+	// 评分参数以当前配置为准（索引本身与 K1/B 无关），仅提示差异
+	if payload.Config.K1 != r.config.K1 || payload.Config.B != r.config.B || payload.Config.Variant != r.config.Variant {
+		log.Infof("ℹ️  BM25 snapshot was built with k1=%.2f b=%.2f variant=%s, using current k1=%.2f b=%.2f variant=%s",
+			payload.Config.K1, payload.Config.B, payload.Config.Variant, r.config.K1, r.config.B, r.config.Variant)
+	}
+
+	index := payload.toIndex()
+
+	// 文档存储替换为快照中的文档：先删除快照中没有的文档（如上次运行后残留的已删除菜谱），再写入快照文档
+	r.index.mu.Lock()
+	defer r.index.mu.Unlock()
+
+	snapshotIDs := make(map[string]bool, len(payload.Documents))
+	for _, doc := range payload.Documents {
+		snapshotIDs[doc.ID] = true
+	}
+	var staleIDs []string
+	for _, id := range r.docStore.IDs(ctx) {
+		if !snapshotIDs[id] {
+			staleIDs = append(staleIDs, id)
+		}
+	}
+	if err := r.docStore.Delete(ctx, staleIDs...); err != nil {
+		return fmt.Errorf("failed to clear document store: %w", err)
+	}
+	if err := r.docStore.Put(ctx, payload.Documents...); err != nil {
+		return fmt.Errorf("failed to restore documents: %w", err)
+	}
+
+	r.index.Postings = index.Postings
+	r.index.DocFreq = index.DocFreq
+	r.index.DocLengths = index.DocLengths
+	r.index.FieldLengths = index.FieldLengths
+	r.index.DocTerms = index.DocTerms
+	r.index.TotalLength = index.TotalLength
+	r.index.TotalFieldLengths = index.TotalFieldLengths
+	r.index.TotalDocs = index.TotalDocs
+	r.index.AvgDocLength = index.AvgDocLength
+	r.index.AvgFieldLengths = index.AvgFieldLengths
+
+	log.Infof("📂 BM25 snapshot loaded: %s (%d docs, built %s, %dms)",
+		path, header.TotalDocs, header.CreatedAt.Format(time.RFC3339), time.Since(startTime).Milliseconds())
+	return nil
+}
+
+// toIndex 用快照数据构建倒排索引
+// gob 不编码空 map，解码后为 nil 的表需要重新初始化
+func (p *bm25SnapshotPayload) toIndex() *InvertedIndex {
+	index := newInvertedIndex()
+	if p.Postings != nil {
+		index.Postings = p.Postings
+	}
+	if p.DocFreq != nil {
+		index.DocFreq = p.DocFreq
+	}
+	if p.DocLengths != nil {
+		index.DocLengths = p.DocLengths
+	}
+	if p.FieldLengths != nil {
+		index.FieldLengths = p.FieldLengths
+	}
+	if p.DocTerms != nil {
+		index.DocTerms = p.DocTerms
+	}
+	if p.TotalFieldLengths != nil {
+		index.TotalFieldLengths = p.TotalFieldLengths
+	}
+	index.TotalLength = p.TotalLength
+	index.TotalDocs = p.TotalDocs
+	index.updateAvgDocLength()
+	return index
+}
+
+// RestoreOrIndex 优先从配置的快照恢复索引，再增量索引快照中缺失或内容已变化的文档，并删除 documents 中已不存在的文档
+// 快照不存在、失效或损坏时全量重建；索引有变化时写入新快照。未配置 SnapshotPath 时直接索引
+func (r *BM25Retriever) RestoreOrIndex(ctx context.Context, documents []models.Document) error {
+	snapshotPath := r.config.SnapshotPath
+	if snapshotPath == "" {
+		return r.IndexDocuments(ctx, documents)
+	}
+
+	pending := documents
+	if err := r.LoadSnapshot(ctx, snapshotPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Infof("📝 BM25 snapshot not found, building index: %s", snapshotPath)
+		} else {
+			log.Warnf("⚠️  BM25 snapshot unusable, rebuilding index: %v", err)
+		}
+	} else {
+		pending = r.changedDocuments(ctx, documents)
+	}
+
+	// 已从语料中删除的文档（文档存储中有、本次 documents 中没有）
+	staleIDs := r.staleDocumentIDs(ctx, documents)
+	if len(pending) == 0 && len(staleIDs) == 0 {
+		return nil
+	}
+	log.Infof("📝 Updating BM25 index: %d new or changed documents, %d removed", len(pending), len(staleIDs))
+
+	if len(pending) > 0 {
+		if err := r.IndexDocuments(ctx, pending); err != nil {
+			return err
+		}
+	}
+	if len(staleIDs) > 0 {
+		if err := r.Delete(ctx, staleIDs); err != nil {
+			return err
+		}
+	}
+
+	if err := r.SaveSnapshot(ctx, snapshotPath); err != nil {
+		log.Warnf("⚠️  Failed to save BM25 snapshot: %v", err)
+	}
+
+	return nil
+}
+
+// changedDocuments 返回不在文档存储中或内容、元数据已变化的文档（元数据参与过滤，变化后也要更新）
+// 按 StableDocumentID 查找，与 IndexDocuments 分配的ID一致（加载器的文档没有调用方ID）
+func (r *BM25Retriever) changedDocuments(ctx context.Context, documents []models.Document) []models.Document {
+	changed := make([]models.Document, 0)
+	for _, doc := range documents {
+		stored, ok := r.docStore.Get(ctx, StableDocumentID(doc))
+		if !ok || stored.Content != doc.Content || !sameMetadata(stored.Metadata, doc.Metadata) {
+			changed = append(changed, doc)
+		}
+	}
+	return changed
+}
+
+// staleDocumentIDs 文档存储中有、documents 中没有的文档ID（按 StableDocumentID 比较）
+func (r *BM25Retriever) staleDocumentIDs(ctx context.Context, documents []models.Document) []string {
+	current := make(map[string]bool, len(documents))
+	for _, doc := range documents {
+		current[StableDocumentID(doc)] = true
+	}
+
+	var stale []string
+	for _, id := range r.docStore.IDs(ctx) {
+		if !current[id] {
+			stale = append(stale, id)
+		}
+	}
+	return stale
+}
+
+// sameMetadata 按 JSON 编码比较元数据（快照解码后 int 变为 float64、[]string 变为 []interface{}，直接比较会误判）
+func sameMetadata(a, b map[string]interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
@@ -0,0 +1,109 @@
+package retrieval
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"cookrag-go/internal/models"
+)
+
+// OverviewSection 一级标题下、第一个二级标题之前的内容（菜品简介、难度）或没有标题的纯文本所在的章节名
+const OverviewSection = "概述"
+
+// 按原文档聚合分块命中时写入的元数据
+const (
+	MatchedSectionsMetadataKey = "matched_sections" // 命中的章节（按分数从高到低）
+	ChunkHitsMetadataKey       = "chunk_hits"       // 命中的分块数
+	ChunkScoresMetadataKey     = "chunk_scores"     // 命中分块的分数（按分数从高到低）
+	ChunkIDMetadataKey         = "chunk_id"         // 分数最高的分块ID（向量按分块存储，据此读取文档向量）
+)
+
+// defaultGroupFetchFactor 按原文档聚合时的多取倍数（同一菜谱的多个分块可能同时命中）
+const defaultGroupFetchFactor = 3
+
+// ParentID 文档所属的原文档ID：分块返回 parent_id，整篇文档返回自身ID
+func ParentID(doc models.Document) string {
+	if parentID, ok := doc.Metadata[ParentMetadataKey].(string); ok && parentID != "" {
+		return parentID
+	}
+	return doc.ID
+}
+
+// GroupByParent 把同一原文档的分块命中合并为一条结果（输入需按分数从高到低排列）
+// 结果ID为原文档ID，分数取最高分块的分数，内容、摘要和高亮沿用最高分块；
+// 元数据中追加命中的章节、分块数和各分块分数。整篇文档（没有 parent_id）按自身ID参与合并
+func GroupByParent(documents []models.Document) []models.Document {
+	grouped := make([]models.Document, 0, len(documents))
+	positions := make(map[string]int)
+	for _, doc := range documents {
+		parentID := ParentID(doc)
+		section, _ := doc.Metadata[SectionMetadataKey].(string)
+
+		if pos, ok := positions[parentID]; ok {
+			best := &grouped[pos]
+			best.Metadata[ChunkHitsMetadataKey] = best.Metadata[ChunkHitsMetadataKey].(int) + 1
+			best.Metadata[ChunkScoresMetadataKey] = append(best.Metadata[ChunkScoresMetadataKey].([]float64), float64(doc.Score))
+			if section != "" {
+				sections, _ := best.Metadata[MatchedSectionsMetadataKey].([]string)
+				best.Metadata[MatchedSectionsMetadataKey] = appendUniqueString(sections, section)
+			}
+			continue
+		}
+
+		// 复制元数据，避免修改文档存储中的文档
+		metadata := make(map[string]interface{}, len(doc.Metadata)+3)
+		for k, v := range doc.Metadata {
+			metadata[k] = v
+		}
+		metadata[ChunkHitsMetadataKey] = 1
+		metadata[ChunkScoresMetadataKey] = []float64{float64(doc.Score)}
+		if doc.ID != parentID {
+			metadata[ChunkIDMetadataKey] = doc.ID
+		}
+		if section != "" {
+			metadata[MatchedSectionsMetadataKey] = []string{section}
+		}
+
+		doc.ID = parentID
+		doc.Metadata = metadata
+		grouped = append(grouped, doc)
+		positions[parentID] = len(grouped) - 1
+	}
+	return grouped
+}
+
+// appendUniqueString 追加不重复的字符串
+func appendUniqueString(items []string, item string) []string {
+	for _, existing := range items {
+		if existing == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
+
+// EstimateTokens 估算 token 数：汉字和标点各算一个 token，连续的英文字母和数字约 4 个字符一个 token
+// 与常见中文 Embedding 模型的分词结果接近，用于控制分块和上下文长度而不依赖具体模型的词表
+func EstimateTokens(text string) int {
+	tokens, word := 0, 0
+	flushWord := func() {
+		if word > 0 {
+			tokens += (word + 3) / 4
+			word = 0
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			word++
+		case unicode.IsSpace(r):
+			flushWord()
+		default:
+			flushWord()
+			tokens++
+		}
+	}
+	flushWord()
+	return tokens
+}
@@ -0,0 +1,42 @@
+package retrieval
+
+import (
+	"crypto/md5"
+	"fmt"
+
+	"cookrag-go/internal/models"
+)
+
+// 文档来源元数据字段（加载菜谱时写入，用于生成稳定的文档ID）
+const (
+	SourceMetadataKey     = "file"        // 来源文件相对路径，如 meat_dish/红烧肉.md
+	ChunkMetadataKey      = "chunk_index" // 分块序号（整篇文档为 0）
+	ChunkCountMetadataKey = "chunk_count" // 所属文档的分块总数
+	ParentMetadataKey     = "parent_id"   // 分块所属的原文档ID（整篇文档没有该字段）
+	SectionMetadataKey    = "section"     // 分块所在章节的标题路径，如 操作 > 预处理
+	DishMetadataKey       = "dish"        // 菜名
+)
+
+// StableDocumentID 文档的稳定ID：同一来源重复索引得到相同ID，从而替换而不是新增
+// 优先使用调用方ID；否则由来源路径和分块序号生成（meat_dish/红烧肉.md#0）；都没有时使用内容哈希
+func StableDocumentID(doc models.Document) string {
+	if doc.ID != "" {
+		return doc.ID
+	}
+
+	if source, ok := doc.Metadata[SourceMetadataKey].(string); ok && source != "" {
+		chunk := 0
+		switch v := doc.Metadata[ChunkMetadataKey].(type) {
+		case int:
+			chunk = v
+		case int64:
+			chunk = int(v)
+		case float64:
+			chunk = int(v)
+		}
+		return fmt.Sprintf("%s#%d", source, chunk)
+	}
+
+	hash := md5.Sum([]byte(doc.Content))
+	return fmt.Sprintf("doc_%x", hash[:8])
+}
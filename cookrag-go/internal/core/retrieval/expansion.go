@@ -0,0 +1,197 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"cookrag-go/internal/models"
+	"cookrag-go/internal/observability"
+	"cookrag-go/pkg/ml/llm"
+
+	"github.com/charmbracelet/log"
+)
+
+// 查询变体类型
+const (
+	VariantOriginal   = "original"   // 原始查询
+	VariantParaphrase = "paraphrase" // LLM 改写的同义查询（multi-query）
+	VariantHyDE       = "hyde"       // LLM 生成的假设菜谱答案（HyDE），只交给向量检索，找与之相似的真实菜谱
+	VariantPartial    = "partial"    // 相邻词对组成的部分查询（没有 LLM 时的兜底扩展）
+)
+
+// QueryExpanderConfig LLM 查询扩展配置
+type QueryExpanderConfig struct {
+	Paraphrases    int           // 改写数量（0 表示不改写）
+	HyDE           bool          // 是否生成假设答案
+	MaxHyDERunes   int           // 假设答案最多保留的字数
+	Timeout        time.Duration // 生成变体的超时时间（超时后只用已生成的变体）
+	OriginalWeight float64       // 原始查询在变体融合中的权重
+	VariantWeight  float64       // 其他变体在变体融合中的权重
+	EnabledDefault bool          // 请求未指定时是否扩展（见 WithExpansion）
+}
+
+// DefaultQueryExpanderConfig 默认配置
+func DefaultQueryExpanderConfig() *QueryExpanderConfig {
+	return &QueryExpanderConfig{
+		Paraphrases:    3,
+		HyDE:           true,
+		MaxHyDERunes:   300,
+		Timeout:        10 * time.Second,
+		OriginalWeight: 1.0,
+		VariantWeight:  0.8,
+		EnabledDefault: false,
+	}
+}
+
+// QueryExpander 用 LLM 生成查询变体：改写（multi-query）和假设菜谱答案（HyDE）
+// 两类变体并行生成，任一失败时只使用另一类（都失败时只有原始查询）
+type QueryExpander struct {
+	config   *QueryExpanderConfig
+	provider llm.Provider
+}
+
+// NewQueryExpander 创建查询扩展器
+func NewQueryExpander(config *QueryExpanderConfig, provider llm.Provider) *QueryExpander {
+	if config == nil {
+		config = DefaultQueryExpanderConfig()
+	}
+
+	return &QueryExpander{
+		config:   config,
+		provider: provider,
+	}
+}
+
+// Expand 生成查询变体（第一个总是原始查询）
+func (e *QueryExpander) Expand(ctx context.Context, query string) []models.QueryVariant {
+	span := observability.GlobalTracer.StartSpan(ctx, "query_expansion", map[string]interface{}{
+		"query":       query,
+		"paraphrases": e.config.Paraphrases,
+		"hyde":        e.config.HyDE,
+	})
+	defer span.End()
+
+	if e.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.Timeout)
+		defer cancel()
+	}
+
+	var paraphrases []string
+	var hypothetical string
+	var paraphraseErr, hydeErr error
+	var wg sync.WaitGroup
+	if e.config.Paraphrases > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			paraphrases, paraphraseErr = e.paraphrase(ctx, query)
+		}()
+	}
+	if e.config.HyDE {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hypothetical, hydeErr = e.hypotheticalAnswer(ctx, query)
+		}()
+	}
+	wg.Wait()
+
+	variants := []models.QueryVariant{{Kind: VariantOriginal, Text: query}}
+	if paraphraseErr != nil {
+		log.Warnf("⚠️  Query paraphrasing failed: %v", paraphraseErr)
+		span.AddMetadata("paraphrase_error", paraphraseErr.Error())
+	}
+	for _, text := range paraphrases {
+		variants = append(variants, models.QueryVariant{Kind: VariantParaphrase, Text: text})
+	}
+	if hydeErr != nil {
+		log.Warnf("⚠️  HyDE generation failed: %v", hydeErr)
+		span.AddMetadata("hyde_error", hydeErr.Error())
+	}
+	if hypothetical != "" {
+		variants = append(variants, models.QueryVariant{Kind: VariantHyDE, Text: hypothetical})
+	}
+
+	span.AddMetadata("variant_count", len(variants))
+	log.Infof("🪄 Expanded query into %d variants (%d paraphrases, hyde=%t)",
+		len(variants), len(paraphrases), hypothetical != "")
+	return variants
+}
+
+// paraphrase 让 LLM 改写查询，返回去重后的改写（不含原始查询）
+func (e *QueryExpander) paraphrase(ctx context.Context, query string) ([]string, error) {
+	prompt := fmt.Sprintf(`你是菜谱检索系统的查询改写助手。请把用户的问题改写成 %d 个不同说法的检索查询，
+可以换用菜名别称、食材名称或更具体的做法描述，但不要改变问题的意思。
+
+问题：%s
+
+每行输出一个查询，不要编号，不要解释。`, e.config.Paraphrases, query)
+
+	answer, err := e.provider.Generate(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate paraphrases: %w", err)
+	}
+	return parseParaphrases(answer, query, e.config.Paraphrases), nil
+}
+
+// hypotheticalAnswer 让 LLM 写一段假设的菜谱答案（HyDE：用答案的向量检索，比问题更接近菜谱文档）
+func (e *QueryExpander) hypotheticalAnswer(ctx context.Context, query string) (string, error) {
+	prompt := fmt.Sprintf(`请针对下面的问题写一段简短的菜谱内容作为回答，包括菜名、主要食材和关键步骤，
+语气和格式与菜谱文档一致，不超过 %d 字。即使不确定也直接写出最可能的做法。
+
+问题：%s
+
+菜谱：`, e.config.MaxHyDERunes, query)
+
+	answer, err := e.provider.Generate(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate hypothetical answer: %w", err)
+	}
+
+	answer = strings.TrimSpace(answer)
+	if runes := []rune(answer); e.config.MaxHyDERunes > 0 && len(runes) > e.config.MaxHyDERunes {
+		answer = string(runes[:e.config.MaxHyDERunes])
+	}
+	return answer, nil
+}
+
+// listMarkerPattern 行首的编号或列表符号（"1."、"2、"、"(3)"、"- " 等）
+var listMarkerPattern = regexp.MustCompile(`^\s*(\d+[.、)）:：]|[(（]\d+[)）]|[-*•])\s*`)
+
+// parseParaphrases 每行一个改写：去掉编号和引号，跳过空行、与原始查询相同的行和重复行，最多 limit 个
+func parseParaphrases(answer, query string, limit int) []string {
+	seen := map[string]bool{strings.TrimSpace(query): true}
+	paraphrases := make([]string, 0, limit)
+	for _, line := range strings.Split(answer, "\n") {
+		text := listMarkerPattern.ReplaceAllString(line, "")
+		text = strings.Trim(strings.TrimSpace(text), `"'“”「」`)
+		if text == "" || seen[text] {
+			continue
+		}
+		seen[text] = true
+		paraphrases = append(paraphrases, text)
+		if len(paraphrases) == limit {
+			break
+		}
+	}
+	return paraphrases
+}
+
+// ExpansionContextKey context key（按请求开关查询扩展）
+type ExpansionContextKey struct{}
+
+// WithExpansion 按请求开启或关闭混合检索的查询扩展（未指定时使用 QueryExpanderConfig.EnabledDefault）
+func WithExpansion(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, ExpansionContextKey{}, enabled)
+}
+
+// ExpansionFromContext 请求是否指定了查询扩展开关
+func ExpansionFromContext(ctx context.Context) (enabled bool, ok bool) {
+	enabled, ok = ctx.Value(ExpansionContextKey{}).(bool)
+	return enabled, ok
+}
@@ -0,0 +1,93 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cookrag-go/internal/models"
+)
+
+// ExplainContextKey context key（开启分数解释）
+type ExplainContextKey struct{}
+
+// WithExplain 开启分数解释：检索器在返回的文档中附带 Explanation
+func WithExplain(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ExplainContextKey{}, true)
+}
+
+// ExplainEnabled 是否开启了分数解释
+func ExplainEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(ExplainContextKey{}).(bool)
+	return enabled
+}
+
+// explainFieldTF 与 fieldTF 相同的计算，同时返回各字段明细（按字段名排序）
+func (s *bm25Scorer) explainFieldTF(freqs FieldFreqs, fieldLengths map[string]int) (float64, []models.FieldExplanation) {
+	fields := make([]string, 0, len(freqs))
+	for field := range freqs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	tf := 0.0
+	explanations := make([]models.FieldExplanation, 0, len(fields))
+	for _, field := range fields {
+		fieldConfig := s.fieldConfig(field)
+		norm := s.lengthNorm(fieldConfig.B, fieldLengths[field], s.avgFieldLengths[field])
+		tf += fieldConfig.Weight * float64(freqs[field]) / norm
+		explanations = append(explanations, models.FieldExplanation{
+			Field:      field,
+			Freq:       freqs[field],
+			Weight:     fieldConfig.Weight,
+			B:          fieldConfig.B,
+			Length:     fieldLengths[field],
+			AvgLength:  s.avgFieldLengths[field],
+			LengthNorm: norm,
+		})
+	}
+	return tf, explanations
+}
+
+// explainClause 单个子句对文档的分数明细（调用方持有读锁）
+func (idx *InvertedIndex) explainClause(scorer *bm25Scorer, clause QueryClause, docID string, freqs FieldFreqs, docFreq int, idf float64) models.TermExplanation {
+	tf, fields := scorer.explainFieldTF(freqs, idx.FieldLengths[docID])
+
+	explanation := models.TermExplanation{
+		Term:    clause.label(),
+		Occur:   "should",
+		Field:   clause.Field,
+		DocFreq: docFreq,
+		IDF:     idf,
+		TF:      tf,
+		Fields:  fields,
+		Score:   scorer.score(idf, tf),
+	}
+	if clause.Occur == OccurMust {
+		explanation.Occur = "must"
+	}
+
+	// 单词子句：记录实际命中的词（词本身或同义词）
+	if !clause.Phrase {
+		for _, term := range clause.termGroup() {
+			if _, ok := idx.Postings[term][docID]; ok {
+				explanation.Matched = append(explanation.Matched, term)
+			}
+		}
+	}
+
+	return explanation
+}
+
+// label 子句的可读形式：词项，或引号包围的短语（带邻近距离）
+func (c QueryClause) label() string {
+	if !c.Phrase {
+		return c.Terms[0]
+	}
+	label := fmt.Sprintf("%q", strings.Join(c.Terms, " "))
+	if c.Slop > 0 {
+		label += fmt.Sprintf("~%d", c.Slop)
+	}
+	return label
+}
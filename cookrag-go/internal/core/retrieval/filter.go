@@ -0,0 +1,138 @@
+package retrieval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cookrag-go/internal/kg"
+	"cookrag-go/internal/models"
+	"cookrag-go/pkg/storage/vectorstore"
+)
+
+// FilterContextKey context key（元数据过滤条件）
+type FilterContextKey struct{}
+
+// Filter 菜谱元数据过滤条件（各项之间为且，列表内为或）
+// 例：{"categories": ["vegetable_dish"], "max_difficulty": "★★"} 只返回两星以内的素菜
+// 向量检索编译为向量存储的过滤条件（Milvus 表达式），BM25 和图检索按同样的条件过滤结果
+type Filter struct {
+	Categories    []string   `json:"categories,omitempty"`     // 分类（任一）
+	Cuisines      []string   `json:"cuisines,omitempty"`       // 菜系（任一）
+	MinDifficulty Difficulty `json:"min_difficulty,omitempty"` // 最低难度星级
+	MaxDifficulty Difficulty `json:"max_difficulty,omitempty"` // 最高难度星级
+	Ingredients   []string   `json:"ingredients,omitempty"`    // 必须包含的原料（全部）
+}
+
+// Difficulty 难度星级，JSON 中可以写数字（2）或星号（"★★"）
+type Difficulty int
+
+// UnmarshalJSON 解析数字或星级字符串
+func (d *Difficulty) UnmarshalJSON(data []byte) error {
+	var n int
+	if err := json.Unmarshal(data, &n); err == nil {
+		*d = Difficulty(n)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("difficulty must be a number or stars: %s", data)
+	}
+	level := kg.ParseDifficulty(s)
+	if level == 0 && strings.TrimSpace(s) != "" {
+		return fmt.Errorf("invalid difficulty: %q", s)
+	}
+	*d = Difficulty(level)
+	return nil
+}
+
+// WithFilter 设置元数据过滤条件：向量、BM25、图检索都只返回满足条件的文档
+func WithFilter(ctx context.Context, filter *Filter) context.Context {
+	if filter.IsEmpty() {
+		return ctx
+	}
+	return context.WithValue(ctx, FilterContextKey{}, filter)
+}
+
+// FilterFromContext 获取元数据过滤条件（未设置时返回 nil）
+func FilterFromContext(ctx context.Context) *Filter {
+	filter, _ := ctx.Value(FilterContextKey{}).(*Filter)
+	return filter
+}
+
+// IsEmpty 是否没有任何条件
+func (f *Filter) IsEmpty() bool {
+	return f == nil || (len(f.Categories) == 0 && len(f.Cuisines) == 0 &&
+		f.MinDifficulty <= 0 && f.MaxDifficulty <= 0 && len(f.Ingredients) == 0)
+}
+
+// Validate 检查难度范围
+func (f *Filter) Validate() error {
+	if f.IsEmpty() {
+		return nil
+	}
+	if f.MinDifficulty < 0 || f.MaxDifficulty < 0 {
+		return fmt.Errorf("difficulty must not be negative")
+	}
+	if f.MinDifficulty > 0 && f.MaxDifficulty > 0 && f.MinDifficulty > f.MaxDifficulty {
+		return fmt.Errorf("min_difficulty %d is greater than max_difficulty %d", f.MinDifficulty, f.MaxDifficulty)
+	}
+	return nil
+}
+
+// Conditions 转换为向量存储的过滤条件
+func (f *Filter) Conditions() vectorstore.Filter {
+	if f.IsEmpty() {
+		return nil
+	}
+
+	conditions := make(vectorstore.Filter, 0)
+	if len(f.Categories) > 0 {
+		conditions = append(conditions, vectorstore.In(kg.MetadataCategory, f.Categories...))
+	}
+	if len(f.Cuisines) > 0 {
+		conditions = append(conditions, vectorstore.In(kg.MetadataCuisine, f.Cuisines...))
+	}
+	if f.MinDifficulty > 0 {
+		conditions = append(conditions, vectorstore.Gte(kg.MetadataDifficulty, float64(f.MinDifficulty)))
+	}
+	if f.MaxDifficulty > 0 {
+		conditions = append(conditions, vectorstore.Lte(kg.MetadataDifficulty, float64(f.MaxDifficulty)))
+	}
+	for _, ingredient := range f.Ingredients {
+		conditions = append(conditions, vectorstore.Contains(kg.MetadataIngredients, ingredient))
+	}
+	return conditions
+}
+
+// Match 文档元数据是否满足过滤条件
+func (f *Filter) Match(metadata map[string]interface{}) bool {
+	return f.Conditions().Match(metadata)
+}
+
+// String 过滤条件摘要（用于日志、链路追踪和缓存key）
+func (f *Filter) String() string {
+	if f.IsEmpty() {
+		return ""
+	}
+	data, _ := json.Marshal(f)
+	return string(data)
+}
+
+// filterDocuments 保留满足过滤条件的文档（filter 为空时原样返回）
+func filterDocuments(documents []models.Document, filter *Filter) []models.Document {
+	if filter.IsEmpty() {
+		return documents
+	}
+
+	conditions := filter.Conditions()
+	filtered := make([]models.Document, 0, len(documents))
+	for _, doc := range documents {
+		if conditions.Match(doc.Metadata) {
+			filtered = append(filtered, doc)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,331 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"cookrag-go/internal/models"
+)
+
+// 融合方法
+const (
+	FusionRRF           = "rrf"            // 加权倒数排名融合：只看排名，不看分数
+	FusionCombSUMMinMax = "combsum_minmax" // 各来源分数最小-最大归一化后加权求和
+	FusionCombSUMZScore = "combsum_zscore" // 各来源分数 z-score 标准化后加权求和
+	FusionCombMNZMinMax = "combmnz_minmax" // CombSUM（最小-最大）× 命中来源数
+	FusionCombMNZZScore = "combmnz_zscore" // CombSUM（z-score，整体平移为非负）× 命中来源数
+	FusionDBSF          = "dbsf"           // 基于分布的分数融合：按 均值±3σ 截断归一化到 [0,1] 后加权求和
+)
+
+// FusionMethods 支持的融合方法
+var FusionMethods = []string{
+	FusionRRF,
+	FusionCombSUMMinMax,
+	FusionCombSUMZScore,
+	FusionCombMNZMinMax,
+	FusionCombMNZZScore,
+	FusionDBSF,
+}
+
+// defaultRRFK RRF 常数
+const defaultRRFK = 60
+
+// FusionSource 参与融合的一路检索结果（Documents 按分数从高到低）
+type FusionSource struct {
+	Name      string  // 来源名称：vector / bm25 / graph
+	Weight    float64 // 来源权重
+	Documents []models.Document
+}
+
+// Fusion 融合策略：把多路检索结果合并为一个按融合分数排序的列表
+// 文档按身份去重（分块按所属原文档，见 ParentID）：同一文档在多路中出现时合并高亮，
+// 并保留第一个带 BM25 明细的 Explanation；同一路中重复出现时只取排名最高的一次
+type Fusion interface {
+	// Name 融合方法名（见 FusionMethods）
+	Name() string
+	// Fuse 融合多路结果，explain 为 true 时在文档中附带各来源的排名和贡献
+	Fuse(sources []FusionSource, explain bool) []models.Document
+}
+
+// NewFusion 按名称创建融合策略（rrfK 只用于 RRF，<=0 时取 60）
+func NewFusion(method string, rrfK int) (Fusion, error) {
+	if rrfK <= 0 {
+		rrfK = defaultRRFK
+	}
+
+	switch method {
+	case FusionRRF, "":
+		return &rrfFusion{k: rrfK}, nil
+	case FusionCombSUMMinMax:
+		return &scoreFusion{name: method, normalize: minMaxNormalize}, nil
+	case FusionCombSUMZScore:
+		return &scoreFusion{name: method, normalize: zScoreNormalize}, nil
+	case FusionCombMNZMinMax:
+		return &scoreFusion{name: method, normalize: minMaxNormalize, mnz: true}, nil
+	case FusionCombMNZZScore:
+		return &scoreFusion{name: method, normalize: zScoreNormalize, mnz: true}, nil
+	case FusionDBSF:
+		return &scoreFusion{name: method, normalize: distributionNormalize}, nil
+	default:
+		return nil, fmt.Errorf("unsupported fusion method: %q (supported: %v)", method, FusionMethods)
+	}
+}
+
+// ValidateFusion 检查融合方法名（空字符串表示使用默认配置）
+func ValidateFusion(method string) error {
+	if method == "" {
+		return nil
+	}
+	_, err := NewFusion(method, 0)
+	return err
+}
+
+// FusionContextKey context key（按请求指定融合方法）
+type FusionContextKey struct{}
+
+// WithFusion 按请求指定混合检索的融合方法（空字符串表示使用配置中的方法）
+func WithFusion(ctx context.Context, method string) context.Context {
+	if method == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, FusionContextKey{}, method)
+}
+
+// FusionFromContext 请求指定的融合方法（未指定时返回空字符串）
+func FusionFromContext(ctx context.Context) string {
+	method, _ := ctx.Value(FusionContextKey{}).(string)
+	return method
+}
+
+// rrfFusion 加权 RRF
+// RRF公式：score = weight × K / (K + rank)，rank 从1开始，排名越靠后分数越低
+// 例：K=60, rank=1 → 60/61 ≈ 0.98（最高分）；K=60, rank=10 → 60/70 ≈ 0.86
+type rrfFusion struct {
+	k int
+}
+
+func (f *rrfFusion) Name() string {
+	return FusionRRF
+}
+
+func (f *rrfFusion) Fuse(sources []FusionSource, explain bool) []models.Document {
+	entries := accumulate(sources, func(_ int, source FusionSource) []float64 {
+		contributions := make([]float64, len(source.Documents))
+		for rank := range source.Documents {
+			contributions[rank] = source.Weight * float64(f.k) / float64(f.k+rank+1)
+		}
+		return contributions
+	})
+	return rankEntries(entries, FusionRRF, f.k, explain)
+}
+
+// scoreFusion 基于分数的融合：各来源分数分别归一化后加权求和（CombSUM / DBSF），
+// CombMNZ 再乘以命中来源数，偏向同时被多路召回的文档（z-score 有负值，相乘前整体平移为非负，
+// 否则低于均值的文档命中的来源越多反而越靠后）
+// 向量分数（相似度）和 BM25 分数量纲不同，RRF 只看排名会低估关键词精确命中的高分文档
+type scoreFusion struct {
+	name      string
+	normalize func(scores []float64) []float64
+	mnz       bool
+}
+
+func (f *scoreFusion) Name() string {
+	return f.name
+}
+
+func (f *scoreFusion) Fuse(sources []FusionSource, explain bool) []models.Document {
+	normalized := make([][]float64, len(sources))
+	for i, source := range sources {
+		scores := make([]float64, len(source.Documents))
+		for j, doc := range source.Documents {
+			scores[j] = float64(doc.Score)
+		}
+		normalized[i] = f.normalize(scores)
+	}
+	if f.mnz {
+		shiftNonNegative(normalized)
+	}
+
+	entries := accumulate(sources, func(i int, source FusionSource) []float64 {
+		contributions := make([]float64, len(normalized[i]))
+		for j, score := range normalized[i] {
+			contributions[j] = score * source.Weight
+		}
+		return contributions
+	})
+	if f.mnz {
+		for _, entry := range entries {
+			entry.score *= float64(len(entry.sources))
+		}
+	}
+	return rankEntries(entries, f.name, 0, explain)
+}
+
+// fusedEntry 融合中的文档
+type fusedEntry struct {
+	doc     models.Document
+	score   float64
+	sources []models.SourceRanking // 各来源中的排名和贡献
+}
+
+// accumulate 累加每个文档在各来源中的贡献（contribution 返回与 sources[i].Documents 一一对应的贡献）
+func accumulate(sources []FusionSource, contribution func(i int, source FusionSource) []float64) []*fusedEntry {
+	entries := make([]*fusedEntry, 0)
+	positions := make(map[string]int)
+	for i, source := range sources {
+		contributions := contribution(i, source)
+		seen := make(map[string]bool, len(source.Documents))
+		for rank, doc := range source.Documents {
+			identity := ParentID(doc)
+			if seen[identity] {
+				continue
+			}
+			seen[identity] = true
+
+			pos, exists := positions[identity]
+			if !exists {
+				entries = append(entries, &fusedEntry{doc: doc})
+				pos = len(entries) - 1
+				positions[identity] = pos
+			} else {
+				// 同时出现在多个来源中：合并高亮，保留 BM25 的得分明细
+				mergeHighlights(&entries[pos].doc, doc)
+				if entries[pos].doc.Explanation == nil {
+					entries[pos].doc.Explanation = doc.Explanation
+				}
+			}
+
+			entry := entries[pos]
+			entry.score += contributions[rank]
+			entry.sources = append(entry.sources, models.SourceRanking{
+				Source:       source.Name,
+				Rank:         rank + 1,
+				Weight:       source.Weight,
+				Score:        doc.Score,
+				Contribution: contributions[rank],
+			})
+		}
+	}
+	return entries
+}
+
+// rankEntries 按融合分数排序并写回文档分数（分数相同时保持先出现的在前）
+func rankEntries(entries []*fusedEntry, method string, k int, explain bool) []models.Document {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].score > entries[j].score
+	})
+
+	fused := make([]models.Document, 0, len(entries))
+	for _, entry := range entries {
+		doc := entry.doc
+		doc.Score = float32(entry.score)
+		if explain {
+			explanation := &models.Explanation{}
+			if doc.Explanation != nil {
+				*explanation = *doc.Explanation // 复制，避免修改来源检索器返回的文档
+			}
+			explanation.Fusion = &models.FusionExplanation{
+				Method:  method,
+				K:       k,
+				Score:   entry.score,
+				Sources: entry.sources,
+			}
+			doc.Explanation = explanation
+		}
+		fused = append(fused, doc)
+	}
+	return fused
+}
+
+// minMaxNormalize 最小-最大归一化到 [0,1]（全部相同时均为1）
+func minMaxNormalize(scores []float64) []float64 {
+	normalized := make([]float64, len(scores))
+	if len(scores) == 0 {
+		return normalized
+	}
+
+	minScore, maxScore := scores[0], scores[0]
+	for _, score := range scores[1:] {
+		minScore = math.Min(minScore, score)
+		maxScore = math.Max(maxScore, score)
+	}
+	for i, score := range scores {
+		if maxScore == minScore {
+			normalized[i] = 1
+			continue
+		}
+		normalized[i] = (score - minScore) / (maxScore - minScore)
+	}
+	return normalized
+}
+
+// zScoreNormalize z-score 标准化（标准差为0时均为0）
+func zScoreNormalize(scores []float64) []float64 {
+	normalized := make([]float64, len(scores))
+	mean, std := meanStd(scores)
+	if std == 0 {
+		return normalized
+	}
+	for i, score := range scores {
+		normalized[i] = (score - mean) / std
+	}
+	return normalized
+}
+
+// distributionNormalize DBSF 归一化：以 均值-3σ 和 均值+3σ 为上下界线性映射到 [0,1]，超出部分截断
+// 与最小-最大归一化相比，不会因为某一路只有个别结果而把差距很小的分数拉开到 0 和 1
+func distributionNormalize(scores []float64) []float64 {
+	normalized := make([]float64, len(scores))
+	mean, std := meanStd(scores)
+	if std == 0 {
+		for i := range normalized {
+			normalized[i] = 1
+		}
+		return normalized
+	}
+
+	lower, upper := mean-3*std, mean+3*std
+	for i, score := range scores {
+		normalized[i] = math.Max(0, math.Min(1, (score-lower)/(upper-lower)))
+	}
+	return normalized
+}
+
+// shiftNonNegative 所有来源的归一化分数减去全局最小值（最小值非负时不变），保持各来源之间的相对差距
+func shiftNonNegative(normalized [][]float64) {
+	minScore := 0.0
+	for _, scores := range normalized {
+		for _, score := range scores {
+			minScore = math.Min(minScore, score)
+		}
+	}
+	if minScore == 0 {
+		return
+	}
+	for _, scores := range normalized {
+		for i := range scores {
+			scores[i] -= minScore
+		}
+	}
+}
+
+// meanStd 均值和总体标准差
+func meanStd(scores []float64) (float64, float64) {
+	if len(scores) == 0 {
+		return 0, 0
+	}
+
+	mean := 0.0
+	for _, score := range scores {
+		mean += score
+	}
+	mean /= float64(len(scores))
+
+	variance := 0.0
+	for _, score := range scores {
+		variance += (score - mean) * (score - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(scores)))
+}
@@ -2,16 +2,21 @@ package retrieval
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"cookrag-go/internal/models"
 	"cookrag-go/internal/observability"
+	"cookrag-go/pkg/storage/docstore"
 	"cookrag-go/pkg/storage/neo4j"
 
 	"github.com/charmbracelet/log"
 )
 
+// ErrNoGraphStore 未连接 Neo4j
+var ErrNoGraphStore = errors.New("graph store is not configured")
+
 // GraphRetrieverConfig 图RAG检索配置
 type GraphRetrieverConfig struct {
 	MaxDepth     int  // 最大跳数
@@ -34,12 +39,14 @@ func DefaultGraphRetrieverConfig() *GraphRetrieverConfig {
 type GraphRetriever struct {
 	config      *GraphRetrieverConfig
 	neo4jClient *neo4j.Client
+	docStore    docstore.Store // 文档存储（可为nil），菜品节点据此回查完整菜谱
 }
 
 // NewGraphRetriever 创建图RAG检索器
 func NewGraphRetriever(
 	config *GraphRetrieverConfig,
 	neo4jClient *neo4j.Client,
+	docStore docstore.Store,
 ) *GraphRetriever {
 	if config == nil {
 		config = DefaultGraphRetrieverConfig()
@@ -48,20 +55,29 @@ func NewGraphRetriever(
 	return &GraphRetriever{
 		config:      config,
 		neo4jClient: neo4jClient,
+		docStore:    docStore,
 	}
 }
 
-// Retrieve 图RAG检索
+// Retrieve 图RAG检索（ctx 中有过滤条件时只保留满足条件的节点文档，见 WithFilter）
 func (r *GraphRetriever) Retrieve(ctx context.Context, query string) (*models.RetrievalResult, error) {
+	filter := FilterFromContext(ctx)
+
 	// 创建链路追踪 span
 	span := observability.GlobalTracer.StartSpan(ctx, "graph_retrieve", map[string]interface{}{
 		"query": query,
 		"max_depth": r.config.MaxDepth,
+		"filter": filter.String(),
 	})
 	defer span.End()
 
 	startTime := time.Now()
 
+	if r.neo4jClient == nil {
+		span.SetError(ErrNoGraphStore)
+		return nil, ErrNoGraphStore
+	}
+
 	log.Infof("🕸️  Graph RAG retrieval: query='%s', max_depth=%d", query, r.config.MaxDepth)
 
 	// 1. 提取查询中的实体,例如菜品、食材具体名称
@@ -109,7 +125,11 @@ func (r *GraphRetriever) Retrieve(ctx context.Context, query string) (*models.Re
 	}
 
 	// 4. 构建文档结果
-	documents := r.buildDocumentsFromSubgraph(subgraph, communities)
+	documents := r.buildDocumentsFromSubgraph(ctx, subgraph, communities)
+	highlightEntities(documents, entities)
+
+	// 元数据过滤（菜品节点已回查原始文档元数据；食材、关系等节点没有菜谱元数据，有过滤条件时不会保留）
+	documents = filterDocuments(documents, filter)
 
 	// 5. 截取top-k
 	if len(documents) > r.config.TopK {
@@ -134,13 +154,20 @@ func (r *GraphRetriever) Retrieve(ctx context.Context, query string) (*models.Re
 // buildDocumentsFromSubgraph 从子图构建文档
 // Document 是统一的检索结果格式，用于：1)传给LLM作为上下文 2)返回给HTTP客户端 3)支持RRF融合排序
 func (r *GraphRetriever) buildDocumentsFromSubgraph(
+	ctx context.Context,
 	subgraph *neo4j.Subgraph,
 	communities map[string][]*neo4j.GraphNode,
 ) []models.Document {
 	documents := make([]models.Document, 0)
+	seenNodes := make(map[string]bool) // 多跳查询每行都会带上起始节点，需要按节点去重
 
 	// 为每个节点创建文档（节点 → Document）
 	for _, node := range subgraph.Nodes {
+		if seenNodes[node.NodeID] {
+			continue
+		}
+		seenNodes[node.NodeID] = true
+
 		doc := models.Document{
 			ID:    node.NodeID,
 			Score: 1.0, // 默认分数
@@ -159,6 +186,9 @@ func (r *GraphRetriever) buildDocumentsFromSubgraph(
 			doc.Metadata[key] = value
 		}
 
+		// 菜品节点：用文档ID回查完整菜谱，保证与 BM25 / 向量检索返回相同的文档
+		r.hydrateFromDocStore(ctx, &doc)
+
 		// 添加社区信息（节点所属的分组）
 		if communities != nil {
 			for communityLabel, communityNodes := range communities {
@@ -211,6 +241,39 @@ func (r *GraphRetriever) buildDocumentsFromSubgraph(
 	return documents
 }
 
+// hydrateFromDocStore 用文档存储中的原始文档填充图节点文档（ID、内容、元数据）
+func (r *GraphRetriever) hydrateFromDocStore(ctx context.Context, doc *models.Document) {
+	docID, ok := doc.Metadata[docIDMetadataKey].(string)
+	if !ok || docID == "" {
+		return
+	}
+
+	doc.ID = docID
+	if r.docStore == nil {
+		return
+	}
+
+	stored, found := r.docStore.Get(ctx, docID)
+	if !found {
+		return
+	}
+
+	doc.Content = stored.Content
+	// 以原始元数据为准，保留图节点信息（node_id/labels/type等）
+	for key, value := range stored.Metadata {
+		doc.Metadata[key] = value
+	}
+}
+
+// highlightEntities 标出文档中出现的查询实体名，并截取命中最多的句子作为摘要
+func highlightEntities(documents []models.Document, entities []string) {
+	for i := range documents {
+		doc := &documents[i]
+		doc.Highlights = highlightPhrases(doc.Content, entities, HighlightEntity)
+		doc.Snippet = snippetFor(doc.Content, doc.Highlights, defaultSnippetRunes)
+	}
+}
+
 // calculateNodeDegrees 计算节点度数
 func (r *GraphRetriever) calculateNodeDegrees(subgraph *neo4j.Subgraph) map[string]int {
 	degrees := make(map[string]int)
@@ -0,0 +1,256 @@
+package retrieval
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"cookrag-go/internal/models"
+)
+
+// 命中来源
+const (
+	HighlightTerm     = "term"     // BM25 命中的词项（含同义词、子词）
+	HighlightSentence = "sentence" // 向量检索中与查询最相似的句子
+	HighlightEntity   = "entity"   // 图检索匹配到的实体名
+)
+
+// defaultSnippetRunes 摘要的默认最大字数
+const defaultSnippetRunes = 120
+
+// textSpan 文本中的一段（字符偏移，End 不含）
+type textSpan struct {
+	Start int
+	End   int
+}
+
+// highlightTokens 按分词结果标出命中的词项
+// 与索引使用同一个分词器，命中位置即倒排表中的词项位置；子词（"简易红烧肉"中的"红烧肉"）标出子词本身
+func highlightTokens(tokenizer *Tokenizer, content string, terms map[string]bool) []models.Highlight {
+	highlights := make([]models.Highlight, 0)
+	if tokenizer == nil || len(terms) == 0 {
+		return highlights
+	}
+
+	for _, info := range tokenizer.Segment(content) {
+		if !info.Kept {
+			continue
+		}
+		if terms[info.Word] {
+			highlights = append(highlights, models.Highlight{
+				Start:  info.Start,
+				End:    info.End,
+				Text:   info.Word,
+				Source: HighlightTerm,
+			})
+			continue
+		}
+		for _, sub := range info.SubWords {
+			if !terms[sub] {
+				continue
+			}
+			if offset := strings.Index(info.Word, sub); offset >= 0 {
+				start := info.Start + utf8.RuneCountInString(info.Word[:offset])
+				highlights = append(highlights, models.Highlight{
+					Start:  start,
+					End:    start + utf8.RuneCountInString(sub),
+					Text:   sub,
+					Source: HighlightTerm,
+				})
+			}
+		}
+	}
+
+	return highlights
+}
+
+// highlightPhrases 标出文本中所有出现的短语（用于实体名等不依赖分词的匹配）
+func highlightPhrases(content string, phrases []string, source string) []models.Highlight {
+	highlights := make([]models.Highlight, 0)
+	for _, phrase := range phrases {
+		if phrase == "" {
+			continue
+		}
+		phraseRunes := utf8.RuneCountInString(phrase)
+		for offset := 0; offset < len(content); {
+			idx := strings.Index(content[offset:], phrase)
+			if idx < 0 {
+				break
+			}
+			start := utf8.RuneCountInString(content[:offset+idx])
+			highlights = append(highlights, models.Highlight{
+				Start:  start,
+				End:    start + phraseRunes,
+				Text:   phrase,
+				Source: source,
+			})
+			offset += idx + len(phrase)
+		}
+	}
+
+	return normalizeHighlights(highlights)
+}
+
+// normalizeHighlights 按位置排序并去掉重叠的片段（保留先出现、较长的片段）
+func normalizeHighlights(highlights []models.Highlight) []models.Highlight {
+	sort.SliceStable(highlights, func(i, j int) bool {
+		if highlights[i].Start != highlights[j].Start {
+			return highlights[i].Start < highlights[j].Start
+		}
+		return highlights[i].End > highlights[j].End
+	})
+
+	result := make([]models.Highlight, 0, len(highlights))
+	for _, highlight := range highlights {
+		if n := len(result); n > 0 && highlight.Start < result[n-1].End {
+			continue
+		}
+		result = append(result, highlight)
+	}
+	return result
+}
+
+// mergeHighlights 合并同一文档在不同检索器中的命中位置（融合排序时使用）
+// 不同来源的片段可以重叠（句子里包含命中的词项），只去掉完全相同的片段
+// 内容不同（例如图节点文档未回查到原文）时位置不可比，保留 dst 原样
+func mergeHighlights(dst *models.Document, src models.Document) {
+	if dst.Content != src.Content || len(src.Highlights) == 0 {
+		return
+	}
+
+	seen := make(map[models.Highlight]bool, len(dst.Highlights))
+	merged := make([]models.Highlight, 0, len(dst.Highlights)+len(src.Highlights))
+	for _, highlight := range append(append([]models.Highlight(nil), dst.Highlights...), src.Highlights...) {
+		if !seen[highlight] {
+			seen[highlight] = true
+			merged = append(merged, highlight)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Start < merged[j].Start
+	})
+
+	dst.Highlights = merged
+	if dst.Snippet == "" {
+		dst.Snippet = src.Snippet
+	}
+}
+
+// splitSentences 按句末标点和换行切分句子，返回每句的字符区间（去掉首尾空白，跳过空句）
+func splitSentences(content string) []textSpan {
+	runes := []rune(content)
+	spans := make([]textSpan, 0)
+
+	start := 0
+	flush := func(end int) {
+		s, e := start, end
+		for s < e && isSpaceRune(runes[s]) {
+			s++
+		}
+		for e > s && isSpaceRune(runes[e-1]) {
+			e--
+		}
+		if e > s {
+			spans = append(spans, textSpan{Start: s, End: e})
+		}
+		start = end
+	}
+
+	// 半角 ! 不作为句末（Markdown 图片语法 ![...]）
+	for i, r := range runes {
+		switch r {
+		case '。', '！', '？', '；', '?', ';':
+			flush(i + 1)
+		case '\n':
+			flush(i)
+		}
+	}
+	flush(len(runes))
+
+	return spans
+}
+
+// snippetFor 选取命中最多的句子作为摘要（先比命中的不同词数，再比命中次数），超长时以首个命中为中心截取
+// Markdown 图片行（![红烧肉](./红烧肉.jpg)）不作为摘要
+func snippetFor(content string, highlights []models.Highlight, maxRunes int) string {
+	if len(highlights) == 0 {
+		return ""
+	}
+	if maxRunes <= 0 {
+		maxRunes = defaultSnippetRunes
+	}
+
+	runes := []rune(content)
+	best, bestDistinct, bestHits := textSpan{Start: highlights[0].Start, End: highlights[0].End}, 0, 0
+	for _, sentence := range splitSentences(content) {
+		if strings.HasPrefix(string(runes[sentence.Start:sentence.End]), "![") {
+			continue
+		}
+
+		hits := 0
+		distinct := make(map[string]bool)
+		for _, highlight := range highlights {
+			if highlight.Start >= sentence.Start && highlight.End <= sentence.End {
+				hits++
+				distinct[highlight.Text] = true
+			}
+		}
+		if len(distinct) > bestDistinct || (len(distinct) == bestDistinct && hits > bestHits) {
+			best, bestDistinct, bestHits = sentence, len(distinct), hits
+		}
+	}
+
+	return clipSpan(runes, best, highlights, maxRunes)
+}
+
+// clipSpan 截取区间文本，超过 maxRunes 时围绕区间内第一个命中截取并加省略号
+func clipSpan(runes []rune, span textSpan, highlights []models.Highlight, maxRunes int) string {
+	if span.End-span.Start <= maxRunes {
+		return string(runes[span.Start:span.End])
+	}
+
+	center := span.Start
+	for _, highlight := range highlights {
+		if highlight.Start >= span.Start && highlight.End <= span.End {
+			center = highlight.Start
+			break
+		}
+	}
+
+	start := max(span.Start, center-maxRunes/3)
+	end := min(span.End, start+maxRunes)
+	start = max(span.Start, end-maxRunes)
+
+	snippet := string(runes[start:end])
+	if start > span.Start {
+		snippet = "…" + snippet
+	}
+	if end < span.End {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// cosineSimilarity 余弦相似度（任一向量为零向量时返回0）
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// isSpaceRune 是否空白字符（包括全角空格）
+func isSpaceRune(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\r' || r == '\n' || r == '　'
+}
@@ -2,8 +2,10 @@ package retrieval
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -13,32 +15,47 @@ import (
 
 // HybridRetrieverConfig 混合检索配置
 type HybridRetrieverConfig struct {
-	VectorWeight  float64 // 向量检索权重 (0-1)
-	BM25Weight    float64 // BM25检索权重 (0-1)
-	TopK          int     // 返回结果数量
-	RRFK          int     // RRF常数 (通常60)
-	RRF           int     // RRF常数 (别名,与RRFK相同)
+	VectorWeight  float64       // 向量检索权重 (0-1)
+	BM25Weight    float64       // BM25检索权重 (0-1)
+	TopK          int           // 返回结果数量
+	RRFK          int           // RRF常数 (通常60)
+	RRF           int           // RRF常数 (别名,与RRFK相同)
+	Fusion        string        // 融合方法（见 FusionMethods，默认 rrf，可按请求覆盖，见 WithFusion）
+	SourceTimeout time.Duration // 每路子检索的超时时间（超时的一路不参与融合，0 表示不限制）
 }
 
 // DefaultHybridRetrieverConfig 默认配置
 func DefaultHybridRetrieverConfig() *HybridRetrieverConfig {
 	return &HybridRetrieverConfig{
-		VectorWeight: 0.7,
-		BM25Weight:   0.3,
-		TopK:         10,
-		RRFK:         60,
-		RRF:          60,
+		VectorWeight:  0.7,
+		BM25Weight:    0.3,
+		TopK:          10,
+		RRFK:          60,
+		RRF:           60,
+		Fusion:        FusionRRF,
+		SourceTimeout: 5 * time.Second,
 	}
 }
 
-// HybridRetriever 混合检索器
+// HybridSource 混合检索的一路子检索器
+type HybridSource struct {
+	Name      string // 来源名称（vector / bm25 / graph），用于融合明细和自适应权重
+	Retriever Retriever
+	Weight    float64       // 融合权重
+	Timeout   time.Duration // 超时时间（0 表示使用 HybridRetrieverConfig.SourceTimeout）
+}
+
+// HybridRetriever 混合检索器：并行调用任意多路加权子检索器，融合为一个结果列表
+// 同一文档（分块按所属原文档）在多路中出现时合并为一条
 type HybridRetriever struct {
-	config         *HybridRetrieverConfig
-	vectorRetriever *VectorRetriever
-	bm25Retriever   *BM25Retriever
+	config        *HybridRetrieverConfig
+	bm25Retriever *BM25Retriever // 查询扩展使用其分词器（可为nil）
+	sources       []HybridSource
+	diversifier   *MMRReranker   // MMR 多样化（可为nil，见 SetDiversifier）
+	expander      *QueryExpander // LLM 查询扩展（可为nil，见 SetExpander）
 }
 
-// NewHybridRetriever 创建混合检索器
+// NewHybridRetriever 创建向量 + BM25 混合检索器（权重取 VectorWeight / BM25Weight，其他来源见 AddSource）
 func NewHybridRetriever(
 	config *HybridRetrieverConfig,
 	vectorRetriever *VectorRetriever,
@@ -48,181 +65,430 @@ func NewHybridRetriever(
 		config = DefaultHybridRetrieverConfig()
 	}
 
+	r := NewHybridRetrieverWithSources(config)
+	r.bm25Retriever = bm25Retriever
+	if vectorRetriever != nil {
+		r.AddSource(HybridSource{Name: "vector", Retriever: vectorRetriever, Weight: config.VectorWeight})
+	}
+	if bm25Retriever != nil {
+		r.AddSource(HybridSource{Name: "bm25", Retriever: bm25Retriever.AsRetriever(config.TopK * 2), Weight: config.BM25Weight})
+	}
+	return r
+}
+
+// NewHybridRetrieverWithSources 用任意多路子检索器创建混合检索器
+func NewHybridRetrieverWithSources(config *HybridRetrieverConfig, sources ...HybridSource) *HybridRetriever {
+	if config == nil {
+		config = DefaultHybridRetrieverConfig()
+	}
+
 	return &HybridRetriever{
-		config:          config,
-		vectorRetriever: vectorRetriever,
-		bm25Retriever:   bm25Retriever,
+		config:  config,
+		sources: sources,
 	}
 }
 
+// AddSource 增加一路子检索器（如图检索），需在开始检索之前调用
+func (r *HybridRetriever) AddSource(source HybridSource) {
+	r.sources = append(r.sources, source)
+}
+
+// SetDiversifier 设置 MMR 多样化：从融合后的全部候选中按 MMR 选出 TopK，代替按分数截断
+func (r *HybridRetriever) SetDiversifier(diversifier *MMRReranker) {
+	r.diversifier = diversifier
+}
+
+// SetExpander 设置 LLM 查询扩展：对原始查询、改写和假设答案（HyDE）分别混合检索后再融合
+// 是否扩展由请求决定（见 WithExpansion），未指定时使用 QueryExpanderConfig.EnabledDefault
+func (r *HybridRetriever) SetExpander(expander *QueryExpander) {
+	r.expander = expander
+}
+
 // Retrieve 混合检索
 func (r *HybridRetriever) Retrieve(ctx context.Context, query string) (*models.RetrievalResult, error) {
+	return r.retrieve(ctx, query, nil)
+}
+
+// retrieve 混合检索，请求开启查询扩展时对每个查询变体分别检索后融合
+func (r *HybridRetriever) retrieve(ctx context.Context, query string, weights map[string]float64) (*models.RetrievalResult, error) {
+	if r.expansionEnabled(ctx) {
+		return r.retrieveExpanded(ctx, query, weights)
+	}
+
+	result, err := r.retrieveQuery(ctx, query, weights, r.sources)
+	if err != nil {
+		return nil, err
+	}
+	result.Documents = r.selectTopK(ctx, result.Documents)
+	return result, nil
+}
+
+// retrieveQuery 并行执行 sources 中的各路子检索并融合，返回全部融合结果（weights 按来源名称覆盖融合权重，可为nil）
+// 失败或超时的一路不参与融合（优雅降级），全部失败时返回错误
+func (r *HybridRetriever) retrieveQuery(ctx context.Context, query string, weights map[string]float64, sources []HybridSource) (*models.RetrievalResult, error) {
 	// 创建链路追踪 span
 	span := observability.GlobalTracer.StartSpan(ctx, "hybrid_retrieve", map[string]interface{}{
-		"query":         query,
-		"vector_weight": r.config.VectorWeight,
-		"bm25_weight":   r.config.BM25Weight,
-		"top_k":         r.config.TopK,
-		"rrf_k":         r.config.RRF,
+		"query":   query,
+		"sources": sourceNames(sources),
+		"top_k":   r.config.TopK,
+		"rrf_k":   r.config.RRF,
 	})
 	defer span.End()
 
 	startTime := time.Now()
 
-	log.Infof("🔀 Hybrid retrieval: query='%s', vector_weight=%.2f, bm25_weight=%.2f",
-		query, r.config.VectorWeight, r.config.BM25Weight)
+	fusion, err := r.fusion(ctx)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	span.AddMetadata("fusion", fusion.Name())
+
+	log.Infof("🔀 Hybrid retrieval: query='%s', sources=%s, fusion=%s",
+		query, strings.Join(sourceNames(sources), "/"), fusion.Name())
+
+	// 并行执行各路检索（每路单独超时）
+	outcomes := r.runSources(ctx, query, sources)
+
+	fusionSources := make([]FusionSource, 0, len(sources))
+	var failed []string
+	var errs []error
+	for i, source := range sources {
+		outcome := outcomes[i]
+		if outcome.err != nil {
+			// 优雅降级：失败的一路不参与融合
+			log.Warnf("⚠️  %s retrieval failed, fusing remaining sources: %v", source.Name, outcome.err)
+			failed = append(failed, source.Name)
+			errs = append(errs, fmt.Errorf("%s: %w", source.Name, outcome.err))
+			continue
+		}
 
-	// 并行执行向量检索和BM25检索
-	type retrievalResult struct {
-		Result *models.RetrievalResult
-		Error  error
+		weight := source.Weight
+		if override, ok := weights[source.Name]; ok {
+			weight = override
+		}
+		span.AddMetadata(source.Name+"_result_count", len(outcome.documents))
+		fusionSources = append(fusionSources, FusionSource{
+			Name:      source.Name,
+			Weight:    weight,
+			Documents: outcome.documents,
+		})
 	}
 
-	vectorResultCh := make(chan retrievalResult, 1)
-	bm25ResultCh := make(chan retrievalResult, 1)
+	if len(fusionSources) == 0 {
+		err := fmt.Errorf("all hybrid sources failed: %w", errors.Join(errs...))
+		span.SetError(err)
+		return nil, err
+	}
+	if len(failed) > 0 {
+		span.AddMetadata("failed_sources", failed)
+	}
 
-	// 向量检索
-	go func() {
-		result, err := r.vectorRetriever.Retrieve(ctx, query)
-		vectorResultCh <- retrievalResult{Result: result, Error: err}
-	}()
+	fusedDocuments := fusion.Fuse(fusionSources, ExplainEnabled(ctx))
 
-	// BM25检索
-	go func() {
-		docs, err := r.bm25Retriever.Retrieve(ctx, query, r.config.TopK * 2)
-		result := &models.RetrievalResult{
-			Documents: docs,
-			Strategy:  "bm25",
+	result := &models.RetrievalResult{
+		Documents: fusedDocuments,
+		Strategy:  "hybrid",
+		Query:     query,
+		Latency:   float64(time.Since(startTime).Milliseconds()),
+	}
+
+	span.AddMetadata("fused_count", len(fusedDocuments))
+	span.AddMetadata("latency_ms", result.Latency)
+
+	log.Infof("✅ Hybrid retrieval completed: %d fused results from %d/%d sources in %.2fms",
+		len(fusedDocuments), len(fusionSources), len(sources), result.Latency)
+
+	return result, nil
+}
+
+// selectTopK 截取top-k（开启 MMR 时按 MMR 选择）
+func (r *HybridRetriever) selectTopK(ctx context.Context, documents []models.Document) []models.Document {
+	if r.diversifier != nil {
+		return r.diversifier.Rerank(ctx, documents, r.config.TopK)
+	}
+	if len(documents) > r.config.TopK {
+		return documents[:r.config.TopK]
+	}
+	return documents
+}
+
+// expansionEnabled 本次检索是否做查询扩展（请求指定的开关优先于配置）
+// 没有配置 LLM 扩展器时不扩展，请求要求扩展时记录警告
+func (r *HybridRetriever) expansionEnabled(ctx context.Context) bool {
+	enabled, requested := ExpansionFromContext(ctx)
+	if r.expander == nil {
+		if requested && enabled {
+			log.Warnf("⚠️  Query expansion requested but no LLM expander is configured, retrieving without expansion")
 		}
-		bm25ResultCh <- retrievalResult{Result: result, Error: err}
-	}()
+		return false
+	}
+	if requested {
+		return enabled
+	}
+	return r.expander.config.EnabledDefault
+}
+
+// retrieveExpanded 查询扩展检索（RAG-Fusion）：各查询变体并行做混合检索，再按加权 RRF 融合各变体的结果
+// 假设答案（HyDE）只发给向量检索；失败的变体不参与融合，全部失败时返回错误
+func (r *HybridRetriever) retrieveExpanded(ctx context.Context, query string, weights map[string]float64) (*models.RetrievalResult, error) {
+	span := observability.GlobalTracer.StartSpan(ctx, "hybrid_expanded_retrieve", map[string]interface{}{
+		"query": query,
+		"top_k": r.config.TopK,
+	})
+	defer span.End()
 
-	// 等待两个检索完成
-	vectorRes := <-vectorResultCh
-	bm25Res := <-bm25ResultCh
+	startTime := time.Now()
 
-	// 优雅降级：如果vector检索失败，只使用BM25结果
-	if vectorRes.Error != nil {
-		log.Warnf("⚠️  Vector retrieval failed, using BM25 only: %v", vectorRes.Error)
-		span.AddMetadata("vector_fallback", true)
-		// 继续使用BM25结果
+	variants := r.expander.Expand(ctx, query)
+	dense := r.denseSources()
+	if len(dense) == 0 {
+		variants = withoutHyDE(variants)
+	}
+	texts := make([]string, 0, len(variants))
+	for _, variant := range variants {
+		texts = append(texts, variant.Text)
+	}
+	span.AddMetadata("variants", texts)
+
+	// 并行检索各变体
+	results := make([]*models.RetrievalResult, len(variants))
+	errs := make([]error, len(variants))
+	var wg sync.WaitGroup
+	for i, variant := range variants {
+		wg.Add(1)
+		sources := r.sources
+		if variant.Kind == VariantHyDE {
+			sources = dense
+		}
+		go func(i int, text string, sources []HybridSource) {
+			defer wg.Done()
+			results[i], errs[i] = r.retrieveQuery(ctx, text, weights, sources)
+		}(i, variant.Text, sources)
 	}
+	wg.Wait()
+
+	fusionSources := make([]FusionSource, 0, len(variants))
+	hits := make(map[string]int, len(variants))
+	var failures []error
+	for i := range variants {
+		name := variantName(variants, i)
+		if errs[i] != nil {
+			log.Warnf("⚠️  Retrieval for %s variant failed: %v", name, errs[i])
+			variants[i].Error = errs[i].Error()
+			failures = append(failures, fmt.Errorf("%s: %w", name, errs[i]))
+			continue
+		}
 
-	if bm25Res.Error != nil {
-		span.SetError(bm25Res.Error)
-		return nil, fmt.Errorf("BM25 retrieval failed: %w", bm25Res.Error)
+		variants[i].Hits = len(results[i].Documents)
+		hits[name] = variants[i].Hits
+		weight := r.expander.variantWeight(variants[i].Kind)
+		fusionSources = append(fusionSources, FusionSource{
+			Name:      name,
+			Weight:    weight,
+			Documents: results[i].Documents,
+		})
 	}
+	span.AddMetadata("variant_hits", hits)
 
-	// RRF融合（如果vector失败，只使用BM25结果）
-	var fusedDocuments []models.Document
-	if vectorRes.Error != nil {
-		// 只使用BM25结果
-		fusedDocuments = bm25Res.Result.Documents
-	} else {
-		// 正常RRF融合
-		fusedDocuments = r.reciprocalRankFusion(
-			vectorRes.Result.Documents,
-			bm25Res.Result.Documents,
-		)
+	if len(fusionSources) == 0 {
+		err := fmt.Errorf("retrieval failed for all query variants: %w", errors.Join(failures...))
+		span.SetError(err)
+		return nil, err
 	}
 
-	// 截取top-k
-	if len(fusedDocuments) > r.config.TopK {
-		fusedDocuments = fusedDocuments[:r.config.TopK]
+	fusion, err := NewFusion(FusionRRF, r.config.RRF)
+	if err != nil {
+		span.SetError(err)
+		return nil, err
+	}
+	explain := ExplainEnabled(ctx)
+	fusedDocuments := fusion.Fuse(fusionSources, explain)
+	if explain {
+		restoreSourceFusion(fusionSources, fusedDocuments)
 	}
 
 	result := &models.RetrievalResult{
-		Documents: fusedDocuments,
+		Documents: r.selectTopK(ctx, fusedDocuments),
 		Strategy:  "hybrid",
 		Query:     query,
+		Variants:  variants,
 		Latency:   float64(time.Since(startTime).Milliseconds()),
 	}
 
-	span.AddMetadata("result_count", len(fusedDocuments))
-	if vectorRes.Result != nil {
-		span.AddMetadata("vector_result_count", len(vectorRes.Result.Documents))
-	}
-	span.AddMetadata("bm25_result_count", len(bm25Res.Result.Documents))
+	span.AddMetadata("result_count", len(result.Documents))
 	span.AddMetadata("latency_ms", result.Latency)
 
-	log.Infof("✅ Hybrid retrieval completed: %d results in %.2fms",
-		len(fusedDocuments), result.Latency)
+	log.Infof("✅ Expanded hybrid retrieval completed: %d results from %d/%d variants in %.2fms",
+		len(result.Documents), len(fusionSources), len(variants), result.Latency)
 
 	return result, nil
 }
 
-// reciprocalRankFusion RRF融合算法
-func (r *HybridRetriever) reciprocalRankFusion(
-	vectorDocs []models.Document,
-	bm25Docs []models.Document,
-) []models.Document {
-	// 记录每个文档的RRF分数
-	type docScore struct {
-		Doc   models.Document
-		Score float64
-	}
-
-	scores := make(map[string]*docScore)
-
-	log.Infof("🐛 DEBUG: Processing %d vector docs", len(vectorDocs))
-
-	// 处理向量检索结果（RRF融合算法）
-	// RRF公式：score = weight × K / (K + rank)
-	// rank=0 → score最大，rank越大 → score越小（排名越靠后，分数越低）
-	for rank, doc := range vectorDocs { // rank: 0,1,2...（0是最高排名）
-		// 计算RRF分数：权重 × (K / (K + 排名 + 1))
-		// 例：K=60, rank=0 → 60/61 ≈ 0.98（最高分）
-		//     K=60, rank=9 → 60/70 ≈ 0.86
-		rrfScore := r.config.VectorWeight * float64(r.config.RRF) / float64(r.config.RRF+rank+1)
-
-		// 调试日志：打印文档 ID 和分数
-		if rank < 3 { // 只打印前3个
-			log.Infof("🐛 DEBUG vector[%d]: ID=%s, rrf_score=%.4f", rank, doc.ID, rrfScore)
+// expandQuery 查询变体：配置了 LLM 时为改写和假设答案，否则为相邻词对组成的部分查询
+func (r *HybridRetriever) expandQuery(ctx context.Context, query string) []models.QueryVariant {
+	if r.expander != nil {
+		return r.expander.Expand(ctx, query)
+	}
+
+	variants := []models.QueryVariant{{Kind: VariantOriginal, Text: query}}
+	if r.bm25Retriever == nil {
+		return variants
+	}
+
+	// 生成查询变体（Query Expansion）：用多种方式表达同一查询，提高召回率
+	// 添加部分查询（用于召回增强）：取相邻词对生成新查询
+	// 例：原查询="红烧肉怎么做" 分词=["红烧", "肉", "怎么", "做"]
+	// 生成变体："红烧 肉", "肉 怎么", "怎么 做"
+	// 作用：如果文档中有"红烧肉"但没有完整句子，也能被召回
+	terms := r.bm25Retriever.Tokenize(query)
+	if len(terms) > 2 {
+		for i := 0; i < len(terms)-1; i++ {
+			variants = append(variants, models.QueryVariant{
+				Kind: VariantPartial,
+				Text: fmt.Sprintf("%s %s", terms[i], terms[i+1]),
+			})
 		}
+	}
+	return variants
+}
 
-		if existing, exists := scores[doc.ID]; exists {
-			existing.Score += rrfScore // 文档已存在，累加分数（同时出现在向量+BM25中）
-		} else {
-			scores[doc.ID] = &docScore{ // 文档不存在，创建新条目
-				Doc:   doc,
-				Score: rrfScore,
-			}
+// withoutHyDE 去掉假设答案变体（没有向量检索时 HyDE 没有意义）
+func withoutHyDE(variants []models.QueryVariant) []models.QueryVariant {
+	kept := make([]models.QueryVariant, 0, len(variants))
+	for _, variant := range variants {
+		if variant.Kind != VariantHyDE {
+			kept = append(kept, variant)
+		}
+	}
+	return kept
+}
+
+// variantName 变体在融合明细和链路追踪中的名称（同类变体按出现顺序编号，如 paraphrase_2）
+func variantName(variants []models.QueryVariant, index int) string {
+	kind := variants[index].Kind
+	if kind == VariantOriginal || kind == VariantHyDE {
+		return kind
+	}
+	n := 0
+	for _, variant := range variants[:index+1] {
+		if variant.Kind == kind {
+			n++
 		}
 	}
+	return fmt.Sprintf("%s_%d", kind, n)
+}
 
-	// 处理BM25检索结果（同样的RRF公式）
-	for rank, doc := range bm25Docs {
-		rrfScore := r.config.BM25Weight * float64(r.config.RRF) / float64(r.config.RRF+rank+1)
+// variantWeight 变体在变体融合中的权重
+func (e *QueryExpander) variantWeight(kind string) float64 {
+	if kind == VariantOriginal {
+		return e.config.OriginalWeight
+	}
+	return e.config.VariantWeight
+}
 
-		if existing, exists := scores[doc.ID]; exists {
-			existing.Score += rrfScore // 累加BM25分数
-		} else {
-			scores[doc.ID] = &docScore{
-				Doc:   doc,
-				Score: rrfScore,
+// restoreSourceFusion 变体融合的明细移到 Explanation.Expansion，Fusion 恢复为文档首次出现的变体中各来源的融合明细
+func restoreSourceFusion(sources []FusionSource, documents []models.Document) {
+	sourceFusion := make(map[string]*models.FusionExplanation)
+	for _, source := range sources {
+		for _, doc := range source.Documents {
+			identity := ParentID(doc)
+			if _, ok := sourceFusion[identity]; !ok && doc.Explanation != nil {
+				sourceFusion[identity] = doc.Explanation.Fusion
 			}
 		}
 	}
 
-	// 转换为切片并排序
-	resultList := make([]*docScore, 0, len(scores))
-	for _, item := range scores {
-		resultList = append(resultList, item)
+	for i := range documents {
+		if explanation := documents[i].Explanation; explanation != nil {
+			explanation.Expansion = explanation.Fusion
+			explanation.Fusion = sourceFusion[ParentID(documents[i])]
+		}
 	}
+}
 
-	sort.Slice(resultList, func(i, j int) bool {
-		return resultList[i].Score > resultList[j].Score
-	})
+// sourceOutcome 一路子检索的结果
+type sourceOutcome struct {
+	documents []models.Document
+	err       error
+}
+
+// runSources 并行执行各路子检索，结果与 sources 一一对应
+func (r *HybridRetriever) runSources(ctx context.Context, query string, sources []HybridSource) []sourceOutcome {
+	outcomes := make([]sourceOutcome, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source HybridSource) {
+			defer wg.Done()
+			outcomes[i] = r.runSource(ctx, source, query)
+		}(i, source)
+	}
+	wg.Wait()
+	return outcomes
+}
 
-	// 返回融合后的文档列表
-	fusedDocuments := make([]models.Document, 0, len(resultList))
-	for _, item := range resultList {
-		doc := item.Doc
-		doc.Score = float32(item.Score) // 更新分数
-		fusedDocuments = append(fusedDocuments, doc)
+// runSource 在超时时间内执行一路子检索（不响应 ctx 的检索器超时后继续在后台完成，结果被丢弃）
+func (r *HybridRetriever) runSource(ctx context.Context, source HybridSource, query string) sourceOutcome {
+	timeout := source.Timeout
+	if timeout <= 0 {
+		timeout = r.config.SourceTimeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	return fusedDocuments
+	done := make(chan sourceOutcome, 1)
+	go func() {
+		result, err := source.Retriever.Retrieve(ctx, query)
+		if err != nil {
+			done <- sourceOutcome{err: err}
+			return
+		}
+		outcome := sourceOutcome{}
+		if result != nil {
+			outcome.documents = result.Documents
+		}
+		done <- outcome
+	}()
+
+	select {
+	case outcome := <-done:
+		return outcome
+	case <-ctx.Done():
+		return sourceOutcome{err: fmt.Errorf("timed out after %s: %w", timeout, ctx.Err())}
+	}
+}
+
+// sourceNames 各路子检索的名称
+func sourceNames(sources []HybridSource) []string {
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		names = append(names, source.Name)
+	}
+	return names
+}
+
+// denseSources 向量检索的各路（HyDE 的假设答案只用于稠密检索，交给 BM25 / 图检索只会引入编造的食材词）
+func (r *HybridRetriever) denseSources() []HybridSource {
+	var dense []HybridSource
+	for _, source := range r.sources {
+		if _, ok := source.Retriever.(*VectorRetriever); ok {
+			dense = append(dense, source)
+		}
+	}
+	return dense
+}
+
+// fusion 本次检索使用的融合策略（请求指定的方法优先于配置）
+func (r *HybridRetriever) fusion(ctx context.Context) (Fusion, error) {
+	method := r.config.Fusion
+	if requested := FusionFromContext(ctx); requested != "" {
+		method = requested
+	}
+	return NewFusion(method, r.config.RRF)
 }
 
 // AdaptiveRetrieval 自适应检索（根据查询复杂度调整策略）
@@ -252,49 +518,22 @@ func (r *HybridRetriever) AdaptiveRetrieval(
 
 	log.Infof("📊 Adaptive weights: vector=%.2f, bm25=%.2f", vectorWeight, bm25Weight)
 
-	// 创建临时配置
-	adaptiveConfig := &HybridRetrieverConfig{
-		VectorWeight: vectorWeight,
-		BM25Weight:   bm25Weight,
-		TopK:         r.config.TopK,
-		RRFK:         r.config.RRF,
-		RRF:          r.config.RRF, // 修复：必须同时设置 RRF
-	}
-
-	// 使用自适应配置创建临时检索器
-	adaptiveRetriever := &HybridRetriever{
-		config:          adaptiveConfig,
-		vectorRetriever: r.vectorRetriever,
-		bm25Retriever:   r.bm25Retriever,
-	}
-
-	return adaptiveRetriever.Retrieve(ctx, query)
+	// 只覆盖向量和BM25两路的权重，其他来源（如图检索）保持配置的权重
+	return r.retrieve(ctx, query, map[string]float64{
+		"vector": vectorWeight,
+		"bm25":   bm25Weight,
+	})
 }
 
-// QueryExpansion 查询扩展
+// QueryExpansion 查询扩展：返回查询变体（第一个为原始查询）
+// 配置了 LLM 扩展器时为改写和假设答案（HyDE），否则为分词后的相邻词对
 func (r *HybridRetriever) QueryExpansion(ctx context.Context, query string) ([]string, error) {
 	log.Infof("🔍 Query expansion for: %s", query)
 
-	// 简单实现：分词后生成变体
-	// 实际应用中可以使用LLM生成相关查询
-	terms := r.bm25Retriever.Tokenize(query)
-
-	if len(terms) == 0 {
-		return []string{query}, nil
-	}
-
-	// 生成查询变体（Query Expansion）：用多种方式表达同一查询，提高召回率
-	queries := []string{query} // 原始查询："红烧肉怎么做"
-
-	// 添加部分查询（用于召回增强）：取相邻词对生成新查询
-	// 例：原查询="红烧肉怎么做" 分词=["红烧", "肉", "怎么", "做"]
-	// 生成变体："红烧肉", "肉怎么", "怎么做"
-	// 作用：如果文档中有"红烧肉"但没有完整句子，也能被召回
-	if len(terms) > 2 {
-		for i := 0; i < len(terms)-1; i++ {
-			partialQuery := fmt.Sprintf("%s %s", terms[i], terms[i+1])
-			queries = append(queries, partialQuery)
-		}
+	variants := r.expandQuery(ctx, query)
+	queries := make([]string, 0, len(variants))
+	for _, variant := range variants {
+		queries = append(queries, variant.Text)
 	}
 
 	log.Infof("✅ Generated %d query variations", len(queries))
@@ -303,11 +542,20 @@ func (r *HybridRetriever) QueryExpansion(ctx context.Context, query string) ([]s
 
 // GetStats 获取检索器统计信息
 func (r *HybridRetriever) GetConfig() map[string]interface{} {
+	weights := make(map[string]float64, len(r.sources))
+	for _, source := range r.sources {
+		weights[source.Name] = source.Weight
+	}
+
 	return map[string]interface{}{
+		"sources":       weights,
 		"vector_weight": r.config.VectorWeight,
 		"bm25_weight":   r.config.BM25Weight,
 		"top_k":         r.config.TopK,
 		"rrf_k":         r.config.RRF,
-		"strategy":      "hybrid_rrf",
+		"fusion":        r.config.Fusion,
+		"strategy":      "hybrid_" + r.config.Fusion,
+		"mmr":           r.diversifier != nil,
+		"expansion":     r.expander != nil,
 	}
 }
@@ -0,0 +1,268 @@
+package retrieval
+
+import (
+	"context"
+	"unicode"
+
+	"cookrag-go/internal/models"
+	"cookrag-go/internal/observability"
+
+	"github.com/charmbracelet/log"
+)
+
+// 文档间相似度的计算方式
+const (
+	MMRSimilarityAuto      = "auto"      // 有存储向量时用向量余弦相似度，没有时用内容相似度
+	MMRSimilarityEmbedding = "embedding" // 只用存储向量（缺向量的文档与其他文档的相似度记为 0）
+	MMRSimilarityContent   = "content"   // 只用内容相似度（字符二元组的 Jaccard 系数）
+)
+
+// MMRConfig MMR（最大边际相关）多样化配置
+type MMRConfig struct {
+	Lambda          float64 // 相关性权重（0-1）：1 只按相关性排序，越小越偏向与已选文档不同的结果
+	TopK            int     // 在查询路由之后使用时保留的数量（0 表示保留全部，只调整顺序）
+	CandidateFactor int     // 在向量检索中使用时多取 TopK 的倍数，从更大的候选集中挑选
+	Similarity      string  // auto / embedding / content
+}
+
+// DefaultMMRConfig 默认配置（lambda=0.7，保留 5 篇）
+func DefaultMMRConfig() *MMRConfig {
+	return &MMRConfig{
+		Lambda:          0.7,
+		TopK:            5,
+		CandidateFactor: 2,
+		Similarity:      MMRSimilarityAuto,
+	}
+}
+
+// DocumentVectorSource 提供文档的已存储向量（VectorRetriever 实现）
+type DocumentVectorSource interface {
+	DocumentVectors(ctx context.Context, documents []models.Document) (map[string][]float32, error)
+}
+
+// MMRReranker MMR 多样化重排：每次选出 lambda*相关性 - (1-lambda)*与已选文档的最大相似度 最高的文档
+// 避免"川菜有哪些特色"之类的查询返回同一道菜的多个变体
+// 相关性为检索分数在候选集内的最小-最大归一化，可用于向量、混合检索或路由后的任意结果
+type MMRReranker struct {
+	config  *MMRConfig
+	vectors DocumentVectorSource // 文档向量来源（可为nil，只用内容相似度）
+}
+
+// NewMMRReranker 创建 MMR 重排器
+func NewMMRReranker(config *MMRConfig, vectors DocumentVectorSource) *MMRReranker {
+	if config == nil {
+		config = DefaultMMRConfig()
+	}
+
+	return &MMRReranker{
+		config:  config,
+		vectors: vectors,
+	}
+}
+
+// TopK 在查询路由之后使用时保留的数量
+func (m *MMRReranker) TopK() int {
+	return m.config.TopK
+}
+
+// CandidateFactor 在检索器内部使用时多取的倍数
+func (m *MMRReranker) CandidateFactor() int {
+	return max(m.config.CandidateFactor, 1)
+}
+
+// Rerank 从 documents（按相关性从高到低）中依次选出 topK 篇（topK<=0 时全部重排），文档分数保持不变
+func (m *MMRReranker) Rerank(ctx context.Context, documents []models.Document, topK int) []models.Document {
+	if topK <= 0 || topK > len(documents) {
+		topK = len(documents)
+	}
+	if len(documents) <= 1 || m.config.Lambda >= 1 {
+		return documents[:topK]
+	}
+
+	span := observability.GlobalTracer.StartSpan(ctx, "mmr_rerank", map[string]interface{}{
+		"lambda":     m.config.Lambda,
+		"candidates": len(documents),
+		"top_k":      topK,
+	})
+	defer span.End()
+
+	relevance := normalizedScores(documents)
+	similarity := m.similarity(ctx, documents)
+	span.AddMetadata("similarity", similarity.method())
+	if similarity.missing > 0 {
+		span.AddMetadata("missing_vectors", similarity.missing)
+	}
+
+	selected := make([]int, 0, topK)
+	used := make([]bool, len(documents))
+	maxSim := make([]float64, len(documents))
+	for len(selected) < topK {
+		best, bestScore := -1, 0.0
+		for i := range documents {
+			if used[i] {
+				continue
+			}
+			score := m.config.Lambda*relevance[i] - (1-m.config.Lambda)*maxSim[i]
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		used[best] = true
+		selected = append(selected, best)
+		for i := range documents {
+			if !used[i] {
+				maxSim[i] = max(maxSim[i], similarity.between(i, best))
+			}
+		}
+	}
+
+	reranked := make([]models.Document, 0, len(selected))
+	moved := 0
+	for rank, i := range selected {
+		if rank != i {
+			moved++
+		}
+		reranked = append(reranked, documents[i])
+	}
+
+	span.AddMetadata("moved", moved)
+	log.Infof("🎯 MMR reranked %d candidates → %d (lambda=%.2f, similarity=%s, %d moved)",
+		len(documents), len(reranked), m.config.Lambda, similarity.method(), moved)
+	return reranked
+}
+
+// documentSimilarity 文档两两之间的相似度
+type documentSimilarity struct {
+	vectors     [][]float32       // 文档向量（没有时为 nil）
+	contents    []string          // 文档内容
+	bigrams     []map[string]bool // 内容的字符二元组（延迟计算）
+	vectorsOnly bool              // 只用向量（embedding），缺向量时相似度记为 0
+	missing     int               // 没有存储向量的文档数
+	usedVec     bool
+	usedText    bool
+}
+
+// similarity 准备文档间相似度：优先使用存储向量
+// auto 时读取失败或不支持的文档退回内容相似度；embedding 时缺向量的文档相似度记为 0
+func (m *MMRReranker) similarity(ctx context.Context, documents []models.Document) *documentSimilarity {
+	sim := &documentSimilarity{
+		vectors:     make([][]float32, len(documents)),
+		contents:    make([]string, len(documents)),
+		bigrams:     make([]map[string]bool, len(documents)),
+		vectorsOnly: m.config.Similarity == MMRSimilarityEmbedding,
+	}
+	for i, doc := range documents {
+		sim.contents[i] = doc.Content
+	}
+	if m.config.Similarity == MMRSimilarityContent {
+		return sim
+	}
+
+	if m.vectors != nil {
+		vectors, err := m.vectors.DocumentVectors(ctx, documents)
+		if err != nil {
+			log.Debugf("MMR failed to load document vectors: %v", err)
+		}
+		for i, doc := range documents {
+			sim.vectors[i] = vectors[doc.ID]
+		}
+	}
+	for _, vector := range sim.vectors {
+		if vector == nil {
+			sim.missing++
+		}
+	}
+	if sim.vectorsOnly && sim.missing > 0 {
+		log.Warnf("⚠️  MMR: %d/%d documents have no stored vector, treating their similarity as 0",
+			sim.missing, len(documents))
+	}
+	return sim
+}
+
+// between 文档 i 与 j 的相似度：两篇都有向量时取余弦相似度
+// 否则 embedding 时为 0，auto 时取内容的字符二元组 Jaccard 系数
+func (s *documentSimilarity) between(i, j int) float64 {
+	if s.vectors[i] != nil && s.vectors[j] != nil {
+		s.usedVec = true
+		return cosineSimilarity(s.vectors[i], s.vectors[j])
+	}
+	if s.vectorsOnly {
+		return 0
+	}
+	s.usedText = true
+	return jaccard(s.bigramsOf(i), s.bigramsOf(j))
+}
+
+// method 实际使用的相似度（embedding / content / mixed）
+func (s *documentSimilarity) method() string {
+	switch {
+	case s.usedVec && s.usedText:
+		return "mixed"
+	case s.usedVec || s.vectorsOnly:
+		return MMRSimilarityEmbedding
+	default:
+		return MMRSimilarityContent
+	}
+}
+
+// bigramsOf 文档 i 内容的字符二元组（忽略空白和标点，延迟计算）
+func (s *documentSimilarity) bigramsOf(i int) map[string]bool {
+	if s.bigrams[i] == nil {
+		s.bigrams[i] = contentBigrams(s.contents[i])
+	}
+	return s.bigrams[i]
+}
+
+// contentBigrams 提取字符二元组，中文菜谱没有分词时也能反映内容重合度
+func contentBigrams(content string) map[string]bool {
+	runes := make([]rune, 0, len(content))
+	for _, r := range content {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, unicode.ToLower(r))
+		}
+	}
+
+	bigrams := make(map[string]bool, len(runes))
+	for i := 0; i+1 < len(runes); i++ {
+		bigrams[string(runes[i:i+2])] = true
+	}
+	return bigrams
+}
+
+// jaccard 两个集合的 Jaccard 系数
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	shared := 0
+	for item := range a {
+		if b[item] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// normalizedScores 分数的最小-最大归一化（全部相同时均为 1）
+func normalizedScores(documents []models.Document) []float64 {
+	minScore, maxScore := documents[0].Score, documents[0].Score
+	for _, doc := range documents[1:] {
+		minScore = min(minScore, doc.Score)
+		maxScore = max(maxScore, doc.Score)
+	}
+
+	normalized := make([]float64, len(documents))
+	for i, doc := range documents {
+		if maxScore == minScore {
+			normalized[i] = 1
+			continue
+		}
+		normalized[i] = float64(doc.Score-minScore) / float64(maxScore-minScore)
+	}
+	return normalized
+}
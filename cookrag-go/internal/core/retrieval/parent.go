@@ -0,0 +1,422 @@
+package retrieval
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"cookrag-go/internal/models"
+	"cookrag-go/internal/observability"
+	"cookrag-go/pkg/storage/docstore"
+
+	"github.com/charmbracelet/log"
+)
+
+// TrimmedSectionsMetadataKey 因上下文长度限制被裁掉的章节
+const TrimmedSectionsMetadataKey = "trimmed_sections"
+
+// ParentScoreMode 父文档得分方式
+type ParentScoreMode string
+
+const (
+	ParentScoreMax ParentScoreMode = "max" // 取最相关分块的分数
+	ParentScoreSum ParentScoreMode = "sum" // 各命中分块的分数之和（多个章节都命中的菜谱排名更靠前）
+)
+
+// ParentDocumentRetrieverConfig 父文档检索配置
+type ParentDocumentRetrieverConfig struct {
+	ScoreMode        ParentScoreMode // max / sum
+	MaxContextTokens int             // 返回的全部父文档的 token 总数上限（0 表示不限制），超出时先裁掉最不相关的章节
+	CoreSections     []string        // 未命中时也优先保留的章节（回答"怎么做"需要原料、用量和步骤）
+}
+
+// DefaultParentDocumentRetrieverConfig 默认配置（取最高分，上下文 3000 token）
+func DefaultParentDocumentRetrieverConfig() *ParentDocumentRetrieverConfig {
+	return &ParentDocumentRetrieverConfig{
+		ScoreMode:        ParentScoreMax,
+		MaxContextTokens: 3000,
+		CoreSections:     []string{"必备原料和工具", "计算", "操作"},
+	}
+}
+
+// ParentDocumentRetriever 父文档检索：在细粒度分块上匹配，按原菜谱合并命中，返回完整菜谱
+// 分块只用于匹配，交给 LLM 的是整篇菜谱（含"计算"中的用量）；超出上下文长度时按章节裁剪
+type ParentDocumentRetriever struct {
+	config  *ParentDocumentRetrieverConfig
+	parents docstore.Store // 原菜谱存储（按原文档ID回查完整内容）
+	counter func(text string) int
+}
+
+// NewParentDocumentRetriever 创建父文档检索器
+func NewParentDocumentRetriever(config *ParentDocumentRetrieverConfig, parents docstore.Store) *ParentDocumentRetriever {
+	if config == nil {
+		config = DefaultParentDocumentRetrieverConfig()
+	}
+
+	return &ParentDocumentRetriever{
+		config:  config,
+		parents: parents,
+		counter: EstimateTokens,
+	}
+}
+
+// SetTokenCounter 设置上下文长度的 token 计数方式（如与 LLM 一致的分词器）
+func (r *ParentDocumentRetriever) SetTokenCounter(counter func(text string) int) {
+	if counter != nil {
+		r.counter = counter
+	}
+}
+
+// parentHit 同一原文档的命中
+type parentHit struct {
+	doc      models.Document // 最相关的分块（合并后替换为原文档）
+	scores   []float64       // 各命中分块的分数
+	sections []string        // 命中的章节（按相关度从高到低）
+}
+
+// Expand 把检索结果（分块或已按原文档聚合的分块）合并为原文档
+// 父文档得分按 ScoreMode 取最高分或分数之和，内容替换为原文档全文，最后按 MaxContextTokens 裁剪章节
+func (r *ParentDocumentRetriever) Expand(ctx context.Context, documents []models.Document) []models.Document {
+	span := observability.GlobalTracer.StartSpan(ctx, "parent_document_expand", map[string]interface{}{
+		"input_count":        len(documents),
+		"score_mode":         string(r.config.ScoreMode),
+		"max_context_tokens": r.config.MaxContextTokens,
+	})
+	defer span.End()
+
+	hits := make([]*parentHit, 0, len(documents))
+	byParent := make(map[string]*parentHit)
+	for _, doc := range documents {
+		parentID := ParentID(doc)
+		hit, ok := byParent[parentID]
+		if !ok {
+			hit = &parentHit{doc: doc}
+			hit.doc.ID = parentID
+			byParent[parentID] = hit
+			hits = append(hits, hit)
+		}
+		hit.scores = append(hit.scores, chunkScores(doc)...)
+		for _, section := range matchedSections(doc) {
+			hit.sections = appendUniqueString(hit.sections, section)
+		}
+	}
+
+	expanded := make([]models.Document, 0, len(hits))
+	missing := 0
+	for _, hit := range hits {
+		doc := hit.doc
+		doc.Score = float32(r.parentScore(hit.scores))
+
+		metadata := make(map[string]interface{}, len(doc.Metadata)+2)
+		for k, v := range doc.Metadata {
+			metadata[k] = v
+		}
+		if parent, ok := r.lookup(ctx, doc.ID); ok {
+			// 原文档的元数据为准，去掉分块特有的字段
+			for k, v := range parent.Metadata {
+				metadata[k] = v
+			}
+			for _, key := range []string{ParentMetadataKey, ChunkMetadataKey, ChunkCountMetadataKey, SectionMetadataKey} {
+				delete(metadata, key)
+			}
+			doc.Content = parent.Content
+		} else {
+			missing++
+		}
+		metadata[ChunkHitsMetadataKey] = len(hit.scores)
+		metadata[ChunkScoresMetadataKey] = hit.scores
+		if len(hit.sections) > 0 {
+			metadata[MatchedSectionsMetadataKey] = hit.sections
+		}
+		doc.Metadata = metadata
+		expanded = append(expanded, doc)
+	}
+
+	sort.SliceStable(expanded, func(i, j int) bool {
+		return expanded[i].Score > expanded[j].Score
+	})
+
+	keep, trimmed, contextTokens := r.fitContext(expanded)
+	expanded = expanded[:keep]
+	for i := range expanded {
+		relocateHighlights(&expanded[i])
+	}
+
+	span.AddMetadata("parent_count", len(expanded))
+	span.AddMetadata("missing_parents", missing)
+	span.AddMetadata("trimmed_sections", trimmed)
+	span.AddMetadata("context_tokens", contextTokens)
+	if missing > 0 {
+		log.Warnf("⚠️  %d parent documents not found, keeping chunk content", missing)
+	}
+	log.Infof("📄 Parent documents: %d hits → %d recipes, %d tokens, %d sections trimmed",
+		len(documents), len(expanded), contextTokens, trimmed)
+
+	return expanded
+}
+
+// parentScore 按 ScoreMode 计算父文档得分
+func (r *ParentDocumentRetriever) parentScore(scores []float64) float64 {
+	result := 0.0
+	for i, score := range scores {
+		switch r.config.ScoreMode {
+		case ParentScoreSum:
+			result += score
+		default:
+			if i == 0 || score > result {
+				result = score
+			}
+		}
+	}
+	return result
+}
+
+// lookup 从原菜谱存储回查原文档
+func (r *ParentDocumentRetriever) lookup(ctx context.Context, id string) (models.Document, bool) {
+	if r.parents == nil {
+		return models.Document{}, false
+	}
+	return r.parents.Get(ctx, id)
+}
+
+// recipeSection 菜谱的二级章节
+type recipeSection struct {
+	title string // 章节标题（一级标题和简介所在的开头部分为 OverviewSection）
+	text  string // 章节全文（含标题行）
+	tier  int    // 裁剪优先级：0 命中的章节，1 未命中的核心章节，2 其他章节（数字越大越先裁掉）
+	rank  int    // 同一优先级内的顺序（数字越大越先裁掉）
+	kept  bool
+}
+
+// fitContext 按上下文长度上限裁剪文档，返回保留的文档数、裁掉的章节数和最终的 token 总数
+// 裁剪顺序：各文档未命中的非核心章节（附加内容等）→ 从排名最后的文档开始裁未命中的核心章节和命中较弱的章节
+// → 从排名最后的文档开始整篇去掉；每篇文档的开头部分（菜名、简介）和最相关的章节只随整篇去掉
+func (r *ParentDocumentRetriever) fitContext(documents []models.Document) (int, int, int) {
+	budget := r.config.MaxContextTokens
+	sections := make([][]*recipeSection, len(documents))
+	total := 0
+	for i := range documents {
+		sections[i] = r.rankSections(documents[i])
+		total += r.counter(documents[i].Content)
+	}
+	if budget <= 0 || total <= budget {
+		return len(documents), 0, total
+	}
+
+	type candidate struct {
+		doc     int
+		section *recipeSection
+		tokens  int
+	}
+	candidates := make([]candidate, 0)
+	for i, docSections := range sections {
+		for _, section := range docSections {
+			if section.title == OverviewSection || (section.tier == 0 && section.rank == 0) {
+				continue
+			}
+			candidates = append(candidates, candidate{doc: i, section: section, tokens: r.counter(section.text)})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if aOther, bOther := a.section.tier == 2, b.section.tier == 2; aOther != bOther {
+			return aOther
+		}
+		if a.doc != b.doc {
+			return a.doc > b.doc
+		}
+		if a.section.tier != b.section.tier {
+			return a.section.tier > b.section.tier
+		}
+		return a.section.rank > b.section.rank
+	})
+
+	trimmed := 0
+	dropped := make(map[int][]string)
+	for _, c := range candidates {
+		if total <= budget {
+			break
+		}
+		c.section.kept = false
+		total -= c.tokens
+		trimmed++
+		dropped[c.doc] = append(dropped[c.doc], c.section.title)
+	}
+
+	// 仍然超出时从排名最后的文档开始整篇去掉（至少保留第一篇）
+	keep := len(documents)
+	for keep > 1 && total > budget {
+		keep--
+		for _, section := range sections[keep] {
+			if section.kept {
+				total -= r.counter(section.text)
+				trimmed++
+			}
+		}
+	}
+
+	for i := 0; i < keep; i++ {
+		var content strings.Builder
+		for _, section := range sections[i] {
+			if section.kept {
+				content.WriteString(section.text)
+			}
+		}
+		documents[i].Content = strings.TrimSpace(content.String())
+		if len(dropped[i]) > 0 {
+			documents[i].Metadata[TrimmedSectionsMetadataKey] = dropped[i]
+		}
+	}
+
+	// 只剩一篇仍然超出时截断到上限
+	if total > budget {
+		documents[0].Content = r.truncate(documents[0].Content, budget)
+		total = r.counter(documents[0].Content)
+	}
+
+	return keep, trimmed, total
+}
+
+// rankSections 按二级标题切分文档并标出裁剪优先级
+// 命中的章节按命中顺序排列，未命中的章节从文末往前裁
+func (r *ParentDocumentRetriever) rankSections(doc models.Document) []*recipeSection {
+	matched := make(map[string]int)
+	if sections, ok := doc.Metadata[MatchedSectionsMetadataKey].([]string); ok {
+		for _, label := range sections {
+			// 分块的章节名可能是合并的多个章节（概述、必备原料和工具）或带下级标题（操作 > 收汁）
+			for _, part := range strings.Split(label, "、") {
+				title := strings.TrimSpace(strings.SplitN(part, " > ", 2)[0])
+				if _, ok := matched[title]; !ok {
+					matched[title] = len(matched)
+				}
+			}
+		}
+	}
+
+	core := make(map[string]bool, len(r.config.CoreSections))
+	for _, title := range r.config.CoreSections {
+		core[title] = true
+	}
+
+	sections := splitRecipeSections(doc.Content)
+	for i, section := range sections {
+		section.kept = true
+		switch rank, ok := matched[section.title]; {
+		case ok:
+			section.tier, section.rank = 0, rank
+		case core[section.title]:
+			section.tier, section.rank = 1, i
+		default:
+			section.tier, section.rank = 2, i
+		}
+	}
+	return sections
+}
+
+// truncate 截断文本到 token 上限
+func (r *ParentDocumentRetriever) truncate(text string, budget int) string {
+	var b strings.Builder
+	tokens := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineTokens := r.counter(line)
+		if tokens+lineTokens > budget {
+			break
+		}
+		b.WriteString(line)
+		tokens += lineTokens
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// splitRecipeSections 按二级标题切分菜谱（代码块中的 ## 不是标题），开头部分的标题为 OverviewSection
+func splitRecipeSections(content string) []*recipeSection {
+	sections := []*recipeSection{{title: OverviewSection}}
+	current := sections[0]
+	var text strings.Builder
+	inFence := false
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(trimmed, "## ") {
+			current.text = text.String()
+			text.Reset()
+			current = &recipeSection{title: strings.TrimSpace(strings.TrimPrefix(trimmed, "## "))}
+			sections = append(sections, current)
+		}
+		text.WriteString(line)
+	}
+	current.text = text.String()
+	return sections
+}
+
+// chunkScores 文档对应的分块分数：按原文档聚合过的结果按聚合时记录的各分块分数比例换算到当前分数
+// （融合排序后的分数与分块原始分数不在同一尺度），未聚合的分块即其自身分数
+func chunkScores(doc models.Document) []float64 {
+	var raw []float64
+	switch v := doc.Metadata[ChunkScoresMetadataKey].(type) {
+	case []float64:
+		raw = v
+	case []interface{}: // 经过缓存（JSON）后的类型
+		for _, item := range v {
+			if f, ok := item.(float64); ok {
+				raw = append(raw, f)
+			}
+		}
+	}
+	if len(raw) == 0 || raw[0] <= 0 {
+		return []float64{float64(doc.Score)}
+	}
+
+	scores := make([]float64, len(raw))
+	for i, score := range raw {
+		scores[i] = float64(doc.Score) * score / raw[0]
+	}
+	return scores
+}
+
+// matchedSections 文档命中的章节（聚合结果取 matched_sections，分块取 section）
+func matchedSections(doc models.Document) []string {
+	switch v := doc.Metadata[MatchedSectionsMetadataKey].(type) {
+	case []string:
+		return v
+	case []interface{}:
+		sections := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				sections = append(sections, s)
+			}
+		}
+		return sections
+	}
+	if section, ok := doc.Metadata[SectionMetadataKey].(string); ok && section != "" {
+		return []string{section}
+	}
+	return nil
+}
+
+// relocateHighlights 内容替换为原文档后按片段文本重新定位命中位置，找不到的片段去掉
+func relocateHighlights(doc *models.Document) {
+	if len(doc.Highlights) == 0 {
+		return
+	}
+
+	bySource := make(map[string][]string)
+	sources := make([]string, 0)
+	for _, highlight := range doc.Highlights {
+		if _, ok := bySource[highlight.Source]; !ok {
+			sources = append(sources, highlight.Source)
+		}
+		bySource[highlight.Source] = appendUniqueString(bySource[highlight.Source], highlight.Text)
+	}
+
+	relocated := make([]models.Highlight, 0, len(doc.Highlights))
+	for _, source := range sources {
+		relocated = append(relocated, highlightPhrases(doc.Content, bySource[source], source)...)
+	}
+	sort.SliceStable(relocated, func(i, j int) bool {
+		return relocated[i].Start < relocated[j].Start
+	})
+	doc.Highlights = relocated
+}
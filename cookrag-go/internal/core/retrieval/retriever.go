@@ -0,0 +1,44 @@
+package retrieval
+
+import (
+	"context"
+	"time"
+
+	"cookrag-go/internal/models"
+)
+
+// Retriever 通用检索器接口：混合检索按该接口并行调用各路子检索器
+// VectorRetriever 和 GraphRetriever 直接实现；BM25Retriever 需要指定返回数量，见 AsRetriever
+type Retriever interface {
+	Retrieve(ctx context.Context, query string) (*models.RetrievalResult, error)
+}
+
+// AsRetriever 以固定的返回数量把BM25检索包装为 Retriever
+func (r *BM25Retriever) AsRetriever(topK int) Retriever {
+	return &bm25Source{
+		retriever: r,
+		topK:      topK,
+	}
+}
+
+// bm25Source 固定返回数量的BM25检索
+type bm25Source struct {
+	retriever *BM25Retriever
+	topK      int
+}
+
+// Retrieve BM25检索
+func (s *bm25Source) Retrieve(ctx context.Context, query string) (*models.RetrievalResult, error) {
+	startTime := time.Now()
+	documents, err := s.retriever.Retrieve(ctx, query, s.topK)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RetrievalResult{
+		Documents: documents,
+		Strategy:  "bm25",
+		Query:     query,
+		Latency:   float64(time.Since(startTime).Milliseconds()),
+	}, nil
+}
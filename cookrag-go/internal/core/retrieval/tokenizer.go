@@ -0,0 +1,321 @@
+package retrieval
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/log"
+	"github.com/yanyiwu/gojieba"
+)
+
+// TokenizerConfig 分词器配置
+type TokenizerConfig struct {
+	UserDictPaths  []string // 用户词典（每行：词 [词频] [词性]，可由 cmd/build-dict 从菜谱生成）
+	StopWordsPaths []string // 停用词表（每行一个词，# 开头为注释），与内置停用词合并
+}
+
+// DefaultTokenizerConfig 默认分词器配置（只使用 jieba 自带词典和内置停用词）
+func DefaultTokenizerConfig() *TokenizerConfig {
+	return &TokenizerConfig{}
+}
+
+// defaultStopWords 内置停用词（简化版）
+var defaultStopWords = map[string]bool{
+	"的": true, "了": true, "在": true, "是": true, "我": true,
+	"有": true, "和": true, "就": true, "不": true, "人": true,
+	"之": true, "与": true, "及": true, "等": true, "或": true,
+	"吗": true, "呢": true, "吧": true, "啊": true, "呀": true,
+	// 英文停用词
+	"the": true, "a": true, "an": true, "and": true, "or": true,
+	"but": true, "in": true, "on": true, "at": true, "to": true,
+	"of": true, "for": true, "with": true, "by": true, "from": true,
+}
+
+// minSubWordRunes 长度不少于该值的词在索引时额外输出词典中的子词（"简易红烧肉" → "红烧肉"、"红烧"）
+const minSubWordRunes = 3
+
+// Tokenizer 中文分词器：jieba + 领域用户词典 + 停用词过滤
+type Tokenizer struct {
+	jieba       *gojieba.Jieba
+	stopWords   map[string]bool
+	userWords   int
+	fingerprint string
+}
+
+// Token 带位置的词项（子词与所属的词共用同一个位置）
+type Token struct {
+	Text     string
+	Position int
+}
+
+// TokenInfo 分词调试信息
+type TokenInfo struct {
+	Word     string   `json:"word"`
+	Start    int      `json:"start"` // 起始位置（字符偏移）
+	End      int      `json:"end"`   // 结束位置（字符偏移，不含）
+	Kept     bool     `json:"kept"`
+	Reason   string   `json:"reason,omitempty"`    // 被过滤的原因：stopword / too_short / punctuation
+	SubWords []string `json:"sub_words,omitempty"` // 索引时额外输出的子词
+}
+
+// NewTokenizer 创建分词器
+// 词典或停用词文件读取失败时返回错误
+func NewTokenizer(config *TokenizerConfig) (*Tokenizer, error) {
+	if config == nil {
+		config = DefaultTokenizerConfig()
+	}
+
+	t := &Tokenizer{
+		jieba:     gojieba.NewJieba(),
+		stopWords: make(map[string]bool, len(defaultStopWords)),
+	}
+	for word := range defaultStopWords {
+		t.stopWords[word] = true
+	}
+
+	// 指纹覆盖 jieba 自带词典、用户词典和停用词，任何一项变化都意味着分词结果可能不同
+	hash := sha256.New()
+	for _, path := range []string{gojieba.DICT_PATH, gojieba.HMM_PATH, gojieba.USER_DICT_PATH} {
+		if err := hashFile(hash, path); err != nil {
+			return nil, fmt.Errorf("failed to read tokenizer dictionary: %w", err)
+		}
+	}
+
+	for _, path := range config.UserDictPaths {
+		count, err := t.loadUserDict(path, hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load user dictionary %s: %w", path, err)
+		}
+		t.userWords += count
+		log.Infof("📖 Loaded user dictionary: %s (%d words)", path, count)
+	}
+
+	for _, path := range config.StopWordsPaths {
+		count, err := t.loadStopWords(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load stop words %s: %w", path, err)
+		}
+		log.Infof("📖 Loaded stop words: %s (%d words)", path, count)
+	}
+
+	stopWords := make([]string, 0, len(t.stopWords))
+	for word := range t.stopWords {
+		stopWords = append(stopWords, word)
+	}
+	sort.Strings(stopWords)
+	for _, word := range stopWords {
+		hash.Write([]byte(word))
+		hash.Write([]byte{0})
+	}
+	t.fingerprint = fmt.Sprintf("%x", hash.Sum(nil))
+
+	return t, nil
+}
+
+// loadUserDict 加载用户词典并计入指纹，返回词条数
+// 格式与 jieba 用户词典一致：词 [词频] [词性]
+func (t *Tokenizer) loadUserDict(path string, hash io.Writer) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash.Write([]byte(line))
+		hash.Write([]byte{'\n'})
+
+		fields := strings.Fields(line)
+		word, freq, tag := fields[0], 0, ""
+		switch len(fields) {
+		case 1:
+		case 2:
+			if n, err := strconv.Atoi(fields[1]); err == nil {
+				freq = n
+			} else {
+				tag = fields[1]
+			}
+		default:
+			freq, _ = strconv.Atoi(fields[1])
+			tag = fields[2]
+		}
+
+		if freq > 0 {
+			t.jieba.AddWordEx(word, freq, tag)
+		} else {
+			t.jieba.AddWord(word)
+		}
+		count++
+	}
+
+	return count, scanner.Err()
+}
+
+// loadStopWords 加载停用词表，返回词数
+func (t *Tokenizer) loadStopWords(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	count := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		t.stopWords[word] = true
+		count++
+	}
+
+	return count, scanner.Err()
+}
+
+// Tokenize 分词并过滤停用词、单字符和标点（用于查询）
+func (t *Tokenizer) Tokenize(text string) []string {
+	words := t.jieba.Cut(text, true)
+
+	filtered := make([]string, 0)
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if t.filterReason(word) == "" {
+			filtered = append(filtered, word)
+		}
+	}
+
+	return filtered
+}
+
+// Analyze 分词并输出带位置的词项（用于索引）
+// 长词额外输出词典中的子词并与长词共用位置，查询"红烧肉"也能命中分词为"简易红烧肉"的文档
+func (t *Tokenizer) Analyze(text string) []Token {
+	words := t.Tokenize(text)
+
+	tokens := make([]Token, 0, len(words))
+	for pos, word := range words {
+		tokens = append(tokens, Token{Text: word, Position: pos})
+		for _, sub := range t.subWords(word) {
+			tokens = append(tokens, Token{Text: sub, Position: pos})
+		}
+	}
+
+	return tokens
+}
+
+// Segment 返回分词调试信息：每个词的位置、是否保留及过滤原因
+func (t *Tokenizer) Segment(text string) []TokenInfo {
+	words := t.jieba.Tokenize(text, gojieba.DefaultMode, true)
+
+	infos := make([]TokenInfo, 0, len(words))
+	for _, word := range words {
+		if strings.TrimSpace(word.Str) == "" {
+			continue
+		}
+		info := TokenInfo{
+			Word:  word.Str,
+			Start: utf8.RuneCountInString(text[:word.Start]),
+			End:   utf8.RuneCountInString(text[:word.End]),
+		}
+		info.Reason = t.filterReason(strings.TrimSpace(word.Str))
+		info.Kept = info.Reason == ""
+		if info.Kept {
+			info.SubWords = t.subWords(word.Str)
+		}
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// Fingerprint 分词器指纹（词典和停用词的哈希，用于判断索引快照是否可复用）
+func (t *Tokenizer) Fingerprint() string {
+	return t.fingerprint
+}
+
+// Stats 分词器统计信息
+func (t *Tokenizer) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"user_words": t.userWords,
+		"stop_words": len(t.stopWords),
+	}
+}
+
+// filterReason 词被过滤的原因（保留返回空字符串）
+func (t *Tokenizer) filterReason(word string) string {
+	switch {
+	case word == "":
+		return "empty"
+	case t.stopWords[word]:
+		return "stopword"
+	case len(word) <= 1:
+		return "too_short"
+	case isPunctuation(word):
+		return "punctuation"
+	}
+	return ""
+}
+
+// subWords 长词在词典中的子词（不含长词本身和被过滤的词）
+func (t *Tokenizer) subWords(word string) []string {
+	if utf8.RuneCountInString(word) < minSubWordRunes {
+		return nil
+	}
+
+	subs := make([]string, 0)
+	seen := map[string]bool{word: true}
+	for _, sub := range t.jieba.CutForSearch(word, false) {
+		if !seen[sub] && t.filterReason(sub) == "" {
+			seen[sub] = true
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// hashFile 将文件内容写入哈希
+func hashFile(hash io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(hash, file)
+	return err
+}
+
+// isPunctuation 判断是否是标点符号
+// 只有整个字符串都是标点符号才返回true，只要有一个有效字符就保留
+func isPunctuation(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	hasValidChar := false
+	for _, r := range s {
+		isAlpha := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		isDigit := r >= '0' && r <= '9'
+		isChinese := r >= 0x4e00 && r <= 0x9fa5
+
+		if isAlpha || isDigit || isChinese {
+			hasValidChar = true
+		}
+	}
+
+	return !hasValidChar // 没有任何有效字符才算标点
+}
@@ -2,8 +2,9 @@ package retrieval
 
 import (
 	"context"
-	"crypto/md5"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -11,46 +12,61 @@ import (
 	"cookrag-go/internal/observability"
 	"cookrag-go/pkg/ml/embedding"
 	"cookrag-go/pkg/storage/cache"
-	"cookrag-go/pkg/storage/milvus"
+	"cookrag-go/pkg/storage/vectorstore"
 )
 
+// docIDMetadataKey 元数据中保存调用方文档ID的字段名（BM25 / Milvus / Neo4j 共用）
+const docIDMetadataKey = vectorstore.DocIDMetadataKey
+
+// ErrNoVectorStore 未配置向量存储
+var ErrNoVectorStore = errors.New("vector store is not configured")
+
+// ErrVectorsUnavailable 向量存储不支持按ID读取向量
+var ErrVectorsUnavailable = errors.New("vector store does not support fetching vectors")
+
 // VectorRetrieverConfig 向量检索配置
 type VectorRetrieverConfig struct {
-	CollectionName string // Milvus集合名称
-	VectorField    string // 向量字段名
-	TextField      string // 文本字段名
-	MetadataField  string // 元数据字段名
-	TopK           int    // 返回结果数量
-	UseCache       bool   // 是否使用缓存
-	CacheTTL       time.Duration // 缓存过期时间
+	CollectionName      string        // 向量集合名称
+	TopK                int           // 返回结果数量
+	UseCache            bool          // 是否使用缓存
+	CacheTTL            time.Duration // 缓存过期时间
+	SentenceHighlight   bool          // 是否标出与查询最相似的句子并作为摘要（每次未命中缓存的检索额外一次批量Embedding，默认关闭）
+	MaxSnippetSentences int           // 每个文档参与比较的最多句子数
+	GroupByParent       bool          // 索引的是分块时，按原文档聚合命中（同一菜谱只返回分数最高的分块）
+	GroupFetchFactor    int           // 聚合前多取 TopK 的倍数（同一菜谱的多个分块会占用名额）
+	EmbeddingCacheTTL   time.Duration // 查询向量的缓存时间（UseCache 时生效，同一查询不重复调用 Embedding）
+	BatchConcurrency    int           // 批量检索时不支持一次搜索多个向量的后端按查询并发搜索的最大并发数
 }
 
 // DefaultVectorRetrieverConfig 默认配置
 func DefaultVectorRetrieverConfig() *VectorRetrieverConfig {
 	return &VectorRetrieverConfig{
-		CollectionName: "cookrag_documents",
-		VectorField:    "vector",
-		TextField:      "text",
-		MetadataField:  "metadata",
-		TopK:           10,
-		UseCache:       true,
-		CacheTTL:       5 * time.Minute,
+		CollectionName:      "cookrag_documents",
+		TopK:                10,
+		UseCache:            true,
+		CacheTTL:            5 * time.Minute,
+		SentenceHighlight:   false,
+		MaxSnippetSentences: 32,
+		GroupFetchFactor:    defaultGroupFetchFactor,
+		EmbeddingCacheTTL:   24 * time.Hour,
+		BatchConcurrency:    4,
 	}
 }
 
 // VectorRetriever 向量检索器
 type VectorRetriever struct {
-	config          *VectorRetrieverConfig
+	config            *VectorRetrieverConfig
 	embeddingProvider embedding.Provider
-	milvusClient    *milvus.Client
-	cache           cache.Cache
+	store             vectorstore.Store // 向量存储（Milvus 或进程内存储，可为nil）
+	cache             cache.Cache
+	diversifier       *MMRReranker // MMR 多样化（可为nil，见 SetDiversifier）
 }
 
 // NewVectorRetriever 创建向量检索器
 func NewVectorRetriever(
 	config *VectorRetrieverConfig,
 	embeddingProvider embedding.Provider,
-	milvusClient *milvus.Client,
+	store vectorstore.Store,
 	cacheClient cache.Cache,
 ) *VectorRetriever {
 	if config == nil {
@@ -60,25 +76,38 @@ func NewVectorRetriever(
 	return &VectorRetriever{
 		config:          config,
 		embeddingProvider: embeddingProvider,
-		milvusClient:    milvusClient,
+		store:           store,
 		cache:           cacheClient,
 	}
 }
 
-// Retrieve 向量检索
+// SetDiversifier 设置 MMR 多样化：多取候选后按 MMR 选出 TopK，代替按分数截断
+func (r *VectorRetriever) SetDiversifier(diversifier *MMRReranker) {
+	r.diversifier = diversifier
+}
+
+// Retrieve 向量检索（ctx 中有过滤条件时只搜索满足条件的记录，见 WithFilter）
 func (r *VectorRetriever) Retrieve(ctx context.Context, query string) (*models.RetrievalResult, error) {
+	filter := FilterFromContext(ctx)
+
 	// 创建链路追踪 span
 	span := observability.GlobalTracer.StartSpan(ctx, "vector_retrieve", map[string]interface{}{
-		"query": query,
-		"top_k": r.config.TopK,
+		"query":  query,
+		"top_k":  r.config.TopK,
+		"filter": filter.String(),
 	})
 	defer span.End()
 
 	startTime := time.Now()
 
+	if r.store == nil {
+		span.SetError(ErrNoVectorStore)
+		return nil, ErrNoVectorStore
+	}
+
 	// 1. 先检查缓存（在Embedding之前，避免不必要的token消耗）
 	if r.config.UseCache && r.cache != nil {
-		cacheKey := r.getCacheKey(query)
+		cacheKey := r.getCacheKey(query, filter)
 		var cachedResult models.RetrievalResult
 		cacheCheckStart := time.Now()
 		if err := r.cache.Get(ctx, cacheKey, &cachedResult); err == nil {
@@ -98,7 +127,7 @@ func (r *VectorRetriever) Retrieve(ctx context.Context, query string) (*models.R
 		"query": query,
 	})
 	embeddingStart := time.Now()
-	queryEmbedding, err := r.embeddingProvider.Embed(ctx, query)
+	queryEmbedding, err := r.embedQuery(ctx, query)
 	embeddingSpan.AddMetadata("duration_ms", float64(time.Since(embeddingStart).Milliseconds()))
 	if err != nil {
 		embeddingSpan.SetError(err)
@@ -109,63 +138,37 @@ func (r *VectorRetriever) Retrieve(ctx context.Context, query string) (*models.R
 	embeddingSpan.End()
 
 	// 3. 执行向量搜索（创建子 span）
-	log.Infof("🔍 Searching in Milvus collection: %s", r.config.CollectionName)
-	searchSpan := observability.GlobalTracer.StartSpan(ctx, "milvus_search", map[string]interface{}{
+	log.Infof("🔍 Searching in vector collection: %s", r.config.CollectionName)
+	searchSpan := observability.GlobalTracer.StartSpan(ctx, "vector_search", map[string]interface{}{
 		"collection": r.config.CollectionName,
-		"top_k": r.config.TopK,
+		"top_k": r.searchTopK(),
 	})
 	searchStart := time.Now()
-	searchResults, err := r.milvusClient.Search(
+	searchResults, err := r.store.Search(
 		ctx,
 		r.config.CollectionName,
 		[][]float32{queryEmbedding},
-		r.config.VectorField,
-		[]string{r.config.TextField, r.config.MetadataField},
-		r.config.TopK,
+		r.searchTopK(),
+		filter.Conditions(),
 	)
 	searchSpan.AddMetadata("duration_ms", float64(time.Since(searchStart).Milliseconds()))
 	if err != nil {
 		searchSpan.SetError(err)
 		searchSpan.End()
 		span.SetError(err)
-		return nil, fmt.Errorf("milvus search failed: %w", err)
+		return nil, fmt.Errorf("vector search failed: %w", err)
 	}
 	searchSpan.End()
 
-	// 4. 转换结果
-	documents := make([]models.Document, 0, len(searchResults))
-	for _, result := range searchResults {
-		// 提取文本（用于生成唯一 ID 的降级方案）
-		var textContent string
-		if text, ok := result.Fields[r.config.TextField].(string); ok {
-			textContent = text
-		}
-
-		// 生成唯一 ID
-		var docID string
-		if result.ID != 0 {
-			// 正常情况：使用 Milvus 返回的 ID
-			docID = fmt.Sprintf("doc_%d", result.ID)
-		} else {
-			// 降级方案：使用内容哈希作为 ID（处理旧数据）
-			hash := md5.Sum([]byte(textContent))
-			docID = fmt.Sprintf("doc_%x", hash[:8])
-			log.Warnf("⚠️  Milvus returned ID=0, using content hash as ID: %s", docID)
-		}
-
-		doc := models.Document{
-			ID:    docID,
-			Score: result.Score,
-		}
-
-		// 提取文本和元数据
-		doc.Content = textContent
-
-		if metadata, ok := result.Fields[r.config.MetadataField].(map[string]interface{}); ok {
-			doc.Metadata = metadata
-		}
+	// 4. 转换结果（文档ID由向量存储还原为索引时的调用方文档ID，索引分块时按原文档聚合）
+	documents := make([]models.Document, 0)
+	if len(searchResults) > 0 {
+		documents = r.selectDocuments(ctx, toDocuments(searchResults[0]))
+	}
 
-		documents = append(documents, doc)
+	// 标出与查询最相似的句子（失败不影响检索结果）
+	if r.config.SentenceHighlight {
+		r.highlightSentences(ctx, queryEmbedding, documents)
 	}
 
 	result := &models.RetrievalResult{
@@ -176,12 +179,7 @@ func (r *VectorRetriever) Retrieve(ctx context.Context, query string) (*models.R
 	}
 
 	// 4. 缓存结果
-	if r.config.UseCache && r.cache != nil {
-		cacheKey := r.getCacheKey(query)
-		if err := r.cache.Set(ctx, cacheKey, result, r.config.CacheTTL); err != nil {
-			log.Warnf("Failed to cache result: %v", err)
-		}
-	}
+	r.cacheResult(ctx, query, filter, result)
 
 	span.AddMetadata("result_count", len(documents))
 	span.AddMetadata("latency_ms", result.Latency)
@@ -191,80 +189,250 @@ func (r *VectorRetriever) Retrieve(ctx context.Context, query string) (*models.R
 	return result, nil
 }
 
-// RetrieveBatch 批量向量检索
+// RetrieveBatch 批量向量检索（过滤条件同 Retrieve），结果与 queries 一一对应（相同的查询只检索一次）
+// 每个查询先查结果缓存；未命中的查询复用已缓存的查询向量，其余一次批量 Embedding。
+// Milvus 一次请求搜索全部查询向量并按查询分组返回，其他后端按查询并发搜索（最多 BatchConcurrency 个）
 func (r *VectorRetriever) RetrieveBatch(ctx context.Context, queries []string) ([]*models.RetrievalResult, error) {
+	filter := FilterFromContext(ctx)
+
+	span := observability.GlobalTracer.StartSpan(ctx, "vector_retrieve_batch", map[string]interface{}{
+		"queries": len(queries),
+		"top_k":   r.config.TopK,
+		"filter":  filter.String(),
+	})
+	defer span.End()
+
 	startTime := time.Now()
 
-	log.Infof("🔤 Batch embedding %d queries", len(queries))
+	if r.store == nil {
+		span.SetError(ErrNoVectorStore)
+		return nil, ErrNoVectorStore
+	}
 
-	// 批量生成查询向量
-	queryEmbeddings, err := r.embeddingProvider.EmbedBatch(ctx, queries)
-	if err != nil {
-		return nil, fmt.Errorf("failed to embed queries: %w", err)
+	// 1. 去重后逐个查询结果缓存
+	resolved := make(map[string]*models.RetrievalResult, len(queries))
+	var misses []string
+	for _, query := range queries {
+		if _, seen := resolved[query]; seen {
+			continue
+		}
+		resolved[query] = r.cachedResult(ctx, query, filter)
+		if resolved[query] == nil {
+			misses = append(misses, query)
+		}
 	}
+	span.AddMetadata("cache_hits", len(resolved)-len(misses))
+
+	if len(misses) > 0 {
+		// 2. 生成查询向量（已缓存的查询向量直接复用）
+		log.Infof("🔤 Batch embedding %d queries (%d cached results)", len(misses), len(resolved)-len(misses))
+		queryEmbeddings, err := r.embedQueries(ctx, misses)
+		if err != nil {
+			span.SetError(err)
+			return nil, fmt.Errorf("failed to embed queries: %w", err)
+		}
 
-	// 批量搜索
-	searchResults, err := r.milvusClient.Search(
-		ctx,
-		r.config.CollectionName,
-		queryEmbeddings,
-		r.config.VectorField,
-		[]string{r.config.TextField, r.config.MetadataField},
-		r.config.TopK,
-	)
+		// 3. 批量搜索（每个查询向量一组结果）
+		searchResults, err := r.searchBatch(ctx, queryEmbeddings, filter)
+		if err != nil {
+			span.SetError(err)
+			return nil, fmt.Errorf("vector batch search failed: %w", err)
+		}
 
-	if err != nil {
-		return nil, fmt.Errorf("milvus batch search failed: %w", err)
+		// 4. 逐个查询聚合、截断并缓存
+		for i, query := range misses {
+			documents := r.selectDocuments(ctx, toDocuments(searchResults[i]))
+			if r.config.SentenceHighlight {
+				r.highlightSentences(ctx, queryEmbeddings[i], documents)
+			}
+
+			result := &models.RetrievalResult{
+				Documents: documents,
+				Strategy:  "vector",
+				Query:     query,
+			}
+			r.cacheResult(ctx, query, filter, result)
+			resolved[query] = result
+		}
 	}
 
-	// 按查询分组结果
-	resultsPerQuery := len(searchResults) / len(queries)
+	latency := float64(time.Since(startTime).Milliseconds())
 	results := make([]*models.RetrievalResult, 0, len(queries))
+	for _, query := range queries {
+		result := *resolved[query]
+		result.Strategy = "vector_batch"
+		result.Latency = latency
+		results = append(results, &result)
+	}
 
-	for i := 0; i < len(queries); i++ {
-		start := i * resultsPerQuery
-		end := start + resultsPerQuery
+	span.AddMetadata("latency_ms", latency)
+	log.Infof("✅ Batch vector retrieval completed: %d queries (%d searched) in %.2fms",
+		len(results), len(misses), latency)
 
-		documents := make([]models.Document, 0, resultsPerQuery)
-		for j := start; j < end; j++ {
-			if j >= len(searchResults) {
-				break
-			}
+	return results, nil
+}
 
-			result := searchResults[j]
-			doc := models.Document{
-				ID:    fmt.Sprintf("doc_%d", result.ID),
-				Score: result.Score,
-			}
+// searchBatch 搜索多个查询向量，返回与 vectors 一一对应的结果
+// 支持一次搜索多个向量的后端（Milvus）只发一次请求，其他后端按查询并发搜索
+func (r *VectorRetriever) searchBatch(ctx context.Context, vectors [][]float32, filter *Filter) ([][]vectorstore.SearchResult, error) {
+	if batcher, ok := r.store.(vectorstore.BatchSearcher); ok && batcher.NativeBatchSearch() {
+		grouped, err := r.store.Search(ctx, r.config.CollectionName, vectors, r.searchTopK(), filter.Conditions())
+		if err != nil {
+			return nil, err
+		}
+		if len(grouped) != len(vectors) {
+			return nil, fmt.Errorf("vector store returned %d result sets for %d queries", len(grouped), len(vectors))
+		}
+		return grouped, nil
+	}
 
-			if text, ok := result.Fields[r.config.TextField].(string); ok {
-				doc.Content = text
+	grouped := make([][]vectorstore.SearchResult, len(vectors))
+	errs := make([]error, len(vectors))
+	sem := make(chan struct{}, max(r.config.BatchConcurrency, 1))
+	var wg sync.WaitGroup
+	for i, vector := range vectors {
+		wg.Add(1)
+		go func(i int, vector []float32) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results, err := r.store.Search(ctx, r.config.CollectionName, [][]float32{vector}, r.searchTopK(), filter.Conditions())
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			if len(results) > 0 {
+				grouped[i] = results[0]
 			}
+		}(i, vector)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return grouped, nil
+}
+
+// embedQuery 生成单个查询的向量（优先使用缓存）
+func (r *VectorRetriever) embedQuery(ctx context.Context, query string) ([]float32, error) {
+	embeddings, err := r.embedQueries(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
 
-			if metadata, ok := result.Fields[r.config.MetadataField].(map[string]interface{}); ok {
-				doc.Metadata = metadata
+// embedQueries 生成查询向量，结果与 queries 一一对应
+// 开启缓存时先读取已缓存的查询向量，只对未命中的查询调用 Embedding，并写回缓存
+func (r *VectorRetriever) embedQueries(ctx context.Context, queries []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(queries))
+	useCache := r.config.UseCache && r.cache != nil
+
+	var missing []int
+	for i, query := range queries {
+		if useCache {
+			var cached []float32
+			if err := r.cache.Get(ctx, r.getEmbeddingCacheKey(query), &cached); err == nil && len(cached) > 0 {
+				embeddings[i] = cached
+				continue
 			}
+		}
+		missing = append(missing, i)
+	}
+	if len(missing) == 0 {
+		return embeddings, nil
+	}
+
+	texts := make([]string, 0, len(missing))
+	for _, i := range missing {
+		texts = append(texts, queries[i])
+	}
 
-			documents = append(documents, doc)
+	var generated [][]float32
+	if len(texts) == 1 {
+		embedding, err := r.embeddingProvider.Embed(ctx, texts[0])
+		if err != nil {
+			return nil, err
+		}
+		generated = [][]float32{embedding}
+	} else {
+		var err error
+		generated, err = r.embeddingProvider.EmbedBatch(ctx, texts)
+		if err != nil {
+			return nil, err
+		}
+		if len(generated) != len(texts) {
+			return nil, fmt.Errorf("embedding provider returned %d vectors for %d queries", len(generated), len(texts))
 		}
+	}
 
-		results = append(results, &models.RetrievalResult{
-			Documents: documents,
-			Strategy:  "vector_batch",
-			Query:     queries[i],
-			Latency:   float64(time.Since(startTime).Milliseconds()),
-		})
+	for j, i := range missing {
+		embeddings[i] = generated[j]
+		if useCache {
+			if err := r.cache.Set(ctx, r.getEmbeddingCacheKey(queries[i]), generated[j], r.config.EmbeddingCacheTTL); err != nil {
+				log.Warnf("Failed to cache query embedding: %v", err)
+			}
+		}
 	}
+	return embeddings, nil
+}
 
-	log.Infof("✅ Batch vector retrieval completed: %d queries, avg %.2fms",
-		len(results), float64(time.Since(startTime).Milliseconds())/float64(len(queries)))
+// cachedResult 读取查询的缓存结果（未开启缓存或未命中时返回 nil）
+func (r *VectorRetriever) cachedResult(ctx context.Context, query string, filter *Filter) *models.RetrievalResult {
+	if !r.config.UseCache || r.cache == nil {
+		return nil
+	}
+	var cachedResult models.RetrievalResult
+	if err := r.cache.Get(ctx, r.getCacheKey(query, filter), &cachedResult); err != nil {
+		return nil
+	}
+	return &cachedResult
+}
 
-	return results, nil
+// cacheResult 缓存查询结果
+func (r *VectorRetriever) cacheResult(ctx context.Context, query string, filter *Filter, result *models.RetrievalResult) {
+	if !r.config.UseCache || r.cache == nil {
+		return
+	}
+	if err := r.cache.Set(ctx, r.getCacheKey(query, filter), result, r.config.CacheTTL); err != nil {
+		log.Warnf("Failed to cache result: %v", err)
+	}
 }
 
-// IndexDocuments 索引文档
+// EnsureCollection 确保向量集合存在（按 Embedding 维度创建）
+func (r *VectorRetriever) EnsureCollection(ctx context.Context) error {
+	if r.store == nil {
+		return ErrNoVectorStore
+	}
+	return r.store.CreateCollection(ctx, r.config.CollectionName, r.embeddingProvider.Dimension())
+}
+
+// Count 向量集合中的记录数
+func (r *VectorRetriever) Count(ctx context.Context) (int64, error) {
+	if r.store == nil {
+		return 0, ErrNoVectorStore
+	}
+
+	stats, err := r.store.Stats(ctx, r.config.CollectionName)
+	if err != nil {
+		return 0, err
+	}
+	return stats.Count, nil
+}
+
+// IndexDocuments 索引文档（文档ID相同的记录会被替换）
 func (r *VectorRetriever) IndexDocuments(ctx context.Context, documents []models.Document) error {
-	log.Infof("📝 Indexing %d documents to Milvus", len(documents))
+	if r.store == nil {
+		return ErrNoVectorStore
+	}
+
+	log.Infof("📝 Indexing %d documents to vector store", len(documents))
+
+	if err := r.EnsureCollection(ctx); err != nil {
+		return fmt.Errorf("failed to create collection: %w", err)
+	}
 
 	// 批量生成文档向量
 	texts := make([]string, len(documents))
@@ -277,67 +445,219 @@ func (r *VectorRetriever) IndexDocuments(ctx context.Context, documents []models
 		return fmt.Errorf("failed to embed documents: %w", err)
 	}
 
-	// 准备Milvus数据
-	ids := make([]int64, len(documents))
-	metadataList := make([]map[string]interface{}, len(documents))
+	records := make([]vectorstore.Record, len(documents))
+	for i, doc := range documents {
+		// 没有ID的文档由来源路径和分块序号生成稳定ID，重复索引时替换而不是新增
+		docID := StableDocumentID(doc)
+
+		// 复制元数据并写入调用方文档ID，检索时据此还原 Document.ID
+		metadata := make(map[string]interface{}, len(doc.Metadata)+1)
+		for k, v := range doc.Metadata {
+			metadata[k] = v
+		}
+		metadata[docIDMetadataKey] = docID
 
-	// 使用时间戳 + 索引确保 ID 唯一
-	// 例: 1737585600123 * 1000 + 0 = 1737585600123000
-	baseTimestamp := time.Now().UnixNano() / 1000000 // 毫秒级时间戳
+		records[i] = vectorstore.Record{
+			ID:       docID,
+			Vector:   embeddings[i],
+			Text:     doc.Content,
+			Metadata: metadata,
+		}
+	}
+
+	if err := r.store.Upsert(ctx, r.config.CollectionName, records); err != nil {
+		return fmt.Errorf("failed to upsert documents: %w", err)
+	}
+
+	log.Infof("✅ Indexed %d documents successfully", len(documents))
+	return nil
+}
+
+// DeleteDocuments 按文档ID删除向量记录
+func (r *VectorRetriever) DeleteDocuments(ctx context.Context, ids ...string) error {
+	if r.store == nil {
+		return ErrNoVectorStore
+	}
+
+	if err := r.store.Delete(ctx, r.config.CollectionName, ids); err != nil {
+		return fmt.Errorf("failed to delete documents: %w", err)
+	}
+	return nil
+}
+
+// highlightSentences 对每个文档找出与查询向量最相似的句子，标为命中并作为摘要
+// 所有文档的句子合并为一次批量Embedding；Embedding失败时只记录警告
+func (r *VectorRetriever) highlightSentences(ctx context.Context, queryEmbedding []float32, documents []models.Document) {
+	type sentenceRef struct {
+		DocIndex int
+		Span     textSpan
+	}
+
+	refs := make([]sentenceRef, 0)
+	texts := make([]string, 0)
 	for i, doc := range documents {
-		ids[i] = baseTimestamp + int64(i)
-		metadataList[i] = doc.Metadata
+		runes := []rune(doc.Content)
+		count := 0
+		for _, span := range splitSentences(doc.Content) {
+			if span.End-span.Start < 4 { // 太短的句子（标题符号、"步骤"等）没有比较意义
+				continue
+			}
+			if r.config.MaxSnippetSentences > 0 && count >= r.config.MaxSnippetSentences {
+				break
+			}
+			refs = append(refs, sentenceRef{DocIndex: i, Span: span})
+			texts = append(texts, string(runes[span.Start:span.End]))
+			count++
+		}
+	}
+	if len(texts) == 0 {
+		return
 	}
 
-	log.Infof("📝 Generated unique IDs: base=%d, count=%d", baseTimestamp, len(ids))
+	span := observability.GlobalTracer.StartSpan(ctx, "sentence_highlight", map[string]interface{}{
+		"sentence_count": len(texts),
+	})
+	defer span.End()
 
-	// 批量插入
-	err = r.milvusClient.Insert(
-		ctx,
-		r.config.CollectionName,
-		ids,
-		embeddings,
-		texts,
-		metadataList,
-	)
+	embeddings, err := r.embeddingProvider.EmbedBatch(ctx, texts)
+	if err != nil {
+		span.SetError(err)
+		log.Warnf("⚠️  Failed to embed sentences for highlighting: %v", err)
+		return
+	}
 
+	bestScores := make(map[int]float64)
+	bestSpans := make(map[int]textSpan)
+	for i, ref := range refs {
+		if i >= len(embeddings) {
+			break
+		}
+		score := cosineSimilarity(queryEmbedding, embeddings[i])
+		if best, ok := bestScores[ref.DocIndex]; !ok || score > best {
+			bestScores[ref.DocIndex] = score
+			bestSpans[ref.DocIndex] = ref.Span
+		}
+	}
+
+	for docIndex, best := range bestSpans {
+		doc := &documents[docIndex]
+		runes := []rune(doc.Content)
+		doc.Highlights = []models.Highlight{{
+			Start:  best.Start,
+			End:    best.End,
+			Text:   string(runes[best.Start:best.End]),
+			Source: HighlightSentence,
+		}}
+		doc.Snippet = clipSpan(runes, best, doc.Highlights, defaultSnippetRunes)
+	}
+}
+
+// DocumentVectors 读取文档的已存储向量（按原文档聚合的结果使用最相关分块的向量）
+// 向量存储不支持按ID读取向量时返回 ErrVectorsUnavailable
+func (r *VectorRetriever) DocumentVectors(ctx context.Context, documents []models.Document) (map[string][]float32, error) {
+	if r.store == nil {
+		return nil, ErrNoVectorStore
+	}
+	fetcher, ok := r.store.(vectorstore.VectorFetcher)
+	if !ok {
+		return nil, ErrVectorsUnavailable
+	}
+
+	ids := make([]string, 0, len(documents))
+	for _, doc := range documents {
+		ids = append(ids, vectorID(doc))
+	}
+	stored, err := fetcher.GetVectors(ctx, r.config.CollectionName, ids)
 	if err != nil {
-		return fmt.Errorf("failed to insert documents: %w", err)
+		return nil, fmt.Errorf("failed to get document vectors: %w", err)
 	}
 
-	// 刷新集合（将数据持久化到磁盘）
-	if err := r.milvusClient.Flush(ctx, r.config.CollectionName); err != nil {
-		return fmt.Errorf("failed to flush collection: %w", err)
+	vectors := make(map[string][]float32, len(stored))
+	for _, doc := range documents {
+		if vector, ok := stored[vectorID(doc)]; ok {
+			vectors[doc.ID] = vector
+		}
 	}
+	return vectors, nil
+}
 
-	log.Infof("✅ Flushed collection to disk")
+// vectorID 文档在向量存储中的记录ID
+func vectorID(doc models.Document) string {
+	if chunkID, ok := doc.Metadata[ChunkIDMetadataKey].(string); ok && chunkID != "" {
+		return chunkID
+	}
+	return doc.ID
+}
 
-	// Flush 后需要重新加载集合到内存（否则搜索不到数据）
-	if err := r.milvusClient.LoadCollection(ctx, r.config.CollectionName); err != nil {
-		return fmt.Errorf("failed to reload collection: %w", err)
+// searchTopK 向量搜索的返回数量（按原文档聚合或 MMR 多样化时多取，之后再选出 TopK）
+func (r *VectorRetriever) searchTopK() int {
+	topK := r.config.TopK
+	if r.config.GroupByParent && r.config.GroupFetchFactor > 1 {
+		topK *= r.config.GroupFetchFactor
+	}
+	if r.diversifier != nil {
+		topK *= r.diversifier.CandidateFactor()
 	}
+	return topK
+}
 
-	log.Infof("✅ Reloaded collection into memory")
+// selectDocuments 按原文档聚合分块命中，再选出 TopK（开启 MMR 时按 MMR 选择，否则按分数截断）
+func (r *VectorRetriever) selectDocuments(ctx context.Context, documents []models.Document) []models.Document {
+	if r.config.GroupByParent {
+		documents = GroupByParent(documents)
+	}
+	if r.diversifier != nil {
+		return r.diversifier.Rerank(ctx, documents, r.config.TopK)
+	}
+	if len(documents) > r.config.TopK {
+		documents = documents[:r.config.TopK]
+	}
+	return documents
+}
 
-	log.Infof("✅ Indexed %d documents successfully", len(documents))
-	return nil
+// toDocuments 向量搜索结果转换为文档
+func toDocuments(results []vectorstore.SearchResult) []models.Document {
+	documents := make([]models.Document, 0, len(results))
+	for _, result := range results {
+		documents = append(documents, models.Document{
+			ID:       result.ID,
+			Content:  result.Text,
+			Metadata: result.Metadata,
+			Score:    result.Score,
+		})
+	}
+	return documents
 }
 
-// getCacheKey 生成缓存key
-func (r *VectorRetriever) getCacheKey(query string) string {
-	return fmt.Sprintf("vector:%s", query)
+// getCacheKey 生成缓存key（不同过滤条件的结果分开缓存）
+func (r *VectorRetriever) getCacheKey(query string, filter *Filter) string {
+	if filter.IsEmpty() {
+		return fmt.Sprintf("vector:%s", query)
+	}
+	return fmt.Sprintf("vector:%s|%s", query, filter.String())
+}
+
+// getEmbeddingCacheKey 查询向量的缓存key（按维度区分，更换 Embedding 模型后不会读到旧向量）
+func (r *VectorRetriever) getEmbeddingCacheKey(query string) string {
+	return fmt.Sprintf("embedding:%d:%s", r.embeddingProvider.Dimension(), query)
 }
 
 // GetStats 获取检索器统计信息
 func (r *VectorRetriever) GetStats(ctx context.Context) (map[string]interface{}, error) {
-	stats, err := r.milvusClient.GetCollectionStats(ctx, r.config.CollectionName)
+	if r.store == nil {
+		return nil, ErrNoVectorStore
+	}
+
+	collectionStats, err := r.store.Stats(ctx, r.config.CollectionName)
 	if err != nil {
 		return nil, err
 	}
 
+	stats := collectionStats.ToMap()
 	stats["top_k"] = r.config.TopK
 	stats["use_cache"] = r.config.UseCache
-	stats["collection_name"] = r.config.CollectionName
+	stats["group_by_parent"] = r.config.GroupByParent
+	stats["mmr"] = r.diversifier != nil
 
 	return stats, nil
 }
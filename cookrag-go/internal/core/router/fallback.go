@@ -0,0 +1,192 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cookrag-go/internal/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// 检索策略
+const (
+	StrategyGraph  = "graph"
+	StrategyHybrid = "hybrid"
+	StrategyVector = "vector"
+	StrategyBM25   = "bm25"
+)
+
+// 回退原因
+const (
+	FallbackError    = "error"     // 检索出错（包括检索器未配置）
+	FallbackEmpty    = "empty"     // 没有结果（如图检索没有识别出实体）
+	FallbackLowScore = "low_score" // 最高分低于 MinTopScore
+)
+
+// DefaultFallbacks 默认回退顺序：图检索和向量检索失败时改用混合检索，最后退到只依赖本地索引的 BM25
+func DefaultFallbacks() map[string][]string {
+	return map[string][]string{
+		StrategyGraph:  {StrategyHybrid, StrategyBM25},
+		StrategyVector: {StrategyHybrid, StrategyBM25},
+		StrategyHybrid: {StrategyBM25},
+	}
+}
+
+// retrieveWithFallback 按回退顺序依次尝试：策略出错、没有结果或最高分低于下限时改用下一个策略
+// 都不可用时返回第一个低分结果，其次是空结果，全部出错时返回错误
+func (r *QueryRouter) retrieveWithFallback(
+	ctx context.Context,
+	query string,
+	analysis *models.QueryAnalysis,
+) (*models.RetrievalResult, []models.StrategyFallback, error) {
+	strategies := r.cascade(analysis.RecommendedStrategy)
+	if len(strategies) == 1 {
+		result, err := r.retrieveStrategy(ctx, strategies[0], query, analysis)
+		return result, nil, err
+	}
+
+	var fallbacks []models.StrategyFallback
+	var lowScoreResult, emptyResult *models.RetrievalResult
+	var errs []error
+	for i, strategy := range strategies {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		result, err := r.retrieveStrategy(ctx, strategy, query, analysis)
+		fallback := models.StrategyFallback{From: strategy}
+		switch {
+		case err != nil:
+			fallback.Reason = FallbackError
+			fallback.Detail = err.Error()
+			errs = append(errs, fmt.Errorf("%s: %w", strategy, err))
+		case len(result.Documents) == 0:
+			fallback.Reason = FallbackEmpty
+			if emptyResult == nil {
+				emptyResult = result
+			}
+		default:
+			minScore, ok := r.config.MinTopScore[strategy]
+			top := topScore(result.Documents)
+			if !ok || float64(top) >= minScore {
+				return result, fallbacks, nil
+			}
+			fallback.Reason = FallbackLowScore
+			fallback.TopScore = top
+			if lowScoreResult == nil {
+				lowScoreResult = result
+			}
+		}
+
+		if i+1 < len(strategies) {
+			fallback.To = strategies[i+1]
+			log.Warnf("⚠️  %s retrieval unusable (%s), falling back to %s", strategy, fallback.Reason, fallback.To)
+		} else {
+			log.Warnf("⚠️  %s retrieval unusable (%s), no more fallbacks", strategy, fallback.Reason)
+		}
+		fallbacks = append(fallbacks, fallback)
+	}
+
+	if lowScoreResult != nil {
+		return lowScoreResult, fallbacks, nil
+	}
+	if emptyResult != nil {
+		return emptyResult, fallbacks, nil
+	}
+	return nil, fallbacks, fmt.Errorf("all strategies failed: %w", errors.Join(errs...))
+}
+
+// cascade 主策略及其回退策略（去重）
+func (r *QueryRouter) cascade(strategy string) []string {
+	strategies := []string{strategy}
+	seen := map[string]bool{strategy: true}
+	for _, fallback := range r.config.Fallbacks[strategy] {
+		if !seen[fallback] {
+			seen[fallback] = true
+			strategies = append(strategies, fallback)
+		}
+	}
+	return strategies
+}
+
+// retrieveStrategy 用指定策略检索
+func (r *QueryRouter) retrieveStrategy(
+	ctx context.Context,
+	strategy string,
+	query string,
+	analysis *models.QueryAnalysis,
+) (*models.RetrievalResult, error) {
+	switch strategy {
+	case StrategyGraph:
+		if r.graphRetriever == nil {
+			return nil, errRetrieverUnavailable(strategy)
+		}
+		log.Infof("🕸️  Routing to Graph RAG")
+		return r.graphRetriever.Retrieve(ctx, query)
+
+	case StrategyHybrid:
+		if r.hybridRetriever == nil {
+			return nil, errRetrieverUnavailable(strategy)
+		}
+		log.Infof("🔀 Routing to Hybrid Retrieval")
+		return r.hybridRetriever.AdaptiveRetrieval(ctx, query, analysis.Complexity)
+
+	case StrategyVector:
+		if r.vectorRetriever == nil {
+			return nil, errRetrieverUnavailable(strategy)
+		}
+		log.Infof("🔍 Routing to Vector Retrieval")
+		return r.vectorRetriever.Retrieve(ctx, query)
+
+	case StrategyBM25:
+		if r.bm25Retriever == nil {
+			return nil, errRetrieverUnavailable(strategy)
+		}
+		log.Infof("📝 Routing to BM25 Retrieval")
+		startTime := time.Now()
+		documents, err := r.bm25Retriever.Retrieve(ctx, query, r.config.BM25TopK)
+		if err != nil {
+			return nil, err
+		}
+		return &models.RetrievalResult{
+			Documents: documents,
+			Strategy:  StrategyBM25,
+			Query:     query,
+			Latency:   float64(time.Since(startTime).Milliseconds()),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported strategy: %q", strategy)
+	}
+}
+
+// errRetrieverUnavailable 策略对应的检索器未配置
+func errRetrieverUnavailable(strategy string) error {
+	return fmt.Errorf("%s retriever is not configured", strategy)
+}
+
+// topScore 结果中的最高分
+func topScore(documents []models.Document) float32 {
+	top := documents[0].Score
+	for _, doc := range documents[1:] {
+		top = max(top, doc.Score)
+	}
+	return top
+}
+
+// describeFallbacks 回退记录的简短描述（写入链路追踪），如 "graph→hybrid: empty"
+func describeFallbacks(fallbacks []models.StrategyFallback) []string {
+	descriptions := make([]string, 0, len(fallbacks))
+	for _, fallback := range fallbacks {
+		to := fallback.To
+		if to == "" {
+			to = "none"
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s→%s: %s", fallback.From, to, fallback.Reason))
+	}
+	return descriptions
+}
@@ -7,6 +7,8 @@ import (
 	"strings"
 	"time"
 
+	"cookrag-go/internal/core/fuzzy"
+	"cookrag-go/internal/core/rerank"
 	"cookrag-go/internal/core/retrieval"
 	"cookrag-go/internal/models"
 	"cookrag-go/internal/observability"
@@ -20,6 +22,10 @@ type QueryRouterConfig struct {
 	EntityMinCount      int     // 实体最小数量
 	EnableGraphRAG      bool    // 是否启用图RAG
 	EnableHybrid        bool    // 是否启用混合检索
+	// 各策略的回退顺序（如 graph → hybrid → bm25）：出错、没有结果或最高分过低时依次尝试下一个
+	Fallbacks   map[string][]string
+	MinTopScore map[string]float64 // 各策略结果的最高分下限（各策略分数量纲不同，未配置的策略不检查）
+	BM25TopK    int                // BM25 作为回退策略时的返回数量
 }
 
 // DefaultQueryRouterConfig 默认配置
@@ -29,6 +35,9 @@ func DefaultQueryRouterConfig() *QueryRouterConfig {
 		EntityMinCount:      1,
 		EnableGraphRAG:      true,
 		EnableHybrid:        true,
+		Fallbacks:           DefaultFallbacks(),
+		MinTopScore:         map[string]float64{},
+		BM25TopK:            10,
 	}
 }
 
@@ -39,6 +48,10 @@ type QueryRouter struct {
 	bm25Retriever   *retrieval.BM25Retriever
 	graphRetriever  *retrieval.GraphRetriever
 	hybridRetriever *retrieval.HybridRetriever
+	corrector       *fuzzy.Corrector                   // 拼音/错别字纠错（可选）
+	parentRetriever *retrieval.ParentDocumentRetriever // 父文档检索：分块命中合并为完整菜谱（可选）
+	diversifier     *retrieval.MMRReranker             // MMR 多样化：减少同一道菜的重复变体（可选）
+	reranker        *rerank.Stage                      // 重排：前 N 个候选重新打分后保留 top-k（可选）
 }
 
 // NewQueryRouter 创建查询路由器
@@ -62,6 +75,26 @@ func NewQueryRouter(
 	}
 }
 
+// SetCorrector 设置查询纠错器，路由前先纠正拼音、同音字和错别字
+func (r *QueryRouter) SetCorrector(corrector *fuzzy.Corrector) {
+	r.corrector = corrector
+}
+
+// SetParentRetriever 设置父文档检索，检索结果中的分块按原菜谱合并并替换为完整菜谱
+func (r *QueryRouter) SetParentRetriever(parentRetriever *retrieval.ParentDocumentRetriever) {
+	r.parentRetriever = parentRetriever
+}
+
+// SetDiversifier 设置 MMR 多样化，对各策略的检索结果统一做多样化重排（在父文档展开之前）
+func (r *QueryRouter) SetDiversifier(diversifier *retrieval.MMRReranker) {
+	r.diversifier = diversifier
+}
+
+// SetReranker 设置重排阶段，检索结果先重排再做 MMR 多样化和父文档展开
+func (r *QueryRouter) SetReranker(reranker *rerank.Stage) {
+	r.reranker = reranker
+}
+
 // Route 智能路由
 func (r *QueryRouter) Route(ctx context.Context, query string) (*models.RetrievalResult, error) {
 	// 创建链路追踪 span
@@ -74,9 +107,21 @@ func (r *QueryRouter) Route(ctx context.Context, query string) (*models.Retrieva
 
 	log.Infof("🚦 Routing query: %s", query)
 
+	// 纠错：hongshaorou → 红烧肉，宫宝鸡丁 → 宫保鸡丁
+	originalQuery := query
+	var corrections []models.QueryCorrection
+	if r.corrector != nil {
+		query, corrections = r.corrector.Correct(query)
+		if len(corrections) > 0 {
+			log.Infof("✏️  Query corrected: %s → %s", originalQuery, query)
+			span.AddMetadata("rewritten_query", query)
+			span.AddMetadata("correction_count", len(corrections))
+		}
+	}
+
 	// 分析查询
 	analysis := r.analyzeQuery(query)
-	log.Infof("📊 Query analysis: complexity=%.2f, entities=%d, strategy=%s",
+	log.Infof("📊 Query analysis: complexity=%.2f, relationship=%.2f, strategy=%s",
 		analysis.Complexity, analysis.RelationshipIntensity, analysis.RecommendedStrategy)
 
 	// 将分析结果添加到 span metadata
@@ -84,35 +129,45 @@ func (r *QueryRouter) Route(ctx context.Context, query string) (*models.Retrieva
 	span.AddMetadata("relationship_intensity", analysis.RelationshipIntensity)
 	span.AddMetadata("recommended_strategy", analysis.RecommendedStrategy)
 
-	// 根据分析结果路由到不同的检索器
-	var result *models.RetrievalResult
-	var err error
-
-	switch analysis.RecommendedStrategy {
-	case "graph":
-		log.Infof("🕸️  Routing to Graph RAG")
-		result, err = r.graphRetriever.Retrieve(ctx, query)
-
-	case "hybrid":
-		log.Infof("🔀 Routing to Hybrid Retrieval")
-		result, err = r.hybridRetriever.AdaptiveRetrieval(ctx, query, analysis.Complexity)
-
-	case "vector":
-		log.Infof("🔍 Routing to Vector Retrieval")
-		result, err = r.vectorRetriever.Retrieve(ctx, query)
-
-	default:
-		log.Infof("🔀 Routing to Hybrid (default)")
-		result, err = r.hybridRetriever.Retrieve(ctx, query)
+	// 根据分析结果路由到不同的检索器（不可用时按配置的顺序回退）
+	result, fallbacks, err := r.retrieveWithFallback(ctx, query, analysis)
+	if len(fallbacks) > 0 {
+		span.AddMetadata("fallbacks", describeFallbacks(fallbacks))
+		span.AddMetadata("fallback_count", len(fallbacks))
 	}
-
 	if err != nil {
 		span.SetError(err)
 		return nil, fmt.Errorf("retrieval failed: %w", err)
 	}
+	result.Fallbacks = fallbacks
+
+	// 重排：用（纠错后的）查询对前 N 个候选重新打分，保留 top-k
+	if r.reranker != nil {
+		var used string
+		result.Documents, used = r.reranker.Apply(ctx, query, result.Documents)
+		if used != "" {
+			span.AddMetadata("reranker", used)
+		}
+	}
+
+	// MMR 多样化：在命中的分块/文档上计算相似度，展开为完整菜谱之前完成选择
+	if r.diversifier != nil {
+		result.Documents = r.diversifier.Rerank(ctx, result.Documents, r.diversifier.TopK())
+		span.AddMetadata("mmr", true)
+	}
+
+	// 父文档模式：在分块上匹配，返回完整菜谱（按上下文长度裁剪章节）
+	if r.parentRetriever != nil {
+		result.Documents = r.parentRetriever.Expand(ctx, result.Documents)
+		span.AddMetadata("parent_document", true)
+	}
 
 	// 添加查询分析信息到结果
-	result.Query = query
+	result.Query = originalQuery
+	if len(corrections) > 0 {
+		result.RewrittenQuery = query
+		result.Corrections = corrections
+	}
 	result.Latency = float64(time.Since(startTime).Milliseconds())
 
 	// 将结果添加到 span metadata
@@ -289,18 +344,18 @@ func (r *QueryRouter) calculateConfidence(analysis *models.QueryAnalysis) float6
 func (r *QueryRouter) recommendStrategy(analysis *models.QueryAnalysis) string {
 	// 优先级1：图RAG（如果检测到强关系且启用）
 	if r.config.EnableGraphRAG && analysis.RelationshipIntensity > 0.6 {
-		return "graph"
+		return StrategyGraph
 	}
 
 	// 优先级2：混合检索（默认策略）
 	// 混合检索结合了向量检索的语义理解能力和BM25的关键词精确匹配
 	// RRF算法自动平衡两种检索结果，提供最佳的召回率和精确度
 	if r.config.EnableHybrid {
-		return "hybrid"
+		return StrategyHybrid
 	}
 
 	// 默认：向量检索
-	return "vector"
+	return StrategyVector
 }
 
 // BatchRoute 批量路由
@@ -331,6 +386,8 @@ func (r *QueryRouter) GetConfig() map[string]interface{} {
 		"entity_min_count":     r.config.EntityMinCount,
 		"enable_graph_rag":     r.config.EnableGraphRAG,
 		"enable_hybrid":        r.config.EnableHybrid,
+		"fallbacks":            r.config.Fallbacks,
+		"min_top_score":        r.config.MinTopScore,
 		"strategy":             "intelligent_routing",
 	}
 }
@@ -3,9 +3,11 @@ package kg
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/charmbracelet/log"
+	"cookrag-go/internal/synonym"
 	"cookrag-go/pkg/storage/neo4j"
 )
 
@@ -14,6 +16,7 @@ type GraphBuilder struct {
 	neo4jClient *neo4j.Client
 	extractor   *RecipeExtractor
 	stats       *BuildStats
+	synonyms    *synonym.Registry // 同义词表（食材别名统一到规范名）
 }
 
 // BuildStats 构建统计
@@ -34,6 +37,11 @@ func NewGraphBuilder(neo4jClient *neo4j.Client) *GraphBuilder {
 	}
 }
 
+// SetSynonyms 设置同义词表，构建时食材别名会合并到同一个规范名节点
+func (b *GraphBuilder) SetSynonyms(registry *synonym.Registry) {
+	b.synonyms = registry
+}
+
 // BuildFromDocuments 从文档构建知识图谱
 func (b *GraphBuilder) BuildFromDocuments(ctx context.Context, documents []Document) (*BuildStats, error) {
 	startTime := time.Now()
@@ -47,6 +55,7 @@ func (b *GraphBuilder) BuildFromDocuments(ctx context.Context, documents []Docum
 
 	totalEntities := make(map[string]*Entity)
 	totalRelations := make([]Relation, 0)
+	ingredientAliases := make(map[string]map[string]bool) // 规范食材ID -> 合并进来的别名
 
 	// 1. 提取所有文档的实体和关系
 	for i, doc := range documents {
@@ -60,6 +69,18 @@ func (b *GraphBuilder) BuildFromDocuments(ctx context.Context, documents []Docum
 			doc.DishName,
 		)
 
+		// 菜品节点记录文档ID，图检索命中后可回查完整菜谱（与 BM25 / Milvus 的ID一致）
+		if doc.ID != "" {
+			for i := range extracted.Entities {
+				if extracted.Entities[i].Type == EntityDish {
+					extracted.Entities[i].Properties["doc_id"] = doc.ID
+				}
+			}
+		}
+
+		// 食材别名统一到规范名（西红柿 → 番茄），关系指向同一个 Ingredient 节点
+		b.canonicalizeIngredients(extracted, ingredientAliases)
+
 		// 合并实体（去重）
 		for _, entity := range extracted.Entities {
 			key := fmt.Sprintf("%s_%s", entity.Type, entity.Name)
@@ -81,6 +102,13 @@ func (b *GraphBuilder) BuildFromDocuments(ctx context.Context, documents []Docum
 	log.Infof("🔨 Creating %d unique entities...", len(totalEntities))
 	entityIDs := make(map[string]string)  // entity.ID -> Neo4j node ID
 	for _, entity := range totalEntities {
+		if aliases, ok := ingredientAliases[entity.ID]; ok && entity.Type == EntityIngredient {
+			if entity.Properties == nil {
+				entity.Properties = make(map[string]interface{})
+			}
+			entity.Properties["aliases"] = sortedKeys(aliases)
+		}
+
 		nodeID, err := b.neo4jClient.CreateNode(ctx, string(entity.Type), entity.Name, entity.Properties)
 		if err != nil {
 			log.Warnf("⚠️  Failed to create node %s: %v", entity.Name, err)
@@ -133,6 +161,52 @@ func (b *GraphBuilder) BuildFromDocuments(ctx context.Context, documents []Docum
 	return b.stats, nil
 }
 
+// canonicalizeIngredients 将食材实体改名为规范名，并同步修改指向它的关系
+func (b *GraphBuilder) canonicalizeIngredients(extracted *ExtractedData, aliases map[string]map[string]bool) {
+	if b.synonyms == nil {
+		return
+	}
+
+	renamed := make(map[string]string) // 原食材ID -> 规范食材ID
+	for i := range extracted.Entities {
+		entity := &extracted.Entities[i]
+		if entity.Type != EntityIngredient {
+			continue
+		}
+
+		canonical := b.synonyms.Canonical(entity.Name)
+		if canonical == entity.Name {
+			continue
+		}
+
+		canonicalID := fmt.Sprintf("ing_%s", canonical)
+		renamed[entity.ID] = canonicalID
+		if aliases[canonicalID] == nil {
+			aliases[canonicalID] = make(map[string]bool)
+		}
+		aliases[canonicalID][entity.Name] = true
+
+		entity.ID = canonicalID
+		entity.Name = canonical
+	}
+
+	for i := range extracted.Relations {
+		if to, ok := renamed[extracted.Relations[i].To]; ok {
+			extracted.Relations[i].To = to
+		}
+	}
+}
+
+// sortedKeys 返回排序后的集合元素
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // createIndexes 创建索引
 // Neo4j 索引用途：加速节点属性查询（类似 MySQL 索引）
 // 例如：MATCH (n:Dish {name: '红烧肉'}) 会直接通过索引定位，而不是扫描所有节点
@@ -171,6 +245,7 @@ func (b *GraphBuilder) createIndexes(ctx context.Context) {
 
 // Document 简化的文档结构
 type Document struct {
+	ID       string // 文档ID（与检索侧 models.Document.ID 一致，如 "meat_dish/红烧肉/红烧肉.md"）
 	Content  string
 	Category string
 	DishName string
@@ -0,0 +1,100 @@
+package kg
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 用户词典词性：菜名为专有名词，食材为普通名词
+const (
+	DictTagDish       = "nz"
+	DictTagIngredient = "n"
+)
+
+// DictEntry 分词用户词典词条
+type DictEntry struct {
+	Word string
+	Tag  string
+}
+
+// GenerateDictionary 从菜谱生成分词用户词典：菜名 + 提取器识别出的食材
+// 让"老友猪肉粉"、"韩式拌饭"这类领域词作为整词切分，结果按词排序，便于比对生成前后的差异
+func GenerateDictionary(documents []Document) []DictEntry {
+	extractor := NewRecipeExtractor()
+	tags := make(map[string]string)
+
+	for _, doc := range documents {
+		for _, ingredient := range extractor.extractIngredients(doc.Content) {
+			if isDictWord(ingredient) {
+				tags[ingredient] = DictTagIngredient
+			}
+		}
+	}
+
+	// 菜名优先于同名食材（如"番茄酱"既是菜谱也是原料）；去掉"（半成品加工）"这类括号说明
+	for _, doc := range documents {
+		name := doc.DishName
+		if idx := strings.IndexAny(name, "（("); idx > 0 {
+			name = name[:idx]
+		}
+		if doc.Category != "template" && isDictWord(name) {
+			tags[name] = DictTagDish
+		}
+	}
+
+	entries := make([]DictEntry, 0, len(tags))
+	for word, tag := range tags {
+		entries = append(entries, DictEntry{Word: word, Tag: tag})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Word < entries[j].Word
+	})
+
+	return entries
+}
+
+// WriteDictionary 以 jieba 用户词典格式（词 词性）写入文件
+func WriteDictionary(path string, entries []DictEntry) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create dictionary directory: %w", err)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create dictionary file: %w", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, "# CookRAG 领域词典：由 cmd/build-dict 根据 docs/dishes 自动生成，请勿手工修改")
+	fmt.Fprintln(w, "# 格式：词 词性（nz=菜名，n=食材）")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s %s\n", entry.Word, entry.Tag)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write dictionary file: %w", err)
+	}
+	return nil
+}
+
+// isDictWord 是否适合作为词典词条：至少两个汉字，且全部由汉字组成
+func isDictWord(word string) bool {
+	runes := []rune(strings.TrimSpace(word))
+	if len(runes) < 2 {
+		return false
+	}
+	for _, r := range runes {
+		if !unicode.Is(unicode.Han, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -247,15 +247,18 @@ func (e *RecipeExtractor) extractIngredients(content string) []string {
 		line = strings.TrimPrefix(line, "-")
 		line = strings.TrimSpace(line)
 
-		// 过滤掉非食材行（如单位、数字等）
-		if e.isIngredient(line) {
+		for _, item := range ingredientItems(line) {
+			// 过滤掉非食材行（如单位、数字等）
+			if !e.isIngredient(item) {
+				continue
+			}
 			// 提取中文名称（去除空格及后续内容）
-			if idx := strings.IndexAny(line, " 0123456789gml克毫升"); idx > 0 {
-				line = line[:idx]
+			if idx := strings.IndexAny(item, " 0123456789gml克毫升"); idx > 0 {
+				item = item[:idx]
 			}
-			line = strings.TrimSpace(line)
-			if len([]rune(line)) >= 2 && len([]rune(line)) <= 4 {
-				ingredients = append(ingredients, line)
+			item = strings.TrimSpace(item)
+			if len([]rune(item)) >= 2 && len([]rune(item)) <= 4 {
+				ingredients = append(ingredients, item)
 			}
 		}
 	}
@@ -263,6 +266,37 @@ func (e *RecipeExtractor) extractIngredients(content string) []string {
 	return uniqueStrings(ingredients)
 }
 
+// parentheticalPattern 括号内的补充说明，如"米饭（推荐用粳米）"中的"（推荐用粳米）"
+var parentheticalPattern = regexp.MustCompile(`（[^）]*）|\([^)]*\)`)
+
+// backtickPattern 反引号标出的食材，如"主料：`大肉`、`鸡蛋`"
+var backtickPattern = regexp.MustCompile("`([^`]+)`")
+
+// ingredientItems 将原料行拆成候选食材：优先取反引号中的名称，否则去掉括号说明后按顿号、逗号拆分
+func ingredientItems(line string) []string {
+	if matches := backtickPattern.FindAllStringSubmatch(line, -1); len(matches) > 0 {
+		items := make([]string, 0, len(matches))
+		for _, match := range matches {
+			items = append(items, strings.TrimSpace(match[1]))
+		}
+		return items
+	}
+	if strings.HasPrefix(line, "注") {
+		return nil
+	}
+
+	line = parentheticalPattern.ReplaceAllString(line, "")
+	items := make([]string, 0)
+	for _, item := range strings.FieldsFunc(line, func(r rune) bool {
+		return r == '、' || r == '，' || r == ','
+	}) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // isIngredient 判断是否是食材
 func (e *RecipeExtractor) isIngredient(text string) bool {
 	text = strings.TrimSpace(text)
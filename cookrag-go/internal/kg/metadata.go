@@ -0,0 +1,46 @@
+package kg
+
+import (
+	"strconv"
+	"strings"
+)
+
+// 菜谱文档的元数据字段（向量存储 / BM25 / 图检索的过滤条件都按这些字段匹配）
+const (
+	MetadataCategory    = "category"    // 分类目录，如 vegetable_dish
+	MetadataCuisine     = "cuisine"     // 菜系，如 川菜
+	MetadataDifficulty  = "difficulty"  // 难度星级（1-5，无法识别时不写入）
+	MetadataIngredients = "ingredients" // 必备原料列表
+)
+
+// chineseNumerals 难度中的中文数字
+var chineseNumerals = map[rune]int{'一': 1, '二': 2, '三': 3, '四': 4, '五': 5}
+
+// ParseDifficulty 解析难度星级："★★" / "★★☆" / "二" / "2" 都解析为 2，无法识别返回 0
+func ParseDifficulty(s string) int {
+	s = strings.TrimSpace(s)
+	if stars := strings.Count(s, "★"); stars > 0 {
+		return stars
+	}
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return n
+	}
+	if runes := []rune(s); len(runes) == 1 {
+		return chineseNumerals[runes[0]]
+	}
+	return 0
+}
+
+// ExtractMetadata 从菜谱内容提取可用于过滤的元数据（菜系、难度星级、必备原料）
+func (e *RecipeExtractor) ExtractMetadata(content, category string) map[string]interface{} {
+	metadata := map[string]interface{}{
+		MetadataCuisine: e.inferCuisine(category, content),
+	}
+	if difficulty := ParseDifficulty(e.extractDifficulty(content)); difficulty > 0 {
+		metadata[MetadataDifficulty] = difficulty
+	}
+	if ingredients := e.extractIngredients(content); len(ingredients) > 0 {
+		metadata[MetadataIngredients] = ingredients
+	}
+	return metadata
+}
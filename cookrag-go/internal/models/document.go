@@ -6,6 +6,85 @@ type Document struct {
 	Content  string                 `json:"content"`
 	Metadata map[string]interface{} `json:"metadata"`
 	Score    float32                `json:"score,omitempty"`
+	// 命中位置和最相关的片段，说明文档为什么被召回（没有时省略）
+	Highlights []Highlight `json:"highlights,omitempty"`
+	Snippet    string      `json:"snippet,omitempty"`
+	// 分数解释（只在 explain 模式下返回）
+	Explanation *Explanation `json:"explanation,omitempty"`
+}
+
+// Explanation 分数解释：BM25 各词项的得分明细、融合排序中各来源的排名和重排前后的分数
+// 开启查询扩展时，Expansion 为各查询变体之间的融合明细（Sources 中的来源为变体名称）
+type Explanation struct {
+	BM25      *BM25Explanation   `json:"bm25,omitempty"`
+	Fusion    *FusionExplanation `json:"fusion,omitempty"`
+	Expansion *FusionExplanation `json:"expansion,omitempty"`
+	Rerank    *RerankExplanation `json:"rerank,omitempty"`
+}
+
+// BM25Explanation BM25 分数明细（Score 为各词项贡献之和）
+type BM25Explanation struct {
+	Variant string            `json:"variant"` // bm25 / bm25+ / bm25l
+	K1      float64           `json:"k1"`
+	Score   float64           `json:"score"`
+	Terms   []TermExplanation `json:"terms"`
+}
+
+// TermExplanation 单个查询子句（词或短语）对文档的分数贡献
+type TermExplanation struct {
+	Term    string             `json:"term"`              // 词项；短语为引号包围的词项序列
+	Occur   string             `json:"occur"`             // should / must
+	Field   string             `json:"field,omitempty"`   // 限定字段
+	Matched []string           `json:"matched,omitempty"` // 实际命中的词（含同义词）
+	DocFreq int                `json:"doc_freq"`
+	IDF     float64            `json:"idf"`
+	TF      float64            `json:"tf"` // BM25F 伪词频：Σ 字段权重 × 字段词频 / 长度归一化因子
+	Fields  []FieldExplanation `json:"fields"`
+	Score   float64            `json:"score"`
+}
+
+// FieldExplanation 词项在单个字段中的词频和长度归一化
+type FieldExplanation struct {
+	Field      string  `json:"field"`
+	Freq       int     `json:"freq"`
+	Weight     float64 `json:"weight"`
+	B          float64 `json:"b"`
+	Length     int     `json:"length"`
+	AvgLength  float64 `json:"avg_length"`
+	LengthNorm float64 `json:"length_norm"` // 1 - b + b × 字段长度 / 平均字段长度
+}
+
+// FusionExplanation 融合排序明细（Score 为各来源贡献之和，CombMNZ 再乘以命中来源数）
+type FusionExplanation struct {
+	Method  string          `json:"method"`      // rrf / combsum_minmax / combsum_zscore / combmnz_minmax / combmnz_zscore / dbsf
+	K       int             `json:"k,omitempty"` // RRF 常数（只有 rrf 使用）
+	Score   float64         `json:"score"`
+	Sources []SourceRanking `json:"sources"`
+}
+
+// RerankExplanation 重排明细（文档分数已替换为重排分数）
+type RerankExplanation struct {
+	Reranker       string  `json:"reranker"` // http / llm / lexical
+	Score          float64 `json:"score"`
+	RetrievalRank  int     `json:"retrieval_rank"` // 重排前的排名，从1开始
+	RetrievalScore float32 `json:"retrieval_score"`
+}
+
+// SourceRanking 文档在某个来源列表中的排名及其贡献
+type SourceRanking struct {
+	Source       string  `json:"source"` // vector / bm25
+	Rank         int     `json:"rank"`   // 从1开始
+	Weight       float64 `json:"weight"`
+	Score        float32 `json:"score"` // 来源检索器给出的原始分数
+	Contribution float64 `json:"contribution"`
+}
+
+// Highlight 命中片段在 Content 中的位置（字符偏移，End 不含）
+type Highlight struct {
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Text   string `json:"text"`
+	Source string `json:"source"` // 命中来源：term（BM25词项）/ sentence（向量最相似的句子）/ entity（图谱实体）
 }
 
 // RetrievalResult 检索结果
@@ -14,6 +93,38 @@ type RetrievalResult struct {
 	Strategy  string      `json:"strategy"`
 	Latency   float64     `json:"latency_ms"`
 	Query     string      `json:"query"`
+	// 查询纠错（拼音、同音字、错别字），RewrittenQuery 为实际用于检索的查询
+	RewrittenQuery string            `json:"rewritten_query,omitempty"`
+	Corrections    []QueryCorrection `json:"corrections,omitempty"`
+	// 查询扩展的变体及各自的命中数（没有扩展时省略）
+	Variants []QueryVariant `json:"variants,omitempty"`
+	// 策略回退记录（按发生顺序，没有回退时省略），Strategy 为最终给出结果的策略
+	Fallbacks []StrategyFallback `json:"fallbacks,omitempty"`
+}
+
+// QueryCorrection 查询纠错记录
+type QueryCorrection struct {
+	Original  string `json:"original"`  // 查询中的原始片段，如 "hongshaorou"、"宫宝鸡丁"
+	Corrected string `json:"corrected"` // 纠正后的词，如 "红烧肉"、"宫保鸡丁"
+	Method    string `json:"method"`    // 纠错方式：pinyin / homophone / edit_distance
+	Distance  int    `json:"distance"`  // 编辑距离（拼音或汉字，同音为0）
+}
+
+// QueryVariant 查询扩展生成的查询变体
+type QueryVariant struct {
+	Kind  string `json:"kind"` // original / paraphrase / hyde / partial
+	Text  string `json:"text"`
+	Hits  int    `json:"hits"`            // 该变体检索到的文档数
+	Error string `json:"error,omitempty"` // 该变体检索失败的原因
+}
+
+// StrategyFallback 策略回退记录：From 的结果不可用，改用 To
+type StrategyFallback struct {
+	From     string  `json:"from"`
+	To       string  `json:"to,omitempty"`        // 为空表示没有可用的后续策略
+	Reason   string  `json:"reason"`              // error / empty / low_score
+	Detail   string  `json:"detail,omitempty"`    // 错误信息
+	TopScore float32 `json:"top_score,omitempty"` // low_score 时的最高分
 }
 
 // QueryAnalysis 查询分析结果
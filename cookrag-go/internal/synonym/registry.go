@@ -0,0 +1,313 @@
+package synonym
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+)
+
+var (
+	// ErrEmptyTerm 规范名或别名为空
+	ErrEmptyTerm = errors.New("synonym term is empty")
+	// ErrNoRegistry 未配置同义词表（nil 注册表上修改同义词）
+	ErrNoRegistry = errors.New("synonym registry is not configured")
+)
+
+// Registry 同义词/别名注册表
+// 每组同义词有一个规范名（如"番茄"）和若干别名（如"西红柿"），BM25 查询时双向扩展，构建图谱时统一到规范名
+// 所有方法对 nil 接收者安全：未配置同义词时查询直接返回原词，Put 返回 ErrNoRegistry，Delete 和 Save 什么也不做
+type Registry struct {
+	mu        sync.RWMutex
+	path      string
+	groups    map[string][]string // 规范名 -> 别名
+	canonical map[string]string   // 词（规范名或别名）-> 规范名
+}
+
+// NewRegistry 创建空注册表（path 为空时不落盘）
+func NewRegistry(path string) *Registry {
+	return &Registry{
+		path:      path,
+		groups:    make(map[string][]string),
+		canonical: make(map[string]string),
+	}
+}
+
+// Load 从文件加载注册表，文件不存在时返回空注册表
+// 文件格式：每行一组，逗号分隔，第一个词为规范名，# 开头为注释
+//
+//	番茄,西红柿
+//	土豆,马铃薯,洋芋
+func Load(path string) (*Registry, error) {
+	registry := NewRegistry(path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return registry, nil
+		}
+		return nil, fmt.Errorf("failed to open synonym file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		terms := splitTerms(line)
+		if len(terms) < 2 {
+			log.Warnf("⚠️  Ignoring synonym line %d without aliases: %s", lineNo, line)
+			continue
+		}
+		registry.set(terms[0], terms[1:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read synonym file: %w", err)
+	}
+
+	log.Infof("📖 Loaded synonyms: %s (%d groups)", path, len(registry.groups))
+	return registry, nil
+}
+
+// Canonical 返回词的规范名（不在注册表中的词原样返回）
+func (r *Registry) Canonical(term string) string {
+	if r == nil {
+		return term
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if canonical, ok := r.canonical[term]; ok {
+		return canonical
+	}
+	return term
+}
+
+// Expand 返回词所在组的全部词（规范名在前，包含词本身）
+func (r *Registry) Expand(term string) []string {
+	if r == nil {
+		return []string{term}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	canonical, ok := r.canonical[term]
+	if !ok {
+		return []string{term}
+	}
+
+	terms := make([]string, 0, len(r.groups[canonical])+1)
+	terms = append(terms, canonical)
+	terms = append(terms, r.groups[canonical]...)
+	return terms
+}
+
+// Groups 返回所有同义词组的副本（规范名 -> 别名）
+func (r *Registry) Groups() map[string][]string {
+	groups := make(map[string][]string)
+	if r == nil {
+		return groups
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for canonical, aliases := range r.groups {
+		groups[canonical] = append([]string(nil), aliases...)
+	}
+	return groups
+}
+
+// Put 新增或替换一组同义词并保存到文件
+// 别名如果已属于其他组，会从原来的组中移出
+func (r *Registry) Put(canonical string, aliases []string) error {
+	if r == nil {
+		return ErrNoRegistry
+	}
+
+	canonical = strings.TrimSpace(canonical)
+	if canonical == "" {
+		return ErrEmptyTerm
+	}
+
+	cleaned := make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" {
+			return ErrEmptyTerm
+		}
+		cleaned = append(cleaned, alias)
+	}
+	if len(cleaned) == 0 {
+		return fmt.Errorf("synonym group %s has no aliases", canonical)
+	}
+
+	r.mu.Lock()
+	// 规范名已有自己的组时整组替换；原本是其他组的别名时由 set 从那一组移出
+	if _, ok := r.groups[canonical]; ok {
+		r.remove(canonical)
+	}
+	r.set(canonical, cleaned)
+	r.mu.Unlock()
+
+	return r.Save()
+}
+
+// Delete 删除一组同义词并保存到文件，返回该组是否存在
+func (r *Registry) Delete(canonical string) (bool, error) {
+	if r == nil {
+		return false, nil
+	}
+
+	r.mu.Lock()
+	_, exists := r.groups[canonical]
+	if exists {
+		r.remove(canonical)
+	}
+	r.mu.Unlock()
+
+	if !exists {
+		return false, nil
+	}
+	return true, r.Save()
+}
+
+// Save 将注册表写回文件（未配置路径时不做任何事）
+func (r *Registry) Save() error {
+	if r == nil || r.path == "" {
+		return nil
+	}
+
+	r.mu.RLock()
+	canonicals := make([]string, 0, len(r.groups))
+	for canonical := range r.groups {
+		canonicals = append(canonicals, canonical)
+	}
+	sort.Strings(canonicals)
+
+	var builder strings.Builder
+	builder.WriteString("# CookRAG 同义词表：每行一组，逗号分隔，第一个词为规范名\n")
+	for _, canonical := range canonicals {
+		builder.WriteString(strings.Join(append([]string{canonical}, r.groups[canonical]...), ","))
+		builder.WriteString("\n")
+	}
+	r.mu.RUnlock()
+
+	if dir := filepath.Dir(r.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create synonym directory: %w", err)
+		}
+	}
+
+	// 先写临时文件再 rename，避免写一半的文件被下次启动加载
+	tmpPath := r.path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(builder.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write synonym file: %w", err)
+	}
+	if err := os.Rename(tmpPath, r.path); err != nil {
+		return fmt.Errorf("failed to replace synonym file: %w", err)
+	}
+
+	return nil
+}
+
+// set 添加一组同义词（调用方持有写锁或处于加载阶段）
+func (r *Registry) set(canonical string, aliases []string) {
+	// 规范名原本是其他组的别名时，先从那一组移出
+	if previous, ok := r.canonical[canonical]; ok && previous != canonical {
+		r.detach(previous, canonical)
+	}
+
+	group := r.groups[canonical]
+	seen := make(map[string]bool, len(group)+1)
+	seen[canonical] = true
+	for _, alias := range group {
+		seen[alias] = true
+	}
+
+	r.canonical[canonical] = canonical
+	for _, alias := range aliases {
+		if seen[alias] {
+			continue
+		}
+		seen[alias] = true
+
+		// 别名只能属于一个组
+		if previous, ok := r.canonical[alias]; ok && previous != canonical {
+			if previous == alias {
+				log.Warnf("⚠️  Synonym %s is a canonical term, merging its group into %s", alias, canonical)
+				for _, moved := range r.groups[alias] {
+					if !seen[moved] {
+						seen[moved] = true
+						group = append(group, moved)
+						r.canonical[moved] = canonical
+					}
+				}
+				delete(r.groups, alias)
+			} else {
+				r.detach(previous, alias)
+			}
+		}
+
+		group = append(group, alias)
+		r.canonical[alias] = canonical
+	}
+	r.groups[canonical] = group
+}
+
+// remove 删除一组同义词（调用方持有写锁）
+func (r *Registry) remove(canonical string) {
+	for _, alias := range r.groups[canonical] {
+		delete(r.canonical, alias)
+	}
+	delete(r.canonical, canonical)
+	delete(r.groups, canonical)
+}
+
+// detach 把别名从所在组中移出，组内没有别名时删除该组（调用方持有写锁）
+func (r *Registry) detach(canonical, alias string) {
+	r.groups[canonical] = removeTerm(r.groups[canonical], alias)
+	delete(r.canonical, alias)
+	if len(r.groups[canonical]) == 0 {
+		r.remove(canonical)
+	}
+}
+
+// splitTerms 按中英文逗号切分一行同义词
+func splitTerms(line string) []string {
+	parts := strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || r == '，'
+	})
+
+	terms := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			terms = append(terms, part)
+		}
+	}
+	return terms
+}
+
+// removeTerm 从切片中移除指定词
+func removeTerm(terms []string, term string) []string {
+	result := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if t != term {
+			result = append(result, t)
+		}
+	}
+	return result
+}
@@ -0,0 +1,250 @@
+package docstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cookrag-go/internal/models"
+
+	"github.com/charmbracelet/log"
+)
+
+// Store 文档存储接口
+// 保存原始文档（内容 + 元数据），检索器只需记录文档ID，命中后再回查完整文档
+type Store interface {
+	Put(ctx context.Context, docs ...models.Document) error
+	Get(ctx context.Context, id string) (models.Document, bool)
+	Delete(ctx context.Context, ids ...string) error
+	IDs(ctx context.Context) []string
+	Count() int
+}
+
+// Closer 退出前需要落盘的文档存储（如 FileStore）
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// DefaultFlushInterval FileStore 后台落盘的默认间隔
+const DefaultFlushInterval = time.Second
+
+// MemoryStore 内存文档存储
+type MemoryStore struct {
+	mu   sync.RWMutex
+	docs map[string]models.Document
+}
+
+// NewMemoryStore 创建内存文档存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		docs: make(map[string]models.Document),
+	}
+}
+
+// Put 写入文档（ID相同则覆盖）
+func (s *MemoryStore) Put(ctx context.Context, docs ...models.Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range docs {
+		if doc.ID == "" {
+			return fmt.Errorf("document id is required")
+		}
+		doc.Score = 0 // 分数属于检索结果，不属于文档本身
+		s.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+// Get 按ID读取文档
+func (s *MemoryStore) Get(ctx context.Context, id string) (models.Document, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.docs[id]
+	return doc, ok
+}
+
+// Delete 删除文档（不存在的ID直接忽略）
+func (s *MemoryStore) Delete(ctx context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.docs, id)
+	}
+	return nil
+}
+
+// IDs 返回所有文档ID（已排序）
+func (s *MemoryStore) IDs(ctx context.Context) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.docs))
+	for id := range s.docs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Count 文档数量
+func (s *MemoryStore) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.docs)
+}
+
+// FileStore 磁盘文档存储
+// 内存中保存全部文档，写入只标记为待落盘，由后台按间隔批量落盘（或调用 Flush / Close 时落盘），
+// 逐个写入 N 篇文档不会整体重写 N 次文件；落盘时先写临时文件再 rename，避免写坏
+type FileStore struct {
+	*MemoryStore
+	path      string
+	saveMu    sync.Mutex
+	dirty     atomic.Bool   // 是否有未落盘的修改
+	stop      chan struct{} // 停止后台落盘
+	done      chan struct{} // 后台落盘已退出
+	closeOnce sync.Once
+}
+
+// NewFileStore 创建磁盘文档存储（文件已存在时加载已有文档），按 DefaultFlushInterval 后台落盘
+func NewFileStore(path string) (*FileStore, error) {
+	return NewFileStoreWithInterval(path, DefaultFlushInterval)
+}
+
+// NewFileStoreWithInterval 创建磁盘文档存储，interval<=0 时只在 Flush / Close 时落盘
+func NewFileStoreWithInterval(path string, interval time.Duration) (*FileStore, error) {
+	store := &FileStore{
+		MemoryStore: NewMemoryStore(),
+		path:        path,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read document store: %w", err)
+	}
+	if err == nil {
+		var docs []models.Document
+		if err := json.Unmarshal(data, &docs); err != nil {
+			return nil, fmt.Errorf("failed to decode document store %s: %w", path, err)
+		}
+		if err := store.MemoryStore.Put(context.Background(), docs...); err != nil {
+			return nil, err
+		}
+	}
+
+	go store.flushLoop(interval)
+	return store, nil
+}
+
+// Put 写入文档（稍后落盘）
+func (s *FileStore) Put(ctx context.Context, docs ...models.Document) error {
+	if err := s.MemoryStore.Put(ctx, docs...); err != nil {
+		return err
+	}
+	s.dirty.Store(true)
+	return nil
+}
+
+// Delete 删除文档（稍后落盘）
+func (s *FileStore) Delete(ctx context.Context, ids ...string) error {
+	if err := s.MemoryStore.Delete(ctx, ids...); err != nil {
+		return err
+	}
+	s.dirty.Store(true)
+	return nil
+}
+
+// Flush 立即落盘未保存的修改（没有修改时不写文件）
+func (s *FileStore) Flush(ctx context.Context) error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	if !s.dirty.Swap(false) {
+		return nil
+	}
+	if err := s.save(ctx); err != nil {
+		s.dirty.Store(true) // 下次重试
+		return err
+	}
+	return nil
+}
+
+// Close 停止后台落盘并保存未落盘的修改
+func (s *FileStore) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+	return s.Flush(ctx)
+}
+
+// flushLoop 后台按间隔落盘
+func (s *FileStore) flushLoop(interval time.Duration) {
+	defer close(s.done)
+	if interval <= 0 {
+		<-s.stop
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(context.Background()); err != nil {
+				log.Warnf("⚠️  Failed to flush document store %s: %v", s.path, err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Path 存储文件路径
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// save 将全部文档写入磁盘（调用方持有 saveMu）
+func (s *FileStore) save(ctx context.Context) error {
+	ids := s.IDs(ctx)
+	docs := make([]models.Document, 0, len(ids))
+	for _, id := range ids {
+		if doc, ok := s.Get(ctx, id); ok {
+			docs = append(docs, doc)
+		}
+	}
+
+	data, err := json.Marshal(docs)
+	if err != nil {
+		return fmt.Errorf("failed to encode document store: %w", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create document store directory: %w", err)
+		}
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write document store: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace document store: %w", err)
+	}
+
+	return nil
+}
@@ -15,12 +15,13 @@ import (
 type Client struct {
 	client  client.Client
 	timeout time.Duration
+	index   *IndexConfig // 索引类型、度量方式和搜索参数
 }
 
 // SearchResult 搜索结果
 type SearchResult struct {
 	ID     int64                  `json:"id"`
-	Score  float32                `json:"score"`
+	Score  float32                `json:"score"` // 原始分数（L2 为距离的平方，越小越相似；见 NormalizeScore）
 	Fields map[string]interface{} `json:"fields"`
 }
 
@@ -38,9 +39,27 @@ func NewClient(host, port string) (*Client, error) {
 	return &Client{
 		client:  c,
 		timeout: 30 * time.Second,
+		index:   DefaultIndexConfig(),
 	}, nil
 }
 
+// SetIndexConfig 设置索引配置（建索引和搜索都使用该配置）
+func (c *Client) SetIndexConfig(config *IndexConfig) error {
+	if config == nil {
+		config = DefaultIndexConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	c.index = config
+	return nil
+}
+
+// IndexConfig 当前索引配置
+func (c *Client) IndexConfig() *IndexConfig {
+	return c.index
+}
+
 // Close 关闭连接
 func (c *Client) Close(ctx context.Context) error {
 	return c.client.Close()
@@ -129,23 +148,21 @@ func (c *Client) CreateCollection(ctx context.Context, collectionName string, di
 	return nil
 }
 
-// CreateIndex 创建索引
-func (c *Client) CreateIndex(ctx context.Context, collectionName, fieldName string, idxType string, params map[string]string) error {
-	log.Printf("📇 Creating index on %s.%s (type: %s)", collectionName, fieldName, idxType)
+// CreateIndex 按索引配置在向量字段上创建索引
+func (c *Client) CreateIndex(ctx context.Context, collectionName, fieldName string) error {
+	log.Printf("📇 Creating index on %s.%s (type: %s, metric: %s)", collectionName, fieldName, c.index.IndexType, c.index.MetricType)
 	// fieldName 是指定要在哪个字段上创建索引
 
 	// Milvus 索引说明：
 	// 索引用于加速向量相似度搜索，没有索引的话就是暴力搜索（FLAT）
 	// IVF_FLAT: 基于倒排文件的索引，平衡速度和精度（推荐）
+	// IVF_SQ8: IVF_FLAT + 标量量化，内存更省，精度略降
 	// HNSW: 基于图的索引，速度更快但内存占用更大
 	// L2: 欧几里得距离的平方（最常用）
 	// IP: 内积（Inner Product）
 	// COSINE: 余弦相似度
 	// nlist: 聚类中心点数量，影响检索速度和精度（通常设为 sqrt(数据量)）
-	idx, err := entity.NewIndexIvfFlat(
-		entity.L2, // 距离度量类型：L2距离（欧几里得距离的平方）
-		128,       // nlist参数：聚类中心点数量，影响索引性能
-	)
+	idx, err := c.index.buildIndex()
 	if err != nil {
 		return fmt.Errorf("failed to create index config: %w", err)
 	}
@@ -158,10 +175,55 @@ func (c *Client) CreateIndex(ctx context.Context, collectionName, fieldName stri
 	return nil
 }
 
+// DescribeIndex 获取向量字段上已有索引的类型和度量方式（没有索引时返回空字符串）
+func (c *Client) DescribeIndex(ctx context.Context, collectionName, fieldName string) (indexType string, metricType string, err error) {
+	indexes, err := c.client.DescribeIndex(ctx, collectionName, fieldName)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to describe index: %w", err)
+	}
+	if len(indexes) == 0 {
+		return "", "", nil
+	}
+	return string(indexes[0].IndexType()), indexes[0].Params()["metric_type"], nil
+}
+
 // Insert 插入数据
 func (c *Client) Insert(ctx context.Context, collectionName string, ids []int64, embeddings [][]float32, texts []string, metadata []map[string]interface{}) error {
 	log.Printf("📝 Inserting %d documents into %s", len(ids), collectionName)
 
+	columns, err := buildColumns(ids, embeddings, texts, metadata)
+	if err != nil {
+		return err
+	}
+
+	// 插入数据
+	if _, err := c.client.Insert(ctx, collectionName, "", columns...); err != nil {
+		return fmt.Errorf("failed to insert: %w", err)
+	}
+
+	log.Printf("✅ Inserted %d documents", len(ids))
+	return nil
+}
+
+// Upsert 按主键插入或替换数据（主键已存在的行会被新数据覆盖）
+func (c *Client) Upsert(ctx context.Context, collectionName string, ids []int64, embeddings [][]float32, texts []string, metadata []map[string]interface{}) error {
+	log.Printf("📝 Upserting %d documents into %s", len(ids), collectionName)
+
+	columns, err := buildColumns(ids, embeddings, texts, metadata)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.client.Upsert(ctx, collectionName, "", columns...); err != nil {
+		return fmt.Errorf("failed to upsert: %w", err)
+	}
+
+	log.Printf("✅ Upserted %d documents", len(ids))
+	return nil
+}
+
+// buildColumns 按集合 schema 构建列数据（id / vector / text / metadata）
+func buildColumns(ids []int64, embeddings [][]float32, texts []string, metadata []map[string]interface{}) ([]entity.Column, error) {
 	// 准备ID列
 	idCol := entity.NewColumnInt64("id", ids)
 
@@ -176,29 +238,13 @@ func (c *Client) Insert(ctx context.Context, collectionName string, ids []int64,
 	for i, meta := range metadata {
 		metaBytes, err := json.Marshal(meta)
 		if err != nil {
-			return fmt.Errorf("failed to marshal metadata: %w", err)
+			return nil, fmt.Errorf("failed to marshal metadata: %w", err)
 		}
 		metadataBytes[i] = metaBytes
 	}
 	metadataCol := entity.NewColumnJSONBytes("metadata", metadataBytes)
 
-	// 插入数据
-	_, err := c.client.Insert(
-		ctx,
-		collectionName,
-		"", // partitionName
-		idCol,
-		vectorCol,
-		textCol,
-		metadataCol,
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to insert: %w", err)
-	}
-
-	log.Printf("✅ Inserted %d documents", len(ids))
-	return nil
+	return []entity.Column{idCol, vectorCol, textCol, metadataCol}, nil
 }
 
 // Flush 刷新数据
@@ -228,9 +274,24 @@ func (c *Client) LoadCollection(ctx context.Context, collectionName string) erro
 	return nil
 }
 
-// Search 向量搜索
+// Search 向量搜索（多个查询向量的结果按顺序拼接在一起）
 func (c *Client) Search(ctx context.Context, collectionName string, vectors [][]float32, vectorField string, outputFields []string, topK int) ([]*SearchResult, error) {
-	log.Printf("🔍 Searching in %s (top_k: %d)", collectionName, topK)
+	grouped, err := c.SearchWithFilter(ctx, collectionName, vectors, vectorField, outputFields, topK, "")
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*SearchResult, 0)
+	for _, group := range grouped {
+		results = append(results, group...)
+	}
+	return results, nil
+}
+
+// SearchWithFilter 带标量过滤的向量搜索，按查询向量分组返回结果
+// expr 为 Milvus 过滤表达式（为空表示不过滤），如 metadata["category"] == "meat_dish"
+func (c *Client) SearchWithFilter(ctx context.Context, collectionName string, vectors [][]float32, vectorField string, outputFields []string, topK int, expr string) ([][]*SearchResult, error) {
+	log.Printf("🔍 Searching in %s (top_k: %d, expr: %q)", collectionName, topK, expr)
 
 	// 准备搜索向量
 	vectorsData := make([]entity.Vector, len(vectors))
@@ -249,8 +310,8 @@ func (c *Client) Search(ctx context.Context, collectionName string, vectors [][]
 	// nprobe = 128 → 最慢，精度最高（搜索所有聚类，等同于暴力搜索）
 	//
 	// 经验值：nprobe 通常设为 nlist 的 1/10 到 1/2
-	// 这里 nlist=128, nprobe=10，比较合理
-	sp, err := entity.NewIndexIvfFlatSearchParam(10) // nprobe: 搜索聚类数量
+	// 默认 nlist=128, nprobe=10，比较合理；HNSW 使用 ef，FLAT 不需要参数（见 IndexConfig）
+	sp, err := c.index.searchParam(topK)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create search param: %w", err)
 	}
@@ -261,14 +322,14 @@ func (c *Client) Search(ctx context.Context, collectionName string, vectors [][]
 		[]string{},              // partitions: 指定搜索哪些分区（空数组=搜索所有分区）
 		                        // 分区示例：[]string{"川菜", "湘菜"} 只搜索这些分区
 		                        // 常见用法：[]string{} 搜索全部
-		"",                      // expr: 标量过滤表达式（类似 SQL 的 WHERE 子句）
-		                        // 示例："metadata[\"difficulty\"] == \"简单\"" 只查简单菜谱
-		                        // 示例："metadata[\"category\"] == \"川菜\"" 只查川菜
+		expr,                    // expr: 标量过滤表达式（类似 SQL 的 WHERE 子句）
+		                        // 示例："metadata[\"difficulty\"] <= 2" 只查两星以内的菜谱
+		                        // 示例："json_contains(metadata[\"ingredients\"], \"鸡蛋\")" 只查用到鸡蛋的菜谱
 		                        // 常见用法："" 不过滤，搜索全部数据
 		outputFields,             // 输出哪些字段（如 ["text", "metadata"]）
 		vectorsData,              // 搜索向量（用户查询的 embedding）
 		vectorField,              // 在哪个字段上搜索（通常是 "vector"）
-		entity.L2,                // metric type: 距离度量类型（L2/IP/COSINE）
+		c.index.Metric(),         // metric type: 距离度量类型（L2/IP/COSINE），必须与建索引时一致
 		topK,                     // 返回最相似的 K 个结果
 		sp,                       // search param: 搜索参数（如 nprobe=10）
 	)
@@ -277,9 +338,11 @@ func (c *Client) Search(ctx context.Context, collectionName string, vectors [][]
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
 
-	// 解析结果
-	results := make([]*SearchResult, 0)
+	// 解析结果（每个查询向量对应一组结果）
+	grouped := make([][]*SearchResult, 0, len(searchResult))
+	total := 0
 	for _, res := range searchResult {
+		results := make([]*SearchResult, 0, res.ResultCount)
 		for i := 0; i < res.ResultCount; i++ {
 			// 获取ID
 			idField := res.IDs.(*entity.ColumnInt64)
@@ -324,10 +387,61 @@ func (c *Client) Search(ctx context.Context, collectionName string, vectors [][]
 				Fields: fields,
 			})
 		}
+		grouped = append(grouped, results)
+		total += len(results)
 	}
 
-	log.Printf("✅ Search completed: %d results", len(results))
-	return results, nil
+	log.Printf("✅ Search completed: %d results", total)
+	return grouped, nil
+}
+
+// QueryVectors 按过滤表达式查询向量，返回主键到向量的映射
+func (c *Client) QueryVectors(ctx context.Context, collectionName string, expr string, idField string, vectorField string) (map[int64][]float32, error) {
+	resultSet, err := c.client.Query(ctx, collectionName, []string{}, expr, []string{idField, vectorField})
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+
+	idColumn, ok := resultSet.GetColumn(idField).(*entity.ColumnInt64)
+	if !ok || idColumn == nil {
+		return nil, fmt.Errorf("id field %s not found in query result", idField)
+	}
+	vectorColumn, ok := resultSet.GetColumn(vectorField).(*entity.ColumnFloatVector)
+	if !ok || vectorColumn == nil {
+		return nil, fmt.Errorf("vector field %s not found in query result", vectorField)
+	}
+
+	ids, vectors := idColumn.Data(), vectorColumn.Data()
+	rows := make(map[int64][]float32, len(ids))
+	for i := 0; i < len(ids) && i < len(vectors); i++ {
+		rows[ids[i]] = vectors[i]
+	}
+	return rows, nil
+}
+
+// Delete 按过滤表达式删除数据，如 metadata["doc_id"] in ["a.md", "b.md"]
+func (c *Client) Delete(ctx context.Context, collectionName string, expr string) error {
+	if err := c.client.Delete(ctx, collectionName, "", expr); err != nil {
+		return fmt.Errorf("failed to delete: %w", err)
+	}
+	return nil
+}
+
+// Dimension 获取集合向量字段的维度
+func (c *Client) Dimension(ctx context.Context, collectionName string, vectorField string) (int, error) {
+	collection, err := c.client.DescribeCollection(ctx, collectionName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to describe collection: %w", err)
+	}
+
+	for _, field := range collection.Schema.Fields {
+		if field.Name == vectorField {
+			var dimension int
+			fmt.Sscanf(field.TypeParams["dim"], "%d", &dimension)
+			return dimension, nil
+		}
+	}
+	return 0, fmt.Errorf("vector field %s not found in collection %s", vectorField, collectionName)
 }
 
 // GetCollectionStats 获取集合统计信息
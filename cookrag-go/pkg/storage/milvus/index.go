@@ -0,0 +1,110 @@
+package milvus
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+)
+
+// 支持的索引类型
+const (
+	IndexFlat    = "FLAT"     // 暴力搜索，精确但最慢
+	IndexIvfFlat = "IVF_FLAT" // 倒排聚类，平衡速度和精度
+	IndexIvfSQ8  = "IVF_SQ8"  // 倒排聚类 + 8bit 标量量化，内存约为 IVF_FLAT 的 1/4
+	IndexHNSW    = "HNSW"     // 图索引，速度快、召回高、内存占用大
+)
+
+// IndexConfig 向量索引配置（索引类型、度量方式、构建和搜索参数）
+// 度量方式在建索引时确定，修改 MetricType 后需要重建集合
+type IndexConfig struct {
+	IndexType      string // FLAT / IVF_FLAT / IVF_SQ8 / HNSW
+	MetricType     string // L2 / IP / COSINE
+	NList          int    // IVF_*：聚类中心数量（通常取 sqrt(数据量)）
+	NProbe         int    // IVF_*：搜索时检查的聚类数量（通常为 nlist 的 1/10 到 1/2）
+	M              int    // HNSW：每个节点的最大邻居数
+	EfConstruction int    // HNSW：构建时的候选集大小
+	Ef             int    // HNSW：搜索时的候选集大小（实际取 max(Ef, topK)）
+}
+
+// DefaultIndexConfig 默认索引配置（IVF_FLAT + L2，nlist=128，nprobe=10）
+func DefaultIndexConfig() *IndexConfig {
+	return &IndexConfig{
+		IndexType:      IndexIvfFlat,
+		MetricType:     string(entity.L2),
+		NList:          128,
+		NProbe:         10,
+		M:              16,
+		EfConstruction: 200,
+		Ef:             64,
+	}
+}
+
+// Validate 检查索引类型和度量方式（大小写不敏感，检查后统一为大写）
+func (c *IndexConfig) Validate() error {
+	c.IndexType = strings.ToUpper(strings.TrimSpace(c.IndexType))
+	c.MetricType = strings.ToUpper(strings.TrimSpace(c.MetricType))
+
+	switch c.IndexType {
+	case IndexFlat, IndexIvfFlat, IndexIvfSQ8, IndexHNSW:
+	default:
+		return fmt.Errorf("unsupported milvus index type: %q", c.IndexType)
+	}
+
+	switch entity.MetricType(c.MetricType) {
+	case entity.L2, entity.IP, entity.COSINE:
+	default:
+		return fmt.Errorf("unsupported milvus metric type: %q", c.MetricType)
+	}
+
+	return nil
+}
+
+// Metric 度量方式
+func (c *IndexConfig) Metric() entity.MetricType {
+	return entity.MetricType(c.MetricType)
+}
+
+// buildIndex 构建索引定义
+func (c *IndexConfig) buildIndex() (entity.Index, error) {
+	switch c.IndexType {
+	case IndexFlat:
+		return entity.NewIndexFlat(c.Metric())
+	case IndexIvfFlat:
+		return entity.NewIndexIvfFlat(c.Metric(), c.NList)
+	case IndexIvfSQ8:
+		return entity.NewIndexIvfSQ8(c.Metric(), c.NList)
+	case IndexHNSW:
+		return entity.NewIndexHNSW(c.Metric(), c.M, c.EfConstruction)
+	default:
+		return nil, fmt.Errorf("unsupported milvus index type: %q", c.IndexType)
+	}
+}
+
+// searchParam 构建搜索参数（HNSW 的 ef 不能小于 topK）
+func (c *IndexConfig) searchParam(topK int) (entity.SearchParam, error) {
+	switch c.IndexType {
+	case IndexFlat:
+		return entity.NewIndexFlatSearchParam()
+	case IndexIvfFlat:
+		return entity.NewIndexIvfFlatSearchParam(c.NProbe)
+	case IndexIvfSQ8:
+		return entity.NewIndexIvfSQ8SearchParam(c.NProbe)
+	case IndexHNSW:
+		return entity.NewIndexHNSWSearchParam(max(c.Ef, topK))
+	default:
+		return nil, fmt.Errorf("unsupported milvus index type: %q", c.IndexType)
+	}
+}
+
+// NormalizeScore 将 Milvus 返回的原始分数转换为"越大越相似"的分数
+// L2 返回的是距离的平方（越小越相似），转换为 1/(1+d)，取值 (0, 1]；IP / COSINE 本身越大越相似，原样返回
+func NormalizeScore(metric entity.MetricType, raw float32) float32 {
+	if metric == entity.L2 {
+		if raw < 0 {
+			raw = 0
+		}
+		return 1 / (1 + raw)
+	}
+	return raw
+}
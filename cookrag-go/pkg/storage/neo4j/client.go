@@ -14,6 +14,12 @@ import (
 type Client struct {
 	driver   neo4j.DriverWithContext
 	database string
+	aliases  AliasResolver // 别名解析（可选）
+}
+
+// AliasResolver 别名解析：把查询中的同义词映射到图谱中的规范实体名（如 西红柿 → 番茄）
+type AliasResolver interface {
+	Canonical(term string) string
 }
 
 // GraphNode 图节点
@@ -73,6 +79,11 @@ func NewClient(uri, username, password, database string) (*Client, error) {
 	}, nil
 }
 
+// SetAliasResolver 设置实体提取时使用的别名解析
+func (c *Client) SetAliasResolver(resolver AliasResolver) {
+	c.aliases = resolver
+}
+
 // Close 关闭连接
 func (c *Client) Close(ctx context.Context) error {
 	return c.driver.Close(ctx)
@@ -151,7 +162,9 @@ func (c *Client) MultiHopSearch(ctx context.Context, entities []string, maxDepth
 		related.name AS related_name,              // 相关节点名称 → row[4]
 		labels(related) AS related_labels,         // 相关节点标签 → row[5]
 		type(last(relationships(path))) AS relation_type,  // 关系类型 → row[6]
-		length(path) AS hops                       // 跳数 → row[7]
+		length(path) AS hops,                      // 跳数 → row[7]
+		start.doc_id AS start_doc_id,              // 起始节点文档ID（仅菜品节点有）→ row[8]
+		related.doc_id AS related_doc_id           // 相关节点文档ID（仅菜品节点有）→ row[9]
 	LIMIT 100
 	`
 
@@ -187,16 +200,18 @@ func (c *Client) MultiHopSearch(ctx context.Context, entities []string, maxDepth
 
 			// 添加起始节点到子图
 			subgraph.Nodes = append(subgraph.Nodes, &GraphNode{
-				NodeID: startID,
-				Labels: startLabels,
-				Name:   startName,
+				NodeID:     startID,
+				Labels:     startLabels,
+				Name:       startName,
+				Properties: docIDProperties(row, 8),
 			})
 
 			// 添加相关节点到子图
 			subgraph.Nodes = append(subgraph.Nodes, &GraphNode{
-				NodeID: relatedID,
-				Labels: relatedLabels,
-				Name:   relatedName,
+				NodeID:     relatedID,
+				Labels:     relatedLabels,
+				Name:       relatedName,
+				Properties: docIDProperties(row, 9),
 			})
 
 			// 添加关系到子图
@@ -242,6 +257,20 @@ func (c *Client) ExtractEntities(ctx context.Context, query string) ([]string, e
 		queryParts = []string{query}
 	}
 
+	// 别名解析：图谱中的食材节点使用规范名，补充查询词对应的规范名
+	if c.aliases != nil {
+		seen := make(map[string]bool, len(queryParts))
+		for _, part := range queryParts {
+			seen[part] = true
+		}
+		for _, part := range queryParts {
+			if canonical := c.aliases.Canonical(part); !seen[canonical] {
+				seen[canonical] = true
+				queryParts = append(queryParts, canonical)
+			}
+		}
+	}
+
 	log.Printf("   Tokenized query parts: %v", queryParts)
 
 	// 查找食材和菜品节点
@@ -424,6 +453,17 @@ func (c *Client) ClearGraph(ctx context.Context) error {
 	return nil
 }
 
+// docIDProperties 从查询结果的指定列读取文档ID，构造节点属性（无文档ID时返回空属性）
+func docIDProperties(row []interface{}, index int) map[string]interface{} {
+	properties := make(map[string]interface{})
+	if index < len(row) && row[index] != nil {
+		if docID, ok := row[index].(string); ok && docID != "" {
+			properties["doc_id"] = docID
+		}
+	}
+	return properties
+}
+
 // toStringSlice 将interface{}转换为[]string
 func toStringSlice(v interface{}) []string {
 	if v == nil {
@@ -0,0 +1,554 @@
+package vectorstore
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+)
+
+// embeddedFileVersion 集合文件格式版本
+const embeddedFileVersion = 1
+
+// 进程内存储的索引类型
+const (
+	IndexFlat = "flat" // 精确（暴力）搜索
+	IndexHNSW = "hnsw" // HNSW 近似最近邻搜索
+)
+
+// EmbeddedStoreConfig 进程内向量存储配置
+type EmbeddedStoreConfig struct {
+	Dir   string      // 数据目录（为空时只保存在内存中）
+	Index string      // 索引类型：flat / hnsw
+	HNSW  *HNSWConfig // HNSW 参数（Index 为 hnsw 时生效）
+}
+
+// DefaultEmbeddedStoreConfig 默认配置（只保存在内存中，精确搜索）
+func DefaultEmbeddedStoreConfig() *EmbeddedStoreConfig {
+	return &EmbeddedStoreConfig{
+		Index: IndexFlat,
+		HNSW:  DefaultHNSWConfig(),
+	}
+}
+
+// EmbeddedStore 进程内向量存储：余弦相似度搜索，每个集合落盘为一个 JSON 文件
+// 默认精确（暴力）搜索，菜谱规模下足够快；数据量大时可启用 HNSW 索引（图结构另存为 <集合>.hnsw）
+// 适合单机部署和测试，不依赖任何外部服务
+type EmbeddedStore struct {
+	mu          sync.RWMutex
+	config      *EmbeddedStoreConfig
+	collections map[string]*embeddedCollection
+}
+
+// embeddedCollection 集合：记录按写入顺序保存，index 用于按ID定位
+type embeddedCollection struct {
+	name      string
+	dimension int
+	records   []Record
+	norms     []float64 // 与 records 对应的向量范数（加载时重新计算，不落盘）
+	index     map[string]int
+	hnsw      *HNSWIndex // HNSW 索引（flat 时为 nil）
+}
+
+// embeddedFile 集合文件格式
+type embeddedFile struct {
+	Version   int      `json:"version"`
+	Name      string   `json:"name"`
+	Dimension int      `json:"dimension"`
+	Records   []Record `json:"records"`
+}
+
+// NewEmbeddedStore 创建进程内向量存储，配置了数据目录时加载目录中已有的集合
+func NewEmbeddedStore(config *EmbeddedStoreConfig) (*EmbeddedStore, error) {
+	if config == nil {
+		config = DefaultEmbeddedStoreConfig()
+	}
+	switch config.Index {
+	case "":
+		config.Index = IndexFlat
+	case IndexFlat, IndexHNSW:
+	default:
+		return nil, fmt.Errorf("unsupported embedded vector index: %q", config.Index)
+	}
+
+	store := &EmbeddedStore{
+		config:      config,
+		collections: make(map[string]*embeddedCollection),
+	}
+	if config.Dir == "" {
+		return store, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(config.Dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vector store directory: %w", err)
+	}
+	for _, path := range paths {
+		collection, err := loadEmbeddedCollection(path)
+		if err != nil {
+			return nil, err
+		}
+		if config.Index == IndexHNSW {
+			store.loadIndex(collection)
+		}
+		store.collections[collection.name] = collection
+		log.Infof("📂 Loaded vector collection: %s (%d records, dimension %d)", collection.name, len(collection.records), collection.dimension)
+	}
+
+	return store, nil
+}
+
+// CreateCollection 创建集合（已存在且维度一致时不做任何事）
+func (s *EmbeddedStore) CreateCollection(ctx context.Context, collection string, dimension int) error {
+	if collection == "" || strings.ContainsAny(collection, `/\`) {
+		return fmt.Errorf("invalid collection name: %q", collection)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.collections[collection]; ok {
+		if existing.dimension != dimension {
+			return fmt.Errorf("%w: collection %s has dimension %d, got %d", ErrDimensionMismatch, collection, existing.dimension, dimension)
+		}
+		return nil
+	}
+
+	c := &embeddedCollection{
+		name:      collection,
+		dimension: dimension,
+		index:     make(map[string]int),
+	}
+	if s.config.Index == IndexHNSW {
+		c.hnsw = NewHNSWIndex(s.config.HNSW, dimension)
+	}
+	s.collections[collection] = c
+	return s.save(collection)
+}
+
+// HasCollection 集合是否存在
+func (s *EmbeddedStore) HasCollection(ctx context.Context, collection string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.collections[collection]
+	return ok, nil
+}
+
+// Upsert 写入记录（ID已存在则替换）并落盘
+func (s *EmbeddedStore) Upsert(ctx context.Context, collection string, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.collections[collection]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, collection)
+	}
+
+	for _, record := range records {
+		if record.ID == "" {
+			return fmt.Errorf("vector record id is required")
+		}
+		if len(record.Vector) != c.dimension {
+			return fmt.Errorf("%w: record %s has dimension %d, collection %s expects %d",
+				ErrDimensionMismatch, record.ID, len(record.Vector), collection, c.dimension)
+		}
+	}
+
+	for _, record := range records {
+		c.put(record)
+	}
+	c.compactIndex(s.config.HNSW)
+	return s.save(collection)
+}
+
+// Delete 按ID删除记录并落盘
+func (s *EmbeddedStore) Delete(ctx context.Context, collection string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.collections[collection]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrCollectionNotFound, collection)
+	}
+
+	for _, id := range ids {
+		c.remove(id)
+	}
+	c.compactIndex(s.config.HNSW)
+	return s.save(collection)
+}
+
+// Search 余弦相似度搜索，取最高的 topK 条
+// 启用 HNSW 时走近似搜索；近似结果不足 topK 条（过滤条件太严或候选被删除的节点占满）且集合中还有更多记录时退回精确搜索
+func (s *EmbeddedStore) Search(ctx context.Context, collection string, vectors [][]float32, topK int, filter Filter) ([][]SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.collections[collection]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, collection)
+	}
+
+	grouped := make([][]SearchResult, 0, len(vectors))
+	for _, vector := range vectors {
+		if len(vector) != c.dimension {
+			return nil, fmt.Errorf("%w: query has dimension %d, collection %s expects %d",
+				ErrDimensionMismatch, len(vector), collection, c.dimension)
+		}
+
+		if c.hnsw != nil && topK > 0 {
+			results, err := c.searchIndex(vector, topK, filter)
+			if err != nil {
+				return nil, err
+			}
+			if len(results) == topK || (len(filter) == 0 && len(results) == len(c.records)) {
+				grouped = append(grouped, results)
+				continue
+			}
+		}
+		grouped = append(grouped, c.searchExact(vector, topK, filter))
+	}
+
+	return grouped, nil
+}
+
+// GetVectors 按文档ID读取向量
+func (s *EmbeddedStore) GetVectors(ctx context.Context, collection string, ids []string) (map[string][]float32, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.collections[collection]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, collection)
+	}
+
+	vectors := make(map[string][]float32, len(ids))
+	for _, id := range ids {
+		if i, ok := c.index[id]; ok {
+			vectors[id] = c.records[i].Vector
+		}
+	}
+	return vectors, nil
+}
+
+// Stats 集合统计信息
+func (s *EmbeddedStore) Stats(ctx context.Context, collection string) (*CollectionStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c, ok := s.collections[collection]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCollectionNotFound, collection)
+	}
+
+	return &CollectionStats{
+		Name:      collection,
+		Backend:   "embedded",
+		Index:     s.config.Index,
+		Dimension: c.dimension,
+		Count:     int64(len(c.records)),
+	}, nil
+}
+
+// Close 进程内存储无需释放资源（每次写入都已落盘）
+func (s *EmbeddedStore) Close(ctx context.Context) error {
+	return nil
+}
+
+// searchExact 精确搜索：计算查询向量与所有（满足过滤条件的）记录的余弦相似度
+func (c *embeddedCollection) searchExact(vector []float32, topK int, filter Filter) []SearchResult {
+	type scored struct {
+		index int
+		score float64
+	}
+
+	queryNorm := vectorNorm(vector)
+	candidates := make([]scored, 0, len(c.records))
+	for i, record := range c.records {
+		if len(filter) > 0 && !filter.Match(record.Metadata) {
+			continue
+		}
+		candidates = append(candidates, scored{index: i, score: cosine(vector, record.Vector, queryNorm, c.norms[i])})
+	}
+
+	// 分数相同按ID排序，保证结果稳定
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		return c.records[candidates[i].index].ID < c.records[candidates[j].index].ID
+	})
+	if topK > 0 && len(candidates) > topK {
+		candidates = candidates[:topK]
+	}
+
+	results := make([]SearchResult, 0, len(candidates))
+	for _, candidate := range candidates {
+		results = append(results, c.result(candidate.index, float32(candidate.score)))
+	}
+	return results
+}
+
+// searchIndex HNSW 近似搜索（过滤条件在索引候选上检查）
+func (c *embeddedCollection) searchIndex(vector []float32, topK int, filter Filter) ([]SearchResult, error) {
+	var accept func(id string) bool
+	if len(filter) > 0 {
+		accept = func(id string) bool {
+			return filter.Match(c.records[c.index[id]].Metadata)
+		}
+	}
+
+	hits, err := c.hnsw.Search(vector, topK, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if i, ok := c.index[hit.ID]; ok {
+			results = append(results, c.result(i, hit.Score))
+		}
+	}
+	return results, nil
+}
+
+// result 将记录转换为搜索结果
+func (c *embeddedCollection) result(i int, score float32) SearchResult {
+	record := c.records[i]
+	return SearchResult{
+		ID:       record.ID,
+		Score:    score,
+		Text:     record.Text,
+		Metadata: copyMetadata(record.Metadata),
+	}
+}
+
+// put 写入或替换记录（向量变化时同步更新 HNSW 索引）
+func (c *embeddedCollection) put(record Record) {
+	norm := vectorNorm(record.Vector)
+	if i, ok := c.index[record.ID]; ok {
+		unchanged := slices.Equal(c.records[i].Vector, record.Vector)
+		c.records[i] = record
+		c.norms[i] = norm
+		if c.hnsw != nil && !unchanged {
+			c.hnsw.Insert(record.ID, record.Vector)
+		}
+		return
+	}
+
+	c.index[record.ID] = len(c.records)
+	c.records = append(c.records, record)
+	c.norms = append(c.norms, norm)
+	if c.hnsw != nil {
+		c.hnsw.Insert(record.ID, record.Vector)
+	}
+}
+
+// remove 删除记录（用最后一条记录填补空位）
+func (c *embeddedCollection) remove(id string) {
+	i, ok := c.index[id]
+	if !ok {
+		return
+	}
+
+	last := len(c.records) - 1
+	if i != last {
+		c.records[i] = c.records[last]
+		c.norms[i] = c.norms[last]
+		c.index[c.records[i].ID] = i
+	}
+	c.records = c.records[:last]
+	c.norms = c.norms[:last]
+	delete(c.index, id)
+	if c.hnsw != nil {
+		c.hnsw.Delete(id)
+	}
+}
+
+// compactIndex 标记删除的节点超过存活节点数时重建 HNSW 索引（删除的节点仍占内存并拖慢搜索）
+func (c *embeddedCollection) compactIndex(config *HNSWConfig) {
+	if c.hnsw == nil || c.hnsw.Deleted() <= c.hnsw.Len() {
+		return
+	}
+	log.Infof("🔧 Rebuilding HNSW index for %s (%d live, %d deleted)", c.name, c.hnsw.Len(), c.hnsw.Deleted())
+	c.rebuildIndex(config)
+}
+
+// rebuildIndex 按记录重建 HNSW 索引
+func (c *embeddedCollection) rebuildIndex(config *HNSWConfig) {
+	c.hnsw = NewHNSWIndex(config, c.dimension)
+	for _, record := range c.records {
+		c.hnsw.Insert(record.ID, record.Vector)
+	}
+}
+
+// save 将集合写入磁盘（调用方持有写锁；未配置目录时不做任何事）
+func (s *EmbeddedStore) save(collection string) error {
+	if s.config.Dir == "" {
+		return nil
+	}
+
+	c := s.collections[collection]
+	records := append([]Record(nil), c.records...)
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+
+	data, err := json.Marshal(embeddedFile{
+		Version:   embeddedFileVersion,
+		Name:      c.name,
+		Dimension: c.dimension,
+		Records:   records,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode vector collection: %w", err)
+	}
+
+	if err := os.MkdirAll(s.config.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create vector store directory: %w", err)
+	}
+
+	// 先写临时文件再 rename，避免写一半的文件被下次启动加载
+	path := filepath.Join(s.config.Dir, collection+".json")
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write vector collection: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace vector collection: %w", err)
+	}
+
+	if c.hnsw == nil {
+		return nil
+	}
+	return s.saveIndex(c)
+}
+
+// saveIndex 将 HNSW 索引写入 <集合>.hnsw（同样先写临时文件再 rename）
+func (s *EmbeddedStore) saveIndex(c *embeddedCollection) error {
+	path := filepath.Join(s.config.Dir, c.name+".hnsw")
+	tmpPath := path + ".tmp"
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create hnsw index file: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	if err := c.hnsw.Save(writer); err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write hnsw index: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write hnsw index: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace hnsw index: %w", err)
+	}
+
+	return nil
+}
+
+// loadIndex 加载集合的 HNSW 索引；文件不存在、损坏或与记录不一致时按记录重建
+func (s *EmbeddedStore) loadIndex(c *embeddedCollection) {
+	path := filepath.Join(s.config.Dir, c.name+".hnsw")
+	if file, err := os.Open(path); err == nil {
+		index, err := LoadHNSWIndex(bufio.NewReader(file), s.config.HNSW)
+		file.Close()
+		if err == nil && c.indexMatches(index) {
+			c.hnsw = index
+			return
+		}
+		log.Warnf("⚠️  HNSW index %s is stale or corrupted, rebuilding: %v", path, err)
+	}
+
+	c.rebuildIndex(s.config.HNSW)
+	log.Infof("🔧 Built HNSW index for %s (%d records)", c.name, len(c.records))
+}
+
+// indexMatches 索引的维度和存活ID是否与集合记录一致
+func (c *embeddedCollection) indexMatches(index *HNSWIndex) bool {
+	if index.dimension != c.dimension || len(index.ids) != len(c.records) {
+		return false
+	}
+	for id := range index.ids {
+		if _, ok := c.index[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// loadEmbeddedCollection 从文件加载集合
+func loadEmbeddedCollection(path string) (*embeddedCollection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vector collection: %w", err)
+	}
+
+	var file embeddedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode vector collection %s: %w", path, err)
+	}
+	if file.Version != embeddedFileVersion {
+		return nil, fmt.Errorf("unsupported vector collection version %d in %s", file.Version, path)
+	}
+
+	collection := &embeddedCollection{
+		name:      file.Name,
+		dimension: file.Dimension,
+		index:     make(map[string]int, len(file.Records)),
+	}
+	for _, record := range file.Records {
+		collection.put(record)
+	}
+	return collection, nil
+}
+
+// vectorNorm 向量的 L2 范数
+func vectorNorm(vector []float32) float64 {
+	sum := 0.0
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum)
+}
+
+// cosine 余弦相似度（范数已预先计算，零向量返回0）
+func cosine(a, b []float32, normA, normB float64) float64 {
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	dot := 0.0
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot / (normA * normB)
+}
+
+// copyMetadata 复制元数据，避免调用方修改存储中的记录
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		return nil
+	}
+
+	copied := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,197 @@
+package vectorstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 过滤条件的比较运算
+const (
+	OpEq       = "eq"       // 等于
+	OpIn       = "in"       // 等于其中任意一个值
+	OpGte      = "gte"      // 数值大于等于
+	OpLte      = "lte"      // 数值小于等于
+	OpContains = "contains" // 列表字段包含该值
+)
+
+// Condition 单个元数据过滤条件
+type Condition struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// Filter 元数据过滤：记录需满足全部条件（为空表示不过滤）
+// 例：Filter{In("category", "vegetable_dish"), Lte("difficulty", 2)} 只搜索两星以内的素菜
+// 进程内存储用 Match 逐条检查，Milvus 编译为布尔表达式，两者语义一致
+type Filter []Condition
+
+// Eq 等值条件
+func Eq(field string, value interface{}) Condition {
+	return Condition{Field: field, Op: OpEq, Value: value}
+}
+
+// In 取值范围条件
+func In(field string, values ...string) Condition {
+	return Condition{Field: field, Op: OpIn, Value: values}
+}
+
+// Gte 数值下限条件
+func Gte(field string, value float64) Condition {
+	return Condition{Field: field, Op: OpGte, Value: value}
+}
+
+// Lte 数值上限条件
+func Lte(field string, value float64) Condition {
+	return Condition{Field: field, Op: OpLte, Value: value}
+}
+
+// Contains 列表包含条件（如食材列表包含"鸡蛋"）
+func Contains(field string, value string) Condition {
+	return Condition{Field: field, Op: OpContains, Value: value}
+}
+
+// Match 元数据是否满足全部条件
+// 等值按字面比较（JSON 解码出的 float64 与 int 视为相等），缺少字段的记录不满足任何条件
+func (f Filter) Match(metadata map[string]interface{}) bool {
+	for _, condition := range f {
+		if !condition.Match(metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match 元数据是否满足条件
+func (c Condition) Match(metadata map[string]interface{}) bool {
+	got, ok := metadata[c.Field]
+	if !ok || got == nil {
+		return false
+	}
+
+	switch c.Op {
+	case OpEq:
+		return fmt.Sprint(got) == fmt.Sprint(c.Value)
+	case OpIn:
+		for _, want := range toStrings(c.Value) {
+			if fmt.Sprint(got) == want {
+				return true
+			}
+		}
+		return false
+	case OpGte, OpLte:
+		value, ok := toFloat(got)
+		bound, boundOK := toFloat(c.Value)
+		if !ok || !boundOK {
+			return false
+		}
+		if c.Op == OpGte {
+			return value >= bound
+		}
+		return value <= bound
+	case OpContains:
+		want := fmt.Sprint(c.Value)
+		for _, item := range toStrings(got) {
+			if item == want {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// MilvusExpr 编译为 Milvus 布尔表达式（字段位于 JSON 列 metadataField 中），空过滤返回空字符串
+// 例：metadata["category"] in ["vegetable_dish"] && metadata["difficulty"] <= 2 && json_contains(metadata["ingredients"], "鸡蛋")
+func (f Filter) MilvusExpr(metadataField string) (string, error) {
+	conditions := make([]string, 0, len(f))
+	for _, condition := range f {
+		expr, err := condition.milvusExpr(metadataField)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, expr)
+	}
+	return strings.Join(conditions, " && "), nil
+}
+
+// milvusExpr 单个条件的 Milvus 表达式
+func (c Condition) milvusExpr(metadataField string) (string, error) {
+	field := fmt.Sprintf("%s[%q]", metadataField, c.Field)
+
+	switch c.Op {
+	case OpEq:
+		value, err := json.Marshal(c.Value)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode filter value for %s: %w", c.Field, err)
+		}
+		return fmt.Sprintf("%s == %s", field, value), nil
+	case OpIn:
+		values, err := json.Marshal(toStrings(c.Value))
+		if err != nil {
+			return "", fmt.Errorf("failed to encode filter values for %s: %w", c.Field, err)
+		}
+		return fmt.Sprintf("%s in %s", field, values), nil
+	case OpGte, OpLte:
+		bound, ok := toFloat(c.Value)
+		if !ok {
+			return "", fmt.Errorf("filter bound for %s is not a number: %v", c.Field, c.Value)
+		}
+		op := ">="
+		if c.Op == OpLte {
+			op = "<="
+		}
+		return fmt.Sprintf("%s %s %s", field, op, strconv.FormatFloat(bound, 'f', -1, 64)), nil
+	case OpContains:
+		value, err := json.Marshal(fmt.Sprint(c.Value))
+		if err != nil {
+			return "", fmt.Errorf("failed to encode filter value for %s: %w", c.Field, err)
+		}
+		return fmt.Sprintf("json_contains(%s, %s)", field, value), nil
+	default:
+		return "", fmt.Errorf("unsupported filter operator %q for %s", c.Op, c.Field)
+	}
+}
+
+// toStrings 将字符串列表或任意列表转换为字符串列表（非列表视为单个值）
+func toStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// toFloat 将数值或数字字符串转换为 float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,506 @@
+package vectorstore
+
+import (
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// hnswFileVersion HNSW 索引文件格式版本
+const hnswFileVersion = 1
+
+// HNSWConfig HNSW 索引参数
+type HNSWConfig struct {
+	M              int   // 每个节点在上层的最大邻居数（第0层为 2M），越大召回越高、内存越多
+	EfConstruction int   // 构建时的候选集大小，越大图质量越好、构建越慢
+	EfSearch       int   // 搜索时的候选集大小（实际取 max(EfSearch, topK)），越大召回越高、搜索越慢
+	Seed           int64 // 层数随机数种子（固定种子可复现构建结果）
+}
+
+// DefaultHNSWConfig 默认 HNSW 参数
+func DefaultHNSWConfig() *HNSWConfig {
+	return &HNSWConfig{
+		M:              16,
+		EfConstruction: 200,
+		EfSearch:       64,
+		Seed:           42,
+	}
+}
+
+// HNSWResult HNSW 搜索结果
+type HNSWResult struct {
+	ID    string
+	Score float32 // 余弦相似度
+}
+
+// HNSWIndex 分层可导航小世界图（Hierarchical Navigable Small World）近似最近邻索引
+// 度量为余弦相似度（向量写入时归一化）；删除只做标记，被删除的节点仍参与图遍历但不出现在结果中
+// 读写锁保证并发读、单写
+type HNSWIndex struct {
+	mu         sync.RWMutex
+	config     HNSWConfig
+	dimension  int
+	levelMult  float64 // 层数分布参数 1/ln(M)
+	rng        *rand.Rand
+	nodes      []*hnswNode
+	ids        map[string]int32 // 未删除的ID -> 节点
+	entryPoint int32            // 入口节点（-1 表示空索引）
+	maxLevel   int
+	deleted    int
+}
+
+// hnswNode 图节点（字段导出以便 gob 持久化）
+type hnswNode struct {
+	ID        string
+	Vector    []float32 // 归一化后的向量
+	Level     int
+	Neighbors [][]int32 // 每层的邻居
+	Deleted   bool
+}
+
+// hnswFile 索引文件格式
+type hnswFile struct {
+	Version    int
+	Config     HNSWConfig
+	Dimension  int
+	EntryPoint int32
+	MaxLevel   int
+	Nodes      []*hnswNode
+}
+
+// NewHNSWIndex 创建空索引
+func NewHNSWIndex(config *HNSWConfig, dimension int) *HNSWIndex {
+	if config == nil {
+		config = DefaultHNSWConfig()
+	}
+
+	c := *config
+	if c.M < 2 {
+		c.M = 2
+	}
+	if c.EfConstruction < c.M {
+		c.EfConstruction = c.M
+	}
+	if c.EfSearch <= 0 {
+		c.EfSearch = DefaultHNSWConfig().EfSearch
+	}
+
+	return &HNSWIndex{
+		config:     c,
+		dimension:  dimension,
+		levelMult:  1 / math.Log(float64(c.M)),
+		rng:        rand.New(rand.NewSource(c.Seed)),
+		ids:        make(map[string]int32),
+		entryPoint: -1,
+	}
+}
+
+// Len 未删除的节点数
+func (h *HNSWIndex) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.ids)
+}
+
+// Deleted 已标记删除的节点数
+func (h *HNSWIndex) Deleted() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.deleted
+}
+
+// Stats 索引统计信息
+func (h *HNSWIndex) Stats() map[string]interface{} {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return map[string]interface{}{
+		"m":               h.config.M,
+		"ef_construction": h.config.EfConstruction,
+		"ef_search":       h.config.EfSearch,
+		"nodes":           len(h.nodes),
+		"live":            len(h.ids),
+		"deleted":         h.deleted,
+		"max_level":       h.maxLevel,
+	}
+}
+
+// SetEfSearch 调整搜索时的候选集大小（不需要重建索引）
+func (h *HNSWIndex) SetEfSearch(ef int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if ef > 0 {
+		h.config.EfSearch = ef
+	}
+}
+
+// Insert 插入向量；ID已存在时旧节点标记删除后插入新节点
+func (h *HNSWIndex) Insert(id string, vector []float32) error {
+	if len(vector) != h.dimension {
+		return fmt.Errorf("%w: vector has dimension %d, index expects %d", ErrDimensionMismatch, len(vector), h.dimension)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if old, ok := h.ids[id]; ok {
+		h.markDeleted(old)
+	}
+
+	level := h.randomLevel()
+	node := &hnswNode{
+		ID:        id,
+		Vector:    normalize(vector),
+		Level:     level,
+		Neighbors: make([][]int32, level+1),
+	}
+	current := int32(len(h.nodes))
+	h.nodes = append(h.nodes, node)
+	h.ids[id] = current
+
+	if h.entryPoint < 0 {
+		h.entryPoint = current
+		h.maxLevel = level
+		return nil
+	}
+
+	// 1. 从最高层贪心下降到新节点所在层的上一层
+	entry := h.entryPoint
+	for l := h.maxLevel; l > level; l-- {
+		entry = h.greedyClosest(node.Vector, entry, l)
+	}
+
+	// 2. 在新节点所在的每一层搜索候选并连接邻居
+	entries := []int32{entry}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(node.Vector, entries, h.config.EfConstruction, l)
+		node.Neighbors[l] = h.selectNeighbors(candidates, h.config.M)
+
+		for _, neighbor := range node.Neighbors[l] {
+			h.connect(neighbor, current, l)
+		}
+
+		entries = entries[:0]
+		for _, candidate := range candidates {
+			entries = append(entries, candidate.node)
+		}
+	}
+
+	if level > h.maxLevel {
+		h.maxLevel = level
+		h.entryPoint = current
+	}
+	return nil
+}
+
+// Delete 标记删除（节点仍保留在图中用于导航），返回ID是否存在
+func (h *HNSWIndex) Delete(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	node, ok := h.ids[id]
+	if !ok {
+		return false
+	}
+	h.markDeleted(node)
+	return true
+}
+
+// Search 搜索与查询向量最相似的 k 个未删除节点
+// accept 不为 nil 时只返回 accept(id) 为 true 的节点（在 ef 个候选中过滤，过滤条件很严格时结果可能少于 k）
+func (h *HNSWIndex) Search(vector []float32, k int, accept func(id string) bool) ([]HNSWResult, error) {
+	if len(vector) != h.dimension {
+		return nil, fmt.Errorf("%w: query has dimension %d, index expects %d", ErrDimensionMismatch, len(vector), h.dimension)
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.entryPoint < 0 || k <= 0 {
+		return []HNSWResult{}, nil
+	}
+
+	query := normalize(vector)
+	entry := h.entryPoint
+	for l := h.maxLevel; l > 0; l-- {
+		entry = h.greedyClosest(query, entry, l)
+	}
+
+	// 标记删除的节点也会占用候选位置，按删除比例放大候选集，使未删除的候选仍约有 ef 个
+	ef := max(h.config.EfSearch, k)
+	if live := len(h.ids); h.deleted > 0 && live > 0 {
+		ef = min(ef*(live+h.deleted)/live, len(h.nodes))
+	}
+	candidates := h.searchLayer(query, []int32{entry}, ef, 0)
+
+	results := make([]HNSWResult, 0, k)
+	for _, candidate := range candidates {
+		node := h.nodes[candidate.node]
+		if node.Deleted || (accept != nil && !accept(node.ID)) {
+			continue
+		}
+		results = append(results, HNSWResult{ID: node.ID, Score: float32(1 - candidate.distance)})
+		if len(results) == k {
+			break
+		}
+	}
+	return results, nil
+}
+
+// Save 将索引写入 w（gob 编码）
+func (h *HNSWIndex) Save(w io.Writer) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if err := gob.NewEncoder(w).Encode(hnswFile{
+		Version:    hnswFileVersion,
+		Config:     h.config,
+		Dimension:  h.dimension,
+		EntryPoint: h.entryPoint,
+		MaxLevel:   h.maxLevel,
+		Nodes:      h.nodes,
+	}); err != nil {
+		return fmt.Errorf("failed to encode hnsw index: %w", err)
+	}
+	return nil
+}
+
+// LoadHNSWIndex 从 r 读取索引（搜索参数 EfSearch 使用 config 中的值，其余参数以文件为准）
+func LoadHNSWIndex(r io.Reader, config *HNSWConfig) (*HNSWIndex, error) {
+	var file hnswFile
+	if err := gob.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode hnsw index: %w", err)
+	}
+	if file.Version != hnswFileVersion {
+		return nil, fmt.Errorf("unsupported hnsw index version %d", file.Version)
+	}
+
+	loaded := file.Config
+	if config != nil && config.EfSearch > 0 {
+		loaded.EfSearch = config.EfSearch
+	}
+
+	h := NewHNSWIndex(&loaded, file.Dimension)
+	h.rng = rand.New(rand.NewSource(loaded.Seed + int64(len(file.Nodes))))
+	h.nodes = file.Nodes
+	h.entryPoint = file.EntryPoint
+	h.maxLevel = file.MaxLevel
+	for i, node := range h.nodes {
+		if node.Deleted {
+			h.deleted++
+			continue
+		}
+		h.ids[node.ID] = int32(i)
+	}
+	if h.entryPoint >= int32(len(h.nodes)) {
+		return nil, fmt.Errorf("corrupted hnsw index: entry point %d out of range", h.entryPoint)
+	}
+
+	return h, nil
+}
+
+// markDeleted 标记节点删除（调用方持有写锁）
+func (h *HNSWIndex) markDeleted(node int32) {
+	n := h.nodes[node]
+	if n.Deleted {
+		return
+	}
+	n.Deleted = true
+	delete(h.ids, n.ID)
+	h.deleted++
+}
+
+// randomLevel 按指数分布随机生成节点层数
+func (h *HNSWIndex) randomLevel() int {
+	return int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+}
+
+// maxConnections 每层的最大邻居数（第0层为 2M）
+func (h *HNSWIndex) maxConnections(level int) int {
+	if level == 0 {
+		return 2 * h.config.M
+	}
+	return h.config.M
+}
+
+// distance 余弦距离（向量均已归一化）
+func (h *HNSWIndex) distance(a, b []float32) float64 {
+	dot := 0.0
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return 1 - dot
+}
+
+// greedyClosest 在单层上贪心移动到离查询最近的节点
+func (h *HNSWIndex) greedyClosest(query []float32, entry int32, level int) int32 {
+	current := entry
+	currentDistance := h.distance(query, h.nodes[current].Vector)
+	for changed := true; changed; {
+		changed = false
+		for _, neighbor := range h.nodes[current].Neighbors[level] {
+			if d := h.distance(query, h.nodes[neighbor].Vector); d < currentDistance {
+				current, currentDistance = neighbor, d
+				changed = true
+			}
+		}
+	}
+	return current
+}
+
+// searchLayer 在单层上做 beam search，返回最近的 ef 个节点（按距离升序，包含已删除节点）
+func (h *HNSWIndex) searchLayer(query []float32, entries []int32, ef int, level int) []hnswCandidate {
+	visited := make([]uint64, (len(h.nodes)+63)/64)
+	visit := func(node int32) bool {
+		word, bit := node/64, uint64(1)<<(node%64)
+		if visited[word]&bit != 0 {
+			return false
+		}
+		visited[word] |= bit
+		return true
+	}
+
+	candidates := &candidateHeap{}       // 待扩展（最近的在堆顶）
+	results := &candidateHeap{max: true} // 当前最好的 ef 个（最远的在堆顶）
+	for _, entry := range entries {
+		if !visit(entry) {
+			continue
+		}
+		c := hnswCandidate{node: entry, distance: h.distance(query, h.nodes[entry].Vector)}
+		heap.Push(candidates, c)
+		heap.Push(results, c)
+	}
+
+	for candidates.Len() > 0 {
+		closest := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && closest.distance > results.items[0].distance {
+			break
+		}
+
+		node := h.nodes[closest.node]
+		if level >= len(node.Neighbors) {
+			continue
+		}
+		for _, neighbor := range node.Neighbors[level] {
+			if !visit(neighbor) {
+				continue
+			}
+			d := h.distance(query, h.nodes[neighbor].Vector)
+			if results.Len() < ef || d < results.items[0].distance {
+				c := hnswCandidate{node: neighbor, distance: d}
+				heap.Push(candidates, c)
+				heap.Push(results, c)
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	sorted := append([]hnswCandidate(nil), results.items...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].distance < sorted[j].distance
+	})
+	return sorted
+}
+
+// selectNeighbors 启发式选择邻居（论文 Algorithm 4）：
+// 候选只有在离新节点比离已选邻居都近时才入选，使邻居分布在不同方向；不足 m 个时用被淘汰的候选补齐
+func (h *HNSWIndex) selectNeighbors(candidates []hnswCandidate, m int) []int32 {
+	selected := make([]int32, 0, m)
+	pruned := make([]int32, 0)
+	for _, candidate := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		good := true
+		for _, s := range selected {
+			if h.distance(h.nodes[candidate.node].Vector, h.nodes[s].Vector) < candidate.distance {
+				good = false
+				break
+			}
+		}
+		if good {
+			selected = append(selected, candidate.node)
+		} else {
+			pruned = append(pruned, candidate.node)
+		}
+	}
+
+	for _, node := range pruned {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, node)
+	}
+	return selected
+}
+
+// connect 把 target 加入 node 在该层的邻居，超出上限时重新选择邻居
+func (h *HNSWIndex) connect(node, target int32, level int) {
+	n := h.nodes[node]
+	n.Neighbors[level] = append(n.Neighbors[level], target)
+	if len(n.Neighbors[level]) <= h.maxConnections(level) {
+		return
+	}
+
+	candidates := make([]hnswCandidate, 0, len(n.Neighbors[level]))
+	for _, neighbor := range n.Neighbors[level] {
+		candidates = append(candidates, hnswCandidate{node: neighbor, distance: h.distance(n.Vector, h.nodes[neighbor].Vector)})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	n.Neighbors[level] = h.selectNeighbors(candidates, h.maxConnections(level))
+}
+
+// hnswCandidate 搜索候选
+type hnswCandidate struct {
+	node     int32
+	distance float64
+}
+
+// candidateHeap 候选堆（max 为 true 时为大顶堆）
+type candidateHeap struct {
+	items []hnswCandidate
+	max   bool
+}
+
+func (c *candidateHeap) Len() int { return len(c.items) }
+
+func (c *candidateHeap) Less(i, j int) bool {
+	if c.max {
+		return c.items[i].distance > c.items[j].distance
+	}
+	return c.items[i].distance < c.items[j].distance
+}
+
+func (c *candidateHeap) Swap(i, j int) { c.items[i], c.items[j] = c.items[j], c.items[i] }
+
+func (c *candidateHeap) Push(x interface{}) { c.items = append(c.items, x.(hnswCandidate)) }
+
+func (c *candidateHeap) Pop() interface{} {
+	last := c.items[len(c.items)-1]
+	c.items = c.items[:len(c.items)-1]
+	return last
+}
+
+// normalize 归一化向量（零向量原样返回）
+func normalize(vector []float32) []float32 {
+	norm := vectorNorm(vector)
+	normalized := make([]float32, len(vector))
+	if norm == 0 {
+		copy(normalized, vector)
+		return normalized
+	}
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
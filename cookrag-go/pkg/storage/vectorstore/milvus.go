@@ -0,0 +1,292 @@
+package vectorstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"cookrag-go/pkg/storage/milvus"
+
+	"github.com/charmbracelet/log"
+)
+
+// Milvus 集合的字段名（与 milvus.Client.CreateCollection 的 schema 一致）
+const (
+	milvusIDField       = "id"
+	milvusVectorField   = "vector"
+	milvusTextField     = "text"
+	milvusMetadataField = "metadata"
+)
+
+// DocIDMetadataKey 元数据中保存调用方文档ID的字段名（Milvus 主键是整数，文档ID写在元数据里）
+const DocIDMetadataKey = "doc_id"
+
+// MilvusStore 基于 Milvus 的向量存储
+type MilvusStore struct {
+	client *milvus.Client
+}
+
+// NewMilvusStore 创建 Milvus 向量存储
+func NewMilvusStore(client *milvus.Client) *MilvusStore {
+	return &MilvusStore{client: client}
+}
+
+// CreateCollection 创建集合和向量索引并加载到内存（已存在时只确保已加载）
+func (s *MilvusStore) CreateCollection(ctx context.Context, collection string, dimension int) error {
+	exists, err := s.client.HasCollection(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("failed to check collection: %w", err)
+	}
+
+	if !exists {
+		if err := s.client.CreateCollection(ctx, collection, dimension); err != nil {
+			return err
+		}
+		if err := s.client.CreateIndex(ctx, collection, milvusVectorField); err != nil {
+			return err
+		}
+	} else {
+		s.checkIndex(ctx, collection)
+	}
+
+	return s.client.LoadCollection(ctx, collection)
+}
+
+// checkIndex 已有集合的索引与配置不一致时给出警告（度量方式不一致时搜索会失败，需要重建集合）
+func (s *MilvusStore) checkIndex(ctx context.Context, collection string) {
+	indexType, metricType, err := s.client.DescribeIndex(ctx, collection, milvusVectorField)
+	if err != nil {
+		log.Warnf("⚠️  Failed to describe index of %s: %v", collection, err)
+		return
+	}
+
+	config := s.client.IndexConfig()
+	if indexType != config.IndexType || metricType != config.MetricType {
+		log.Warnf("⚠️  Collection %s has index %s/%s but config expects %s/%s, recreate the collection to apply",
+			collection, indexType, metricType, config.IndexType, config.MetricType)
+	}
+}
+
+// HasCollection 集合是否存在
+func (s *MilvusStore) HasCollection(ctx context.Context, collection string) (bool, error) {
+	return s.client.HasCollection(ctx, collection)
+}
+
+// Upsert 以文档ID的哈希作为主键写入（见 PrimaryKey），同一文档重复写入时替换原有行，然后刷新并重新加载集合
+// 旧版本以时间戳为主键写入的同一文档的行会先被删除
+func (s *MilvusStore) Upsert(ctx context.Context, collection string, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	// 同一批次中ID重复时以最后一条为准（同一主键在一次 Upsert 中出现多次时结果不确定）
+	positions := make(map[string]int, len(records))
+	unique := make([]Record, 0, len(records))
+	for _, record := range records {
+		if record.ID == "" {
+			return fmt.Errorf("vector record id is required")
+		}
+		if i, ok := positions[record.ID]; ok {
+			unique[i] = record
+			continue
+		}
+		positions[record.ID] = len(unique)
+		unique = append(unique, record)
+	}
+
+	docIDs := make([]string, len(unique))
+	ids := make([]int64, len(unique))
+	embeddings := make([][]float32, len(unique))
+	texts := make([]string, len(unique))
+	metadataList := make([]map[string]interface{}, len(unique))
+	for i, record := range unique {
+		docIDs[i] = record.ID
+		ids[i] = PrimaryKey(record.ID)
+		embeddings[i] = record.Vector
+		texts[i] = record.Text
+
+		// 复制元数据并写入调用方文档ID，检索时据此还原 Document.ID
+		metadata := copyMetadata(record.Metadata)
+		if metadata == nil {
+			metadata = make(map[string]interface{}, 1)
+		}
+		metadata[DocIDMetadataKey] = record.ID
+		metadataList[i] = metadata
+	}
+
+	if err := s.deleteStale(ctx, collection, docIDs, ids); err != nil {
+		return err
+	}
+	if err := s.client.Upsert(ctx, collection, ids, embeddings, texts, metadataList); err != nil {
+		return err
+	}
+
+	// 刷新集合（将数据持久化到磁盘），Flush 后需要重新加载集合到内存（否则搜索不到数据）
+	if err := s.client.Flush(ctx, collection); err != nil {
+		return fmt.Errorf("failed to flush collection: %w", err)
+	}
+	return s.client.LoadCollection(ctx, collection)
+}
+
+// deleteStale 删除这些文档ID对应、但主键不是 PrimaryKey(文档ID) 的旧行
+func (s *MilvusStore) deleteStale(ctx context.Context, collection string, docIDs []string, ids []int64) error {
+	quotedDocIDs, err := json.Marshal(docIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode ids: %w", err)
+	}
+	primaryKeys, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("failed to encode primary keys: %w", err)
+	}
+	return s.client.Delete(ctx, collection, fmt.Sprintf("%s[%q] in %s && %s not in %s",
+		milvusMetadataField, DocIDMetadataKey, quotedDocIDs, milvusIDField, primaryKeys))
+}
+
+// Delete 按文档ID删除记录
+func (s *MilvusStore) Delete(ctx context.Context, collection string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	quoted, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("failed to encode ids: %w", err)
+	}
+	return s.client.Delete(ctx, collection, fmt.Sprintf("%s[%q] in %s", milvusMetadataField, DocIDMetadataKey, quoted))
+}
+
+// Search 向量搜索，过滤条件转换为 Milvus 表达式
+func (s *MilvusStore) Search(ctx context.Context, collection string, vectors [][]float32, topK int, filter Filter) ([][]SearchResult, error) {
+	expr, err := filter.MilvusExpr(milvusMetadataField)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped, err := s.client.SearchWithFilter(
+		ctx,
+		collection,
+		vectors,
+		milvusVectorField,
+		[]string{milvusTextField, milvusMetadataField},
+		topK,
+		expr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// 原始分数统一为越大越相似（L2 距离转换为 1/(1+d)），与进程内存储的余弦相似度方向一致，便于融合排序
+	metric := s.client.IndexConfig().Metric()
+	results := make([][]SearchResult, 0, len(grouped))
+	for _, group := range grouped {
+		converted := make([]SearchResult, 0, len(group))
+		for _, result := range group {
+			text, _ := result.Fields[milvusTextField].(string)
+			metadata, _ := result.Fields[milvusMetadataField].(map[string]interface{})
+			converted = append(converted, SearchResult{
+				ID:       milvusDocumentID(result.ID, metadata),
+				Score:    milvus.NormalizeScore(metric, result.Score),
+				Text:     text,
+				Metadata: metadata,
+			})
+		}
+		results = append(results, converted)
+	}
+
+	return results, nil
+}
+
+// NativeBatchSearch Milvus 一次请求搜索全部查询向量，按查询向量分组返回
+func (s *MilvusStore) NativeBatchSearch() bool {
+	return true
+}
+
+// GetVectors 按文档ID读取向量（按文档ID对应的主键查询）
+func (s *MilvusStore) GetVectors(ctx context.Context, collection string, ids []string) (map[string][]float32, error) {
+	vectors := make(map[string][]float32, len(ids))
+	if len(ids) == 0 {
+		return vectors, nil
+	}
+
+	docIDs := make(map[int64]string, len(ids))
+	primaryKeys := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		key := PrimaryKey(id)
+		if _, ok := docIDs[key]; !ok {
+			docIDs[key] = id
+			primaryKeys = append(primaryKeys, key)
+		}
+	}
+
+	encoded, err := json.Marshal(primaryKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode primary keys: %w", err)
+	}
+	rows, err := s.client.QueryVectors(ctx, collection, fmt.Sprintf("%s in %s", milvusIDField, encoded), milvusIDField, milvusVectorField)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, vector := range rows {
+		if id, ok := docIDs[key]; ok {
+			vectors[id] = vector
+		}
+	}
+	return vectors, nil
+}
+
+// Stats 集合统计信息（Milvus 返回的 row_count 可能是 string / int64 / float64）
+func (s *MilvusStore) Stats(ctx context.Context, collection string) (*CollectionStats, error) {
+	stats, err := s.client.GetCollectionStats(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	rowCount := int64(0)
+	switch v := stats["row_count"].(type) {
+	case int64:
+		rowCount = v
+	case string:
+		fmt.Sscanf(v, "%d", &rowCount)
+	case float64:
+		rowCount = int64(v)
+	}
+
+	dimension, err := s.client.Dimension(ctx, collection, milvusVectorField)
+	if err != nil {
+		log.Warnf("⚠️  Failed to get collection dimension: %v", err)
+	}
+
+	return &CollectionStats{
+		Name:      collection,
+		Backend:   "milvus",
+		Dimension: dimension,
+		Count:     rowCount,
+	}, nil
+}
+
+// Close 关闭 Milvus 连接
+func (s *MilvusStore) Close(ctx context.Context) error {
+	return s.client.Close(ctx)
+}
+
+// milvusDocumentID 还原文档ID：使用元数据中的调用方文档ID，没有时（外部写入的行）使用 Milvus 主键
+func milvusDocumentID(id int64, metadata map[string]interface{}) string {
+	if docID, ok := metadata[DocIDMetadataKey].(string); ok && docID != "" {
+		return docID
+	}
+	return fmt.Sprintf("milvus_%d", id)
+}
+
+// PrimaryKey 文档ID对应的 Milvus 主键：SHA-256 前8字节（取正数），同一文档ID总是得到同一主键
+func PrimaryKey(docID string) int64 {
+	sum := sha256.Sum256([]byte(docID))
+	key := int64(binary.BigEndian.Uint64(sum[:8]) & math.MaxInt64)
+	if key == 0 {
+		return 1
+	}
+	return key
+}
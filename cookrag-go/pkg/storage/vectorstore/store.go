@@ -0,0 +1,81 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrCollectionNotFound 集合不存在
+var ErrCollectionNotFound = errors.New("vector collection not found")
+
+// ErrDimensionMismatch 向量维度与集合不一致
+var ErrDimensionMismatch = errors.New("vector dimension mismatch")
+
+// Store 向量存储接口
+// 记录以调用方文档ID为主键，Upsert 时ID相同的记录会被替换
+// Milvus 和进程内的 EmbeddedStore 都实现了该接口，检索器不关心具体后端
+type Store interface {
+	// CreateCollection 创建集合（已存在时不做任何事）
+	CreateCollection(ctx context.Context, collection string, dimension int) error
+	// HasCollection 集合是否存在
+	HasCollection(ctx context.Context, collection string) (bool, error)
+	// Upsert 写入记录（ID已存在则替换）
+	Upsert(ctx context.Context, collection string, records []Record) error
+	// Delete 按ID删除记录（不存在的ID直接忽略）
+	Delete(ctx context.Context, collection string, ids []string) error
+	// Search 向量搜索，按查询向量分组返回最相似的 topK 条记录（filter 为空表示不过滤）
+	Search(ctx context.Context, collection string, vectors [][]float32, topK int, filter Filter) ([][]SearchResult, error)
+	// Stats 集合统计信息
+	Stats(ctx context.Context, collection string) (*CollectionStats, error)
+	// Close 释放资源
+	Close(ctx context.Context) error
+}
+
+// BatchSearcher 标记 Search 在一次请求中完成多个查询向量的搜索（可选能力，Milvus 实现）
+// 未实现的后端由检索器按查询分别调用 Search 并控制并发
+type BatchSearcher interface {
+	// NativeBatchSearch 多个查询向量是否在一次请求中搜索
+	NativeBatchSearch() bool
+}
+
+// VectorFetcher 按文档ID读取已存储的向量（可选能力，MMR 多样化等需要文档间相似度的场景使用）
+type VectorFetcher interface {
+	// GetVectors 返回文档ID到向量的映射（不存在的ID不出现在结果中）
+	GetVectors(ctx context.Context, collection string, ids []string) (map[string][]float32, error)
+}
+
+// Record 向量记录
+type Record struct {
+	ID       string                 `json:"id"` // 调用方文档ID
+	Vector   []float32              `json:"vector"`
+	Text     string                 `json:"text"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// SearchResult 搜索结果
+type SearchResult struct {
+	ID       string                 `json:"id"`
+	Score    float32                `json:"score"` // 相似度分数，越大越相似（EmbeddedStore 为余弦相似度；Milvus 见 milvus.NormalizeScore）
+	Text     string                 `json:"text"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// CollectionStats 集合统计信息
+type CollectionStats struct {
+	Name      string `json:"name"`
+	Backend   string `json:"backend"`         // milvus / embedded
+	Index     string `json:"index,omitempty"` // embedded 的索引类型：flat / hnsw
+	Dimension int    `json:"dimension"`
+	Count     int64  `json:"count"`
+}
+
+// ToMap 转换为统计信息 map（供 GetStats 接口使用）
+func (s *CollectionStats) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"collection_name": s.Name,
+		"backend":         s.Backend,
+		"index":           s.Index,
+		"dimension":       s.Dimension,
+		"row_count":       s.Count,
+	}
+}